@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/querylog"
+	"github.com/queryplan-ai/qp/pkg/workload"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func AnalyzeLogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "analyze-log <file>",
+		Short: "Plan the statements in a query log",
+		Long: `Plan the statements in a query log. Statements are grouped by their
+fingerprint, and the ones that took the most time are planned and reported with
+their measured cost. With --db-uri the statements are planned against the
+schema of the database. Without it only the issues that don't depend on the
+schema are found.
+Postgres logs are read from the statements log_min_duration_statement logs, and
+the plans auto_explain logs are checked for scans that discard most of their
+rows, bad row estimates and sorts that spill to disk. Use - to read the log
+from stdin.
+
+Formats: ` + strings.Join(querylog.Formats, ", "),
+		Args: cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			uri, err := connectionURI(v)
+			if err != nil {
+				return err
+			}
+
+			database := &types.DB{}
+			if uri != "" {
+				connected, err := db.Connect(uri)
+				if err != nil {
+					return err
+				}
+				database = connected
+			}
+			if err := applyIssueFilter(v, database); err != nil {
+				return err
+			}
+
+			var r io.Reader = os.Stdin
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("open %s: %w", args[0], err)
+				}
+				defer f.Close()
+				r = f
+			}
+
+			message, err := db.AnalyzeLog(database, v.GetString("format"), r, workload.Options{
+				OrderBy: v.GetString("order-by"),
+				Limit:   v.GetInt("limit"),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(message)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("db-uri", "", "database connection URI to load the schema from")
+	cmd.Flags().String("format", querylog.FormatMySQLSlow, "log format")
+	cmd.Flags().String("order-by", workload.OrderByTotalTime, "measurement to pick and rank the top statements by")
+	cmd.Flags().Int("limit", workload.DefaultLimit, "number of statements to plan")
+
+	return cmd
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func BatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch <file>",
+		Short: "Plan the transactions in a SQL file",
+		Long: `Plan the transactions in a SQL file. The statements between BEGIN and COMMIT
+are planned as one transaction: the locks each statement takes are reported,
+along with the lock orderings that deadlock or block other transactions in the
+file. Use - to read the file from stdin.`,
+		Args: cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			uri, err := connectionURI(v)
+			if err != nil {
+				return err
+			}
+			if uri == "" {
+				return fmt.Errorf("a database connection URI is required, use --db-uri or QP_DB_URI")
+			}
+
+			script, err := readScript(args[0])
+			if err != nil {
+				return err
+			}
+
+			database, err := db.Connect(uri)
+			if err != nil {
+				return err
+			}
+			if err := applyIssueFilter(v, database); err != nil {
+				return err
+			}
+
+			message, err := db.PlanScript(database, script)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(message)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("db-uri", "", "database connection URI")
+
+	return cmd
+}
+
+func readScript(path string) (string, error) {
+	if path == "-" {
+		script, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("read stdin: %w", err)
+		}
+		return string(script), nil
+	}
+
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	return string(script), nil
+}
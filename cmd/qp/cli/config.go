@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/queryplan-ai/qp/pkg/config"
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/spf13/viper"
+)
+
+var (
+	// profileName is the --profile flag. It's needed before the flags are
+	// bound to viper, to set the defaults the profile gives them.
+	profileName string
+
+	// activeProfile is the name of the profile in use, if any
+	activeProfile string
+	// profile is the profile in use. Its connection URI is only resolved
+	// by the commands that connect, since that can need a secret.
+	profile config.Profile
+)
+
+// initProfile loads the config file and makes the settings of the selected
+// profile the defaults, so flags and environment variables still override
+// them. The profile is the one named by --profile or QP_PROFILE, or the
+// default profile of the config file. Its connection URI isn't resolved here,
+// see connectionURI.
+func initProfile() error {
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return err
+	}
+
+	name := profileName
+	if name == "" {
+		name = viper.GetString("profile")
+	}
+	if name == "" {
+		name = cfg.DefaultProfile
+	}
+	if name == "" {
+		return nil
+	}
+
+	selected, err := cfg.Profile(name)
+	if err != nil {
+		return err
+	}
+	profile = selected
+
+	if profile.Schema != "" {
+		viper.SetDefault("schema", profile.SchemaPath())
+	}
+	if profile.Output != "" {
+		viper.SetDefault("output", profile.Output)
+	}
+	if profile.Severity != "" {
+		viper.SetDefault("severity", profile.Severity)
+	}
+	if len(profile.Ignore) > 0 {
+		viper.SetDefault("ignore", profile.Ignore)
+	}
+
+	activeProfile = name
+	return nil
+}
+
+// connectionURI returns the URI of the database to connect to, from --db-uri
+// or QP_DB_URI, or else from the profile, whose password is read here rather
+// than when every command starts.
+func connectionURI(v *viper.Viper) (string, error) {
+	if uri := v.GetString("db-uri"); uri != "" || activeProfile == "" {
+		return uri, nil
+	}
+
+	uri, err := profile.ConnectionURI()
+	if err != nil {
+		return "", fmt.Errorf("profile %s: %w", activeProfile, err)
+	}
+	return uri, nil
+}
+
+// issueFilter returns the filter for the issues to report, from --severity
+// and --ignore or the profile.
+func issueFilter(v *viper.Viper) (issuetypes.Filter, error) {
+	severity, err := issuetypes.ParseSeverity(v.GetString("severity"))
+	if err != nil {
+		return issuetypes.Filter{}, err
+	}
+
+	return issuetypes.Filter{
+		MinSeverity:  severity,
+		IgnoredTypes: v.GetStringSlice("ignore"),
+	}, nil
+}
+
+// applyIssueFilter sets the issue filter on a database.
+func applyIssueFilter(v *viper.Viper, database *types.DB) error {
+	filter, err := issueFilter(v)
+	if err != nil {
+		return err
+	}
+	database.IssueFilter = filter
+	return nil
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
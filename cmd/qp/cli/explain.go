@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/queryplan-ai/qp/pkg/plan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func ExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <query>",
+		Short: "Show the plan the database picks for a query",
+		Long: `Show the plan the database picks for a query, without running it. Use --param
+once for each parameter of the query, in order, to plan it with those values.
+Without values Postgres 16 and later show a generic plan, and older versions
+and MySQL plan the query with a representative value of each parameter's
+type, inferred from the column it's compared with.`,
+		Args: cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			uri, err := connectionURI(v)
+			if err != nil {
+				return err
+			}
+			if uri == "" {
+				return fmt.Errorf("a database connection URI is required, use --db-uri or QP_DB_URI")
+			}
+
+			database, err := db.Connect(uri)
+			if err != nil {
+				return err
+			}
+
+			// read from the flag rather than viper, which splits values on
+			// commas
+			params, err := cmd.Flags().GetStringArray("param")
+			if err != nil {
+				return err
+			}
+			values := []string{}
+			for _, value := range params {
+				values = append(values, plan.ParameterLiteral(value))
+			}
+
+			message, err := db.Explain(database, args[0], values)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(message)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("db-uri", "", "database connection URI")
+	cmd.Flags().StringArray("param", nil, "value of the next parameter of the query")
+
+	return cmd
+}
@@ -21,15 +21,30 @@ func RootCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			v := viper.GetViper()
 
+			filter, err := issueFilter(v)
+			if err != nil {
+				return err
+			}
+
 			opts := shelltypes.ShellOpts{
-				ConnectionURI: v.GetString("db-uri"),
-				OpenAIAPIKey:  v.GetString("openai-api-key"),
+				ConnectionName: activeProfile,
+				OpenAIAPIKey:   v.GetString("openai-api-key"),
+				IssueFilter:    filter,
 			}
 
-			// parse the args, args[0] should be the connection string, but it's optional
-			// and overrides the env var if one one set
-			if len(args) > 0 {
+			// parse the args, args[0] should be the connection string, or
+			// @profile, but it's optional and overrides the env var if one
+			// one set. Without either, the shell connects with the profile,
+			// which resolves its URI or loads its schema snapshot
+			switch {
+			case len(args) > 0:
 				opts.ConnectionURI = args[0]
+				opts.ConnectionName = ""
+			case v.GetString("db-uri") != "":
+				opts.ConnectionURI = v.GetString("db-uri")
+			case activeProfile != "":
+				opts.ConnectionURI = "@" + activeProfile
+				opts.ConnectionName = ""
 			}
 
 			if err := shell.RunShell(opts); err != nil {
@@ -43,8 +58,17 @@ func RootCmd() *cobra.Command {
 	cobra.OnInitialize(initConfig)
 
 	cmd.AddCommand(VersionCmd())
+	cmd.AddCommand(BatchCmd())
+	cmd.AddCommand(WorkloadCmd())
+	cmd.AddCommand(AnalyzeLogCmd())
+	cmd.AddCommand(ScanCodeCmd())
+	cmd.AddCommand(SnapshotCmd())
+	cmd.AddCommand(ExplainCmd())
 
 	cmd.PersistentFlags().String("log-level", "info", "log level")
+	cmd.PersistentFlags().StringVar(&profileName, "profile", "", "profile of the config file to use")
+	cmd.PersistentFlags().String("severity", "", "lowest severity of the issues to report: low, medium or high")
+	cmd.PersistentFlags().StringSlice("ignore", nil, "types of the issues not to report")
 
 	cmd.Flags().String("db-uri", "", "database connection URI to automatically use")
 	cmd.Flags().String("openai-api-key", "", "OpenAI API key to use")
@@ -64,4 +88,6 @@ func InitAndExecute() {
 func initConfig() {
 	viper.SetEnvPrefix("QP")
 	viper.AutomaticEnv()
+
+	exitOnError(initProfile())
 }
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/queryplan-ai/qp/pkg/codescan"
+	"github.com/queryplan-ai/qp/pkg/config"
+	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func ScanCodeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan-code [path...]",
+		Short: "Plan the SQL queries in Go source code",
+		Long: `Plan the SQL queries in Go source code. The queries are the string literals
+and constants passed to the Query, QueryRow, Exec and Prepare methods of
+database/sql, sqlx and pgx, and their Context variants. Issues are reported at
+the file:line:column of the call. A path is a file, a directory, or a directory
+followed by /... to scan the packages below it; the default is ./...
+
+The schema is loaded from the database with --db-uri, or from a snapshot
+written by qp snapshot with --schema.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			uri, err := connectionURI(v)
+			if err != nil {
+				return err
+			}
+
+			database, err := loadSchema(uri, v.GetString("schema"))
+			if err != nil {
+				return err
+			}
+			if err := applyIssueFilter(v, database); err != nil {
+				return err
+			}
+
+			output := v.GetString("output")
+			if output != config.OutputText && output != config.OutputJSON {
+				return fmt.Errorf("unknown output %q, use %s or %s", output, config.OutputText, config.OutputJSON)
+			}
+
+			paths := args
+			if len(paths) == 0 {
+				paths = []string{"./..."}
+			}
+
+			queries, err := codescan.Scan(paths, codescan.Options{
+				IncludeTests: v.GetBool("tests"),
+			})
+			if err != nil {
+				return err
+			}
+
+			report := db.ScanCode(database, queries)
+			if output == config.OutputJSON {
+				b, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("marshal report: %w", err)
+				}
+				fmt.Println(string(b))
+			} else {
+				fmt.Println(report.String())
+			}
+
+			if report.IssueCount() > 0 && v.GetBool("fail") {
+				os.Exit(1)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("db-uri", "", "database connection URI to load the schema from")
+	cmd.Flags().String("schema", "", "schema snapshot file to plan against instead of a database")
+	cmd.Flags().Bool("tests", false, "scan _test.go files too")
+	cmd.Flags().Bool("fail", false, "exit with status 1 when issues are found")
+	cmd.Flags().String("output", config.OutputText, "format of the report, text or json")
+
+	return cmd
+}
+
+func SnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot <file>",
+		Short: "Save the schema of a database to a file",
+		Long: `Save the schema of a database to a file, so queries can be planned against it
+without a connection, for example by qp scan-code --schema in CI. The file
+doesn't include the connection URI. Use - to write to stdout.`,
+		Args: cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			uri, err := connectionURI(v)
+			if err != nil {
+				return err
+			}
+			if uri == "" {
+				return fmt.Errorf("a database connection URI is required, use --db-uri or QP_DB_URI")
+			}
+
+			database, err := db.Connect(uri)
+			if err != nil {
+				return err
+			}
+
+			if args[0] == "-" {
+				return db.SaveSnapshot(database, os.Stdout)
+			}
+
+			f, err := os.Create(args[0])
+			if err != nil {
+				return fmt.Errorf("create %s: %w", args[0], err)
+			}
+			defer f.Close()
+
+			return db.SaveSnapshot(database, f)
+		},
+	}
+
+	cmd.Flags().String("db-uri", "", "database connection URI")
+
+	return cmd
+}
+
+// loadSchema connects to the database at the URI, or reads the schema snapshot
+// when there's no URI.
+func loadSchema(uri string, snapshotPath string) (*types.DB, error) {
+	switch {
+	case uri != "":
+		return db.Connect(uri)
+	case snapshotPath != "":
+		f, err := os.Open(snapshotPath)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", snapshotPath, err)
+		}
+		defer f.Close()
+		return db.LoadSnapshot(f)
+	}
+
+	return nil, fmt.Errorf("a schema is required, use --db-uri, QP_DB_URI or --schema")
+}
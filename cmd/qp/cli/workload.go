@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/queryplan-ai/qp/pkg/workload"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func WorkloadCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workload",
+		Short: "Plan the statements the database spends the most time on",
+		Long: `Plan the statements the database spends the most time on, as recorded by
+pg_stat_statements on Postgres or the performance_schema statement digests on
+MySQL, and report their issues ranked by what the statements cost. Use
+--order-by to pick the top statements by total-time, mean-time, calls or rows,
+and blocks-read on Postgres or examined-ratio (rows examined for every row
+returned) on MySQL.`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			uri, err := connectionURI(v)
+			if err != nil {
+				return err
+			}
+			if uri == "" {
+				return fmt.Errorf("a database connection URI is required, use --db-uri or QP_DB_URI")
+			}
+
+			database, err := db.Connect(uri)
+			if err != nil {
+				return err
+			}
+			if err := applyIssueFilter(v, database); err != nil {
+				return err
+			}
+
+			message, err := db.AnalyzeWorkload(database, workload.Options{
+				OrderBy: v.GetString("order-by"),
+				Limit:   v.GetInt("limit"),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(message)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("db-uri", "", "database connection URI")
+	cmd.Flags().String("order-by", workload.OrderByTotalTime, "measurement to pick and rank the top statements by")
+	cmd.Flags().Int("limit", workload.DefaultLimit, "number of statements to plan")
+
+	return cmd
+}
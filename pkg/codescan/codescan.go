@@ -0,0 +1,289 @@
+package codescan
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// methods are the names of the database/sql, sqlx and pgx methods that take a
+// query. The query isn't always the first argument: pgx and the Context
+// variants take a context first, and sqlx's Get and Select take the
+// destination first, so the query is the first argument that is a constant
+// string.
+var methods = map[string]bool{
+	"Query": true, "QueryContext": true, "QueryRow": true, "QueryRowContext": true,
+	"Exec": true, "ExecContext": true, "Prepare": true, "PrepareContext": true,
+	"Queryx": true, "QueryxContext": true, "QueryRowx": true, "QueryRowxContext": true,
+	"Get": true, "GetContext": true, "Select": true, "SelectContext": true,
+	"MustExec": true, "MustExecContext": true, "Preparex": true, "PreparexContext": true,
+	"NamedExec": true, "NamedExecContext": true, "NamedQuery": true, "NamedQueryContext": true,
+}
+
+// statementKeywords are the words a string has to start with to be a query.
+// Get and Select are common method names, so strings that aren't SQL are
+// skipped.
+var statementKeywords = map[string]bool{
+	"select": true, "insert": true, "update": true, "delete": true, "with": true,
+	"create": true, "alter": true, "drop": true, "truncate": true, "replace": true,
+	"lock": true, "vacuum": true, "analyze": true, "reindex": true, "cluster": true,
+}
+
+// Query is a SQL statement found in Go source.
+type Query struct {
+	Position token.Position
+	// Method is the name of the method the query is passed to
+	Method string
+	SQL    string
+}
+
+// Options selects the files to scan.
+type Options struct {
+	IncludeTests bool
+}
+
+// Scan finds the queries passed as constant strings to database methods in
+// the Go files of the given paths. A path is a file, a directory, or a
+// directory followed by /... to include the directories below it. Vendored
+// code, testdata and hidden directories are skipped.
+func Scan(paths []string, opts Options) ([]Query, error) {
+	dirs := map[string][]string{}
+	addFile := func(path string) {
+		if !strings.HasSuffix(path, ".go") || (!opts.IncludeTests && strings.HasSuffix(path, "_test.go")) {
+			return
+		}
+		dirs[filepath.Dir(path)] = append(dirs[filepath.Dir(path)], path)
+	}
+
+	for _, path := range paths {
+		recursive := false
+		if path == "..." || strings.HasSuffix(path, "/...") {
+			recursive = true
+			path = strings.TrimSuffix(strings.TrimSuffix(path, "..."), "/")
+			if path == "" {
+				path = "."
+			}
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			addFile(path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(walked string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if walked == path {
+					return nil
+				}
+				if !recursive || skipDir(entry.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			addFile(walked)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walk %s: %w", path, err)
+		}
+	}
+
+	dirNames := []string{}
+	for dir := range dirs {
+		dirNames = append(dirNames, dir)
+	}
+	sort.Strings(dirNames)
+
+	queries := []Query{}
+	for _, dir := range dirNames {
+		found, err := scanPackage(dirs[dir])
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, found...)
+	}
+
+	return queries, nil
+}
+
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// scanPackage finds the queries in the files of one directory. The files are
+// parsed together, so a query can be a constant declared in another file of
+// the package.
+func scanPackage(files []string) ([]Query, error) {
+	sort.Strings(files)
+
+	fset := token.NewFileSet()
+	parsed := []*ast.File{}
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		parsed = append(parsed, f)
+	}
+
+	// package level constants are shared by all files of a package, while
+	// the constants of a function are only seen by it. Names are resolved to
+	// the closest declaration by collecting the function constants as the
+	// function is walked.
+	packageConstants := map[string]ast.Expr{}
+	for _, f := range parsed {
+		for _, decl := range f.Decls {
+			if genDecl, ok := decl.(*ast.GenDecl); ok {
+				collectConstants(genDecl, packageConstants)
+			}
+		}
+	}
+
+	queries := []Query{}
+	for _, f := range parsed {
+		for _, decl := range f.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Body == nil {
+				continue
+			}
+
+			constants := map[string]ast.Expr{}
+			for name, expr := range packageConstants {
+				constants[name] = expr
+			}
+
+			ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.DeclStmt:
+					if genDecl, ok := node.Decl.(*ast.GenDecl); ok {
+						collectConstants(genDecl, constants)
+					}
+				case *ast.CallExpr:
+					if query, ok := queryOfCall(node, constants); ok {
+						query.Position = fset.Position(node.Pos())
+						queries = append(queries, query)
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	return queries, nil
+}
+
+// collectConstants adds the constants a declaration declares with an explicit
+// value.
+func collectConstants(genDecl *ast.GenDecl, constants map[string]ast.Expr) {
+	if genDecl.Tok != token.CONST {
+		return
+	}
+	for _, spec := range genDecl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok || len(valueSpec.Values) != len(valueSpec.Names) {
+			continue
+		}
+		for i, name := range valueSpec.Names {
+			constants[name.Name] = valueSpec.Values[i]
+		}
+	}
+}
+
+// queryOfCall returns the query passed to a call, if the call is to a database
+// method and one of its arguments is a constant string that is SQL.
+func queryOfCall(call *ast.CallExpr, constants map[string]ast.Expr) (Query, bool) {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !methods[selector.Sel.Name] {
+		return Query{}, false
+	}
+
+	for _, arg := range call.Args {
+		value, ok := constantString(arg, constants, 0)
+		if !ok {
+			continue
+		}
+		if !isSQL(value) {
+			return Query{}, false
+		}
+		return Query{
+			Method: selector.Sel.Name,
+			SQL:    strings.TrimSpace(value),
+		}, true
+	}
+
+	return Query{}, false
+}
+
+// constantString returns the value of an expression that is a string literal,
+// a constant, or a concatenation of them.
+func constantString(expr ast.Expr, constants map[string]ast.Expr, depth int) (string, bool) {
+	// constants that refer to each other in a cycle don't compile, but the
+	// depth keeps a broken file from recursing forever
+	if depth > 32 {
+		return "", false
+	}
+
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		if expr.Kind != token.STRING {
+			return "", false
+		}
+		value, err := strconv.Unquote(expr.Value)
+		if err != nil {
+			return "", false
+		}
+		return value, true
+	case *ast.Ident:
+		value, ok := constants[expr.Name]
+		if !ok {
+			return "", false
+		}
+		return constantString(value, constants, depth+1)
+	case *ast.ParenExpr:
+		return constantString(expr.X, constants, depth+1)
+	case *ast.BinaryExpr:
+		if expr.Op != token.ADD {
+			return "", false
+		}
+		left, ok := constantString(expr.X, constants, depth+1)
+		if !ok {
+			return "", false
+		}
+		right, ok := constantString(expr.Y, constants, depth+1)
+		if !ok {
+			return "", false
+		}
+		return left + right, true
+	}
+
+	return "", false
+}
+
+func isSQL(value string) bool {
+	for _, token := range lexer.Tokenize(value) {
+		switch token.Type {
+		case lexer.Comment:
+			continue
+		case lexer.Word:
+			return statementKeywords[strings.ToLower(token.Value)]
+		}
+		return false
+	}
+	return false
+}
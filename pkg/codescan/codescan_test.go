@@ -0,0 +1,82 @@
+package codescan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const queriesFile = `package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+const userColumns = "id, email"
+
+func Users(ctx context.Context, db *sql.DB, pool Pool, id int) error {
+	const byID = "select " + userColumns + " from users where id = $1"
+
+	db.QueryRow(byID, id)
+	db.ExecContext(ctx, ` + "`" + `
+		update users set email = $1
+	` + "`" + `, "a@example.com")
+	pool.Query(ctx, ordersByStatus, "open")
+	db.Query(fmt.Sprintf("select * from %s", "users"))
+	cache.Get("users:1")
+
+	return nil
+}
+`
+
+const constantsFile = `package store
+
+const ordersByStatus = "select * from orders where status = $1"
+`
+
+const testFile = `package store
+
+func TestUsers(t *testing.T) {
+	db.Exec("delete from users")
+}
+`
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "internal", "store")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "vendor", "lib"), 0o755))
+
+	require.NoError(t, os.WriteFile(filepath.Join(nested, "users.go"), []byte(queriesFile), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(nested, "queries.go"), []byte(constantsFile), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(nested, "users_test.go"), []byte(testFile), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor", "lib", "lib.go"), []byte(testFile), 0o644))
+
+	queries, err := Scan([]string{dir + "/..."}, Options{})
+	require.NoError(t, err)
+	require.Len(t, queries, 3)
+
+	assert.Equal(t, "QueryRow", queries[0].Method)
+	assert.Equal(t, "select id, email from users where id = $1", queries[0].SQL)
+	assert.Equal(t, filepath.Join(nested, "users.go"), queries[0].Position.Filename)
+	assert.Equal(t, 13, queries[0].Position.Line)
+
+	assert.Equal(t, "ExecContext", queries[1].Method)
+	assert.Equal(t, "update users set email = $1", queries[1].SQL)
+
+	assert.Equal(t, "Query", queries[2].Method)
+	assert.Equal(t, "select * from orders where status = $1", queries[2].SQL)
+
+	queries, err = Scan([]string{nested}, Options{IncludeTests: true})
+	require.NoError(t, err)
+	assert.Len(t, queries, 4)
+
+	// without /... the directories below aren't scanned
+	queries, err = Scan([]string{dir}, Options{})
+	require.NoError(t, err)
+	assert.Len(t, queries, 0)
+}
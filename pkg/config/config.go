@@ -0,0 +1,238 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adrg/xdg"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Config is the qp configuration file, which holds named profiles:
+//
+//	default-profile: local
+//	profiles:
+//	  prod:
+//	    uri: postgres://app@db.internal:5432/app
+//	    password-env: PROD_DB_PASSWORD
+//	    severity: medium
+//	    ignore: [limit_without_order_by]
+//	  ci:
+//	    schema: ./schema.json
+//	    output: json
+type Config struct {
+	// DefaultProfile is the profile used when none is selected
+	DefaultProfile string             `yaml:"default-profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// Profile is a named set of settings to connect and plan with.
+type Profile struct {
+	// URI is the connection URI. It doesn't need a password: the password
+	// is read from PasswordEnv when it's set, from ~/.my.cnf for MySQL, and
+	// Postgres reads ~/.pgpass, or the file PGPASSFILE names, by itself.
+	URI         string `yaml:"uri"`
+	PasswordEnv string `yaml:"password-env"`
+	// Schema is a snapshot file to plan against instead of connecting
+	Schema string `yaml:"schema"`
+	// Output is the format of the reports, text or json
+	Output string `yaml:"output"`
+	// Severity is the lowest severity of the issues that are reported
+	Severity string `yaml:"severity"`
+	// Ignore are the types of the issues that aren't reported
+	Ignore []string `yaml:"ignore"`
+}
+
+// DefaultPath returns the path of the configuration file,
+// $XDG_CONFIG_HOME/qp/config.yaml.
+func DefaultPath() string {
+	return filepath.Join(xdg.ConfigHome, "qp", "config.yaml")
+}
+
+// Load reads a configuration file. A file that doesn't exist is an empty
+// configuration.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	for name, profile := range config.Profiles {
+		if err := profile.validate(); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	if config.DefaultProfile != "" {
+		if _, ok := config.Profiles[config.DefaultProfile]; !ok {
+			return nil, fmt.Errorf("default profile %s isn't defined", config.DefaultProfile)
+		}
+	}
+
+	return config, nil
+}
+
+func (p Profile) validate() error {
+	switch p.Output {
+	case "", OutputText, OutputJSON:
+	default:
+		return fmt.Errorf("unknown output %q, use %s or %s", p.Output, OutputText, OutputJSON)
+	}
+
+	if _, err := issuetypes.ParseSeverity(p.Severity); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Profile returns the profile with a name.
+func (c *Config) Profile(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		names := []string{}
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if len(names) == 0 {
+			return Profile{}, fmt.Errorf("profile %s not found, %s has no profiles", name, DefaultPath())
+		}
+		return Profile{}, fmt.Errorf("profile %s not found, the profiles are %s", name, strings.Join(names, ", "))
+	}
+
+	return profile, nil
+}
+
+// IssueFilter returns the filter for the issues the profile reports.
+func (p Profile) IssueFilter() issuetypes.Filter {
+	return issuetypes.Filter{
+		MinSeverity:  p.Severity,
+		IgnoredTypes: p.Ignore,
+	}
+}
+
+// SchemaPath returns the path of the schema snapshot, with a leading ~
+// expanded to the home directory.
+func (p Profile) SchemaPath() string {
+	return expandHome(p.Schema)
+}
+
+// ConnectionURI returns the URI of the profile, with the password filled in
+// from where the profile says it comes from if the URI has none.
+func (p Profile) ConnectionURI() (string, error) {
+	if p.URI == "" {
+		return "", nil
+	}
+
+	uri, err := url.Parse(p.URI)
+	if err != nil {
+		return "", fmt.Errorf("parse uri: %w", err)
+	}
+	if _, hasPassword := uri.User.Password(); hasPassword {
+		return p.URI, nil
+	}
+
+	username := uri.User.Username()
+	password := ""
+	switch {
+	case p.PasswordEnv != "":
+		value, ok := os.LookupEnv(p.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("the password environment variable %s isn't set", p.PasswordEnv)
+		}
+		password = value
+
+	case uri.Scheme == "mysql":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return p.URI, nil
+		}
+		options, err := readMySQLOptionFile(filepath.Join(home, ".my.cnf"))
+		if err != nil {
+			return "", err
+		}
+		if options["password"] == "" {
+			return p.URI, nil
+		}
+		password = options["password"]
+		if username == "" {
+			username = options["user"]
+		}
+
+	default:
+		return p.URI, nil
+	}
+
+	uri.User = url.UserPassword(username, password)
+	return uri.String(), nil
+}
+
+// readMySQLOptionFile returns the options of the [client] group of a MySQL
+// option file, such as ~/.my.cnf. A file that doesn't exist has no options.
+func readMySQLOptionFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	options := map[string]string{}
+	inClient := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inClient = strings.EqualFold(strings.TrimSpace(line[1:len(line)-1]), "client")
+			continue
+		}
+		if !inClient {
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ReplaceAll(strings.TrimSpace(key), "-", "_")
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		options[key] = value
+	}
+
+	return options, nil
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
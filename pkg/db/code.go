@@ -0,0 +1,97 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/codescan"
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/workload"
+)
+
+// CodeIssue is an issue with a query found in Go source, or the error that
+// kept it from being planned.
+type CodeIssue struct {
+	// Position is the file:line:column of the call that runs the query
+	Position string `json:"position"`
+	Method   string `json:"method"`
+	Severity string `json:"severity,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CodeReport is the result of planning the queries found in Go source. The
+// issues are in the order the queries were found, and a query that couldn't be
+// planned is an issue with an Error.
+type CodeReport struct {
+	Queries int         `json:"queries"`
+	Issues  []CodeIssue `json:"issues"`
+}
+
+// ScanCode plans the queries found in Go source, and reports their issues at
+// the position of the call that runs them.
+func ScanCode(db *types.DB, queries []codescan.Query) *CodeReport {
+	report := &CodeReport{
+		Queries: len(queries),
+		Issues:  []CodeIssue{},
+	}
+
+	for _, query := range queries {
+		queryIssues, err := workload.ScanSafely(func(query string) ([]issuetypes.QueryIssue, error) {
+			return ScanQueryForIssues(db, query)
+		}, query.SQL)
+		if err != nil {
+			report.Issues = append(report.Issues, CodeIssue{
+				Position: query.Position.String(),
+				Method:   query.Method,
+				Error:    err.Error(),
+			})
+			continue
+		}
+
+		for _, queryIssue := range queryIssues {
+			report.Issues = append(report.Issues, CodeIssue{
+				Position: query.Position.String(),
+				Method:   query.Method,
+				Severity: queryIssue.IssueSeverity,
+				Type:     queryIssue.IssueType,
+				Message:  queryIssue.Message,
+			})
+		}
+	}
+
+	return report
+}
+
+// IssueCount returns the number of issues, without the queries that couldn't
+// be planned.
+func (r *CodeReport) IssueCount() int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Error == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// String formats the report in the file:line:column form editors and CI
+// annotations understand.
+func (r *CodeReport) String() string {
+	var b strings.Builder
+
+	for _, issue := range r.Issues {
+		if issue.Error != "" {
+			fmt.Fprintf(&b, "%s: could not plan query passed to %s: %s\n", issue.Position, issue.Method, issue.Error)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: [%s] %s\n", issue.Position, issue.Severity, issue.Message)
+	}
+
+	issueCount := r.IssueCount()
+	fmt.Fprintf(&b, "%d queries, %d issues, %d queries could not be planned", r.Queries, issueCount, len(r.Issues)-issueCount)
+
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/queryplan-ai/qp/pkg/db/types"
+)
+
+// Connect verifies the connection to a database and loads its schema.
+func Connect(uri string) (*types.DB, error) {
+	database := &types.DB{
+		ConnectionURI: uri,
+	}
+
+	var err error
+	switch dbEngine(database) {
+	case "mysql":
+		database.DatabaseName, err = VerifyMysqlConnection(uri)
+	case "postgres":
+		database.DatabaseName, err = VerifyPGConnection(uri)
+	default:
+		return nil, fmt.Errorf("unsupported connection scheme")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	if err := LoadSchema(database); err != nil {
+		return nil, fmt.Errorf("load schema: %w", err)
+	}
+
+	return database, nil
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/fingerprint"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/mysql"
+	"github.com/queryplan-ai/qp/pkg/pg"
+	"github.com/queryplan-ai/qp/pkg/querylog"
+	"github.com/queryplan-ai/qp/pkg/workload"
+)
+
+// AnalyzeLog aggregates the statements of a query log by their fingerprint,
+// plans the ones that took the most time, and reports their issues ranked by
+// what the statements cost. The database is optional: without one the
+// statements are planned without a schema, which only finds the issues that
+// don't depend on tables and indexes. The plans auto_explain logged to a
+// Postgres log are analyzed too.
+func AnalyzeLog(db *types.DB, format string, r io.Reader, opts workload.Options) (string, error) {
+	if opts.OrderBy == "" {
+		opts.OrderBy = workload.OrderByTotalTime
+	}
+
+	if db == nil {
+		db = &types.DB{}
+	}
+
+	aggregator := querylog.NewAggregator()
+	if err := querylog.Parse(format, r, aggregator.Add); err != nil {
+		return "", fmt.Errorf("parse log: %w", err)
+	}
+
+	statements := workload.Top(aggregator.Statements(), opts)
+
+	var scan workload.Scanner
+	switch querylog.Engine(format) {
+	case "mysql":
+		scan = func(query string) ([]issuetypes.QueryIssue, error) {
+			return mysql.ScanQueryForIssues(db, query)
+		}
+	case "postgres":
+		plans := map[string]string{}
+		for _, statement := range statements {
+			plans[statement.Query] = statement.Plan
+		}
+		scan = func(query string) ([]issuetypes.QueryIssue, error) {
+			return scanPostgresLogStatement(db, query, plans[query])
+		}
+	}
+
+	return workload.FormatReport(workload.Analyze(statements, scan, opts.OrderBy)), nil
+}
+
+// scanPostgresLogStatement returns the issues planning a statement finds,
+// followed by the issues its logged plan shows. A statement that can't be
+// planned is still reported when its plan has issues.
+func scanPostgresLogStatement(db *types.DB, query string, plan string) ([]issuetypes.QueryIssue, error) {
+	queryIssues, err := pg.ScanQueryForIssues(db, query)
+	if plan == "" {
+		return queryIssues, err
+	}
+
+	root, _, parseErr := pg.ParseExplain(plan)
+	if parseErr != nil {
+		return queryIssues, err
+	}
+	planIssues := db.IssueFilter.Apply(pg.ScanExplainForIssues(root))
+	if len(planIssues) == 0 {
+		return queryIssues, err
+	}
+
+	queryID := fingerprint.Fingerprint(query)
+	for i := range planIssues {
+		planIssues[i].QueryID = queryID
+	}
+
+	return append(queryIssues, planIssues...), nil
+}
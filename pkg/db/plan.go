@@ -1,9 +1,13 @@
 package db
 
 import (
+	"fmt"
+
 	"github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
 	"github.com/queryplan-ai/qp/pkg/mysql"
 	"github.com/queryplan-ai/qp/pkg/pg"
+	"github.com/queryplan-ai/qp/pkg/plan"
 )
 
 func PlanQuery(db *types.DB, query string) (string, error) {
@@ -16,3 +20,46 @@ func PlanQuery(db *types.DB, query string) (string, error) {
 
 	return "", nil
 }
+
+// ScanQueryForIssues returns the issues with a query under the rules of the
+// database's engine, or nil if the statement isn't one that's planned.
+func ScanQueryForIssues(db *types.DB, query string) ([]issuetypes.QueryIssue, error) {
+	switch dbEngine(db) {
+	case "mysql":
+		return mysql.ScanQueryForIssues(db, query)
+	case "postgres":
+		return pg.ScanQueryForIssues(db, query)
+	}
+
+	return nil, fmt.Errorf("unsupported database engine")
+}
+
+// Explain returns the plan the database picks for a query, with its parameters
+// bound to the values when they're given.
+func Explain(db *types.DB, query string, values []string) (string, error) {
+	if db.ConnectionURI == "" {
+		return "", ErrNotConnected
+	}
+
+	switch dbEngine(db) {
+	case "mysql":
+		return mysql.Explain(db, query, values)
+	case "postgres":
+		return pg.Explain(db, query, values)
+	}
+
+	return "", fmt.Errorf("unsupported database engine")
+}
+
+// EstimateLocks returns the locks a statement takes under the locking rules of
+// the database's engine.
+func EstimateLocks(db *types.DB, query string) ([]plan.Lock, error) {
+	switch dbEngine(db) {
+	case "mysql":
+		return mysql.EstimateLocks(db, query)
+	case "postgres":
+		return pg.EstimateLocks(db, query)
+	}
+
+	return plan.EstimateLocks(query, db.Tables)
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/mysql"
+	"github.com/queryplan-ai/qp/pkg/pg"
+)
+
+// Run executes a query on the database, in transactions that are rolled back.
+func Run(db *types.DB, query string, opts types.RunOptions) (*types.RunResult, error) {
+	if db.ConnectionURI == "" {
+		return nil, ErrNotConnected
+	}
+
+	switch dbEngine(db) {
+	case "mysql":
+		return mysql.Run(db, query, opts)
+	case "postgres":
+		return pg.Run(db, query, opts)
+	}
+
+	return nil, fmt.Errorf("unsupported database engine")
+}
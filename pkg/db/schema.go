@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"net/url"
 	"strings"
 
@@ -10,6 +11,12 @@ import (
 	"github.com/xo/dburl"
 )
 
+var (
+	// ErrNotConnected is returned for what needs a connection on a schema
+	// that was loaded from a snapshot
+	ErrNotConnected = fmt.Errorf("not connected to a database, the schema was loaded from a snapshot")
+)
+
 func DatabaseNameFromURI(uri string) (string, error) {
 	parsed, err := dburl.Parse(uri)
 	if err != nil {
@@ -19,16 +26,26 @@ func DatabaseNameFromURI(uri string) (string, error) {
 	return strings.TrimLeft(parsed.Path, "/"), nil
 }
 
-func LoadSchema(db *types.DB) {
+func LoadSchema(db *types.DB) error {
+	if db.ConnectionURI == "" {
+		return ErrNotConnected
+	}
+
 	switch dbEngine(db) {
 	case "mysql":
-		mysql.LoadSchema(db)
+		return mysql.LoadSchema(db)
 	case "postgres":
-		pg.LoadSchema(db)
+		return pg.LoadSchema(db)
 	}
+
+	return nil
 }
 
 func dbEngine(db *types.DB) string {
+	if db.Engine != "" {
+		return db.Engine
+	}
+
 	uri, err := url.Parse(db.ConnectionURI)
 	if err != nil {
 		return ""
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/mysql"
+	"github.com/queryplan-ai/qp/pkg/pg"
+)
+
+// Snapshot is the schema of a database saved to a file, so queries can be
+// planned against it without a connection. The connection URI isn't saved,
+// since it can hold a password.
+type Snapshot struct {
+	Engine         string             `json:"engine"`
+	DatabaseName   string             `json:"databaseName"`
+	ServerVersion  string             `json:"serverVersion"`
+	PostgresTables []pg.PostgresTable `json:"postgresTables,omitempty"`
+	MysqlTables    []mysql.MysqlTable `json:"mysqlTables,omitempty"`
+	Views          []types.View       `json:"views,omitempty"`
+}
+
+// SaveSnapshot writes the loaded schema of a database as JSON.
+func SaveSnapshot(db *types.DB, w io.Writer) error {
+	snapshot := Snapshot{
+		Engine:        dbEngine(db),
+		DatabaseName:  db.DatabaseName,
+		ServerVersion: db.ServerVersion,
+		Views:         db.Views,
+	}
+	if snapshot.Engine == "" {
+		return fmt.Errorf("unsupported database engine")
+	}
+
+	for _, table := range db.Tables {
+		switch table := table.(type) {
+		case pg.PostgresTable:
+			snapshot.PostgresTables = append(snapshot.PostgresTables, table)
+		case mysql.MysqlTable:
+			snapshot.MysqlTables = append(snapshot.MysqlTables, table)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot reads a schema written by SaveSnapshot.
+func LoadSnapshot(r io.Reader) (*types.DB, error) {
+	snapshot := Snapshot{}
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+
+	db := &types.DB{
+		Engine:        snapshot.Engine,
+		DatabaseName:  snapshot.DatabaseName,
+		ServerVersion: snapshot.ServerVersion,
+		Views:         snapshot.Views,
+		SchemaLoaded:  true,
+	}
+	switch snapshot.Engine {
+	case "postgres":
+		for _, table := range snapshot.PostgresTables {
+			db.Tables = append(db.Tables, table)
+		}
+	case "mysql":
+		for _, table := range snapshot.MysqlTables {
+			db.Tables = append(db.Tables, table)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported snapshot engine %q", snapshot.Engine)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+// PlanTransactions plans a batch of transactions, each a list of statements
+// that run between BEGIN and COMMIT, and reports the locks they take and how
+// they interact.
+func PlanTransactions(db *types.DB, transactions [][]string) (string, error) {
+	estimateLocks := func(query string) ([]plan.Lock, error) {
+		return EstimateLocks(db, query)
+	}
+
+	plans, issues, err := plan.AnalyzeTransactions(transactions, estimateLocks, plan.LargeTables(db.Tables))
+	if err != nil {
+		return "", fmt.Errorf("analyze transactions: %w", err)
+	}
+	issues = db.IssueFilter.Apply(issues)
+
+	var b strings.Builder
+	for i, transactionPlan := range plans {
+		fmt.Fprintf(&b, "Transaction %d:\n", i+1)
+		for j, statement := range transactionPlan.Statements {
+			fmt.Fprintf(&b, "  %d. %s\n", j+1, statement)
+			if len(transactionPlan.Locks[j]) == 0 {
+				b.WriteString("     no locks\n")
+			}
+			for _, lock := range transactionPlan.Locks[j] {
+				fmt.Fprintf(&b, "     %s\n", lock)
+			}
+		}
+	}
+
+	if len(issues) == 0 {
+		b.WriteString("No issues found")
+		return b.String(), nil
+	}
+
+	for _, issue := range issues {
+		b.WriteString(issue.Message + "\n")
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// PlanScript plans every transaction in a SQL script. Statements outside of a
+// BEGIN ... COMMIT block are planned as transactions of their own.
+func PlanScript(db *types.DB, script string) (string, error) {
+	transactions, err := plan.GroupTransactions(lexer.SplitStatements(script))
+	if err != nil {
+		return "", fmt.Errorf("group transactions: %w", err)
+	}
+
+	return PlanTransactions(db, transactions)
+}
@@ -1,19 +1,36 @@
 package types
 
+import (
+	"time"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+)
+
 type DB struct {
 	ConnectionURI string
 	DatabaseName  string
+	ServerVersion string
+	// Engine is the engine of a schema loaded from a snapshot, which has no
+	// connection URI to tell it from
+	Engine string
 
 	SchemaLoading bool
 	SchemaLoaded  bool
 
 	Tables []Table
+	Views  []View
+
+	// IssueFilter selects the issues that are reported for the database,
+	// as configured by a profile
+	IssueFilter issuetypes.Filter
 }
 
 type Table interface {
 	GetName() string
 	GetColumns() []Column
 	GetPrimaryKeys() []string
+	GetIndexes() []Index
+	GetForeignKeys() []ForeignKey
 	GetEstimatedRowCount() int64
 }
 
@@ -26,3 +43,50 @@ type Column interface {
 	GetColumnDefault() *string
 	GetExtra() string
 }
+
+// Index is a secondary index on a table. Primary keys are reported
+// separately by GetPrimaryKeys.
+type Index struct {
+	Name     string
+	Columns  []string
+	IsUnique bool
+}
+
+// ForeignKey is a foreign key constraint, from the columns of the table it's
+// on to the columns of the referenced table.
+type ForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+}
+
+// View is a view, or a materialized view, and the query that defines it.
+type View struct {
+	Name       string
+	Definition string
+}
+
+// RunOptions controls how a query is executed by Run.
+type RunOptions struct {
+	// ReadOnly runs the query in a read only transaction, so that a query
+	// that was expected to only read fails instead of writing
+	ReadOnly bool
+	// Runs is the number of times the query is executed
+	Runs int
+	// MaxRows is the number of rows of the result that are kept
+	MaxRows int
+}
+
+// RunResult is the result of executing a query. Every run is in a
+// transaction that's rolled back, so the data isn't changed.
+type RunResult struct {
+	Columns []string
+	// Rows are the first rows of the result, formatted as text
+	Rows [][]string
+	// RowCount is the number of rows returned, or affected by a statement
+	// that returns none
+	RowCount int64
+	// Durations is the time each run took
+	Durations []time.Duration
+}
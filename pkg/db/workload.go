@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/mysql"
+	"github.com/queryplan-ai/qp/pkg/pg"
+	"github.com/queryplan-ai/qp/pkg/workload"
+)
+
+// AnalyzeWorkload plans the statements the database spends the most time on,
+// according to the statistics it keeps, and reports their issues ranked by
+// what the statements cost.
+func AnalyzeWorkload(db *types.DB, opts workload.Options) (string, error) {
+	if db.ConnectionURI == "" {
+		return "", ErrNotConnected
+	}
+
+	if opts.OrderBy == "" {
+		opts.OrderBy = workload.OrderByTotalTime
+	}
+
+	var statements []workload.Statement
+	var scan workload.Scanner
+
+	switch dbEngine(db) {
+	case "postgres":
+		topStatements, err := pg.TopStatements(db, opts)
+		if err != nil {
+			return "", fmt.Errorf("top statements: %w", err)
+		}
+		statements = topStatements
+		scan = func(query string) ([]issuetypes.QueryIssue, error) {
+			return pg.ScanQueryForIssues(db, query)
+		}
+	case "mysql":
+		topStatements, err := mysql.TopStatements(db, opts)
+		if err != nil {
+			return "", fmt.Errorf("top statements: %w", err)
+		}
+		statements = topStatements
+		scan = func(query string) ([]issuetypes.QueryIssue, error) {
+			return mysql.ScanQueryForIssues(db, query)
+		}
+	default:
+		return "", fmt.Errorf("workload analysis is not supported for this database")
+	}
+
+	return workload.FormatReport(workload.Analyze(statements, scan, opts.OrderBy)), nil
+}
@@ -0,0 +1,190 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// Placeholder replaces every literal and bind parameter.
+const Placeholder = "?"
+
+// keywords are the words that are followed by a space before an opening
+// parenthesis. Any other word before a parenthesis is a function or a table
+// name, and is written without one.
+var keywords = map[string]bool{
+	"all": true, "and": true, "any": true, "as": true, "by": true, "else": true, "exists": true,
+	"filter": true, "from": true, "in": true, "into": true, "join": true, "lateral": true,
+	"not": true, "on": true, "or": true, "over": true, "select": true, "set": true, "some": true,
+	"then": true, "union": true, "using": true, "values": true, "when": true, "where": true,
+	"with": true, "like": true, "is": true, "between": true, "case": true, "return": true,
+	"returning": true, "having": true, "limit": true, "offset": true,
+}
+
+// Normalize returns the query with its literals replaced by placeholders, IN
+// lists collapsed to (...), only the first row of a multi-row VALUES, keywords and unquoted identifiers in
+// lower case and whitespace canonicalized. Queries that only differ in their
+// parameters normalize to the same text, which is what pg_stat_statements and
+// the MySQL statement digest do.
+func Normalize(query string) string {
+	tokens := normalizedTokens(lexer.Tokenize(query))
+	for len(tokens) > 0 && tokens[len(tokens)-1].IsPunctuation(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	var b strings.Builder
+	var previous *lexer.Token
+	write := func(token lexer.Token) {
+		if previous != nil && spaceBetween(*previous, token) {
+			b.WriteString(" ")
+		}
+		b.WriteString(token.Value)
+		previous = &token
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		switch {
+		case token.Is("in") && i+1 < len(tokens) && tokens[i+1].IsPunctuation("("):
+			if closing := matchingParen(tokens, i+1); closing > 0 && isValueList(tokens[i+2:closing]) {
+				write(token)
+				write(tokens[i+1])
+				b.WriteString("...")
+				write(tokens[closing])
+				i = closing
+				continue
+			}
+
+		case token.Is("values") && i+1 < len(tokens) && tokens[i+1].IsPunctuation("("):
+			write(token)
+			closing := matchingParen(tokens, i+1)
+			if closing < 0 {
+				continue
+			}
+			for _, rowToken := range tokens[i+1 : closing+1] {
+				write(rowToken)
+			}
+			i = closing
+
+			// the rows after the first are dropped, so a batch has the
+			// same fingerprint no matter how many rows it inserts
+			for i+2 < len(tokens) && tokens[i+1].IsPunctuation(",") && tokens[i+2].IsPunctuation("(") {
+				next := matchingParen(tokens, i+2)
+				if next < 0 {
+					break
+				}
+				i = next
+			}
+			continue
+		}
+
+		if isNegativeNumber(tokens, i) {
+			i++
+		}
+
+		write(tokens[i])
+	}
+
+	return b.String()
+}
+
+// Fingerprint returns a hash of the normalized query, as a 16 character hex
+// string. Like a pg_stat_statements queryid, it's a 64 bit hash that is the
+// same for every execution of a query no matter the parameters.
+func Fingerprint(query string) string {
+	sum := sha256.Sum256([]byte(Normalize(query)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// normalizedTokens drops comments, replaces literals with placeholders and
+// lowercases words.
+func normalizedTokens(tokens []lexer.Token) []lexer.Token {
+	normalized := make([]lexer.Token, 0, len(tokens))
+	for _, token := range tokens {
+		switch token.Type {
+		case lexer.Comment:
+			continue
+		case lexer.String, lexer.Number, lexer.Placeholder:
+			token.Type = lexer.Placeholder
+			token.Value = Placeholder
+		case lexer.Word:
+			token.Value = strings.ToLower(token.Value)
+		}
+		normalized = append(normalized, token)
+	}
+	return normalized
+}
+
+// isValueList returns true if the tokens are only placeholders and commas, or
+// a list that was already collapsed.
+func isValueList(tokens []lexer.Token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	for _, token := range tokens {
+		switch {
+		case token.Type == lexer.Placeholder:
+		case token.IsPunctuation(","), token.IsPunctuation("."), token.IsPunctuation("-"):
+		case token.Is("null"), token.Is("true"), token.Is("false"):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isNegativeNumber returns true if the token is a minus sign that belongs to
+// the number after it, rather than a subtraction.
+func isNegativeNumber(tokens []lexer.Token, i int) bool {
+	if !tokens[i].IsPunctuation("-") || i+1 >= len(tokens) || tokens[i+1].Type != lexer.Placeholder {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	previous := tokens[i-1]
+	switch previous.Type {
+	case lexer.Punctuation:
+		return !previous.IsPunctuation(")")
+	case lexer.Word:
+		return keywords[previous.Value]
+	}
+	return false
+}
+
+// spaceBetween returns true if two normalized tokens are written with a space
+// between them.
+func spaceBetween(previous lexer.Token, token lexer.Token) bool {
+	switch {
+	case token.IsPunctuation(","), token.IsPunctuation(")"), token.IsPunctuation("."), token.IsPunctuation("::"):
+		return false
+	case previous.IsPunctuation("("), previous.IsPunctuation("."), previous.IsPunctuation("::"):
+		return false
+	case token.IsPunctuation("("):
+		if previous.Type == lexer.Word {
+			return keywords[previous.Value]
+		}
+		return previous.Type != lexer.QuotedIdentifier
+	}
+	return true
+}
+
+func matchingParen(tokens []lexer.Token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].IsPunctuation("("):
+			depth++
+		case tokens[i].IsPunctuation(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,68 @@
+package fingerprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "literals and whitespace",
+			query: "SELECT *\n  FROM Users\tWHERE email = 'a@example.com' AND id > 10;",
+			want:  "select * from users where email = ? and id > ?",
+		},
+		{
+			name:  "bind parameters",
+			query: "select * from orders where user_id = $1 and status = :status",
+			want:  "select * from orders where user_id = ? and status = ?",
+		},
+		{
+			name:  "in list",
+			query: "select * from users where id in (1, 2, 3) and name not in ('a')",
+			want:  "select * from users where id in (...) and name not in (...)",
+		},
+		{
+			name:  "in subquery is kept",
+			query: "select * from users where id IN (select user_id from orders where total > -5)",
+			want:  "select * from users where id in (select user_id from orders where total > ?)",
+		},
+		{
+			name:  "multi-row insert",
+			query: "insert into users (email, name) values ('a', 'b'), ('c', 'd')",
+			want:  "insert into users(email, name) values (?, ?)",
+		},
+		{
+			name:  "functions, casts and comments",
+			query: "select COUNT(*) /* report */ from orders o where o.created_at > now() - '1 day'::interval -- last day",
+			want:  "select count(*) from orders o where o.created_at > now() - ?::interval",
+		},
+		{
+			name:  "quoted identifiers keep their case",
+			query: `select "Name" from "Users"`,
+			want:  `select "Name" from "Users"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Normalize(tt.query)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, got, Normalize(got), "normalizing is idempotent")
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint("select * from users where id in (1, 2)")
+	b := Fingerprint("SELECT * FROM users WHERE id IN (7, 8, 9, 10)")
+	c := Fingerprint("select * from users where email in (1, 2)")
+
+	assert.Len(t, a, 16)
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
@@ -0,0 +1,59 @@
+package types
+
+import "fmt"
+
+// Filter selects the issues that are reported: the ones at or above a
+// severity, and not of an ignored type. The zero Filter keeps every issue.
+type Filter struct {
+	MinSeverity  string
+	IgnoredTypes []string
+}
+
+// SeverityRank orders the severities from low to high. An unknown severity
+// ranks below low.
+func SeverityRank(severity string) int {
+	switch severity {
+	case IssueSeverityLow:
+		return 1
+	case IssueSeverityMedium:
+		return 2
+	case IssueSeverityHigh:
+		return 3
+	}
+	return 0
+}
+
+// ParseSeverity returns a severity given as an option, which is empty to keep
+// every severity.
+func ParseSeverity(severity string) (string, error) {
+	if severity != "" && SeverityRank(severity) == 0 {
+		return "", fmt.Errorf("unknown severity %q, use %s, %s or %s", severity, IssueSeverityLow, IssueSeverityMedium, IssueSeverityHigh)
+	}
+	return severity, nil
+}
+
+// Apply returns the issues the filter keeps. A nil list stays nil, since nil
+// means that a statement isn't planned.
+func (f Filter) Apply(issues []QueryIssue) []QueryIssue {
+	if issues == nil {
+		return nil
+	}
+
+	ignored := map[string]bool{}
+	for _, issueType := range f.IgnoredTypes {
+		ignored[issueType] = true
+	}
+
+	kept := []QueryIssue{}
+	for _, issue := range issues {
+		if ignored[issue.IssueType] {
+			continue
+		}
+		if f.MinSeverity != "" && SeverityRank(issue.IssueSeverity) < SeverityRank(f.MinSeverity) {
+			continue
+		}
+		kept = append(kept, issue)
+	}
+
+	return kept
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterApply(t *testing.T) {
+	issues := []QueryIssue{
+		{IssueType: QueryIssueTypeWhereClauseMissingIndex, IssueSeverity: IssueSeverityLow},
+		{IssueType: QueryIssueTypeLimitWithoutOrderBy, IssueSeverity: IssueSeverityMedium},
+		{IssueType: QueryIssueTypeMissingWhereClause, IssueSeverity: IssueSeverityHigh},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{
+			name:   "zero filter",
+			filter: Filter{},
+			want:   []string{QueryIssueTypeWhereClauseMissingIndex, QueryIssueTypeLimitWithoutOrderBy, QueryIssueTypeMissingWhereClause},
+		},
+		{
+			name:   "min severity",
+			filter: Filter{MinSeverity: IssueSeverityMedium},
+			want:   []string{QueryIssueTypeLimitWithoutOrderBy, QueryIssueTypeMissingWhereClause},
+		},
+		{
+			name:   "ignored types",
+			filter: Filter{IgnoredTypes: []string{QueryIssueTypeLimitWithoutOrderBy}},
+			want:   []string{QueryIssueTypeWhereClauseMissingIndex, QueryIssueTypeMissingWhereClause},
+		},
+		{
+			name:   "both",
+			filter: Filter{MinSeverity: IssueSeverityHigh, IgnoredTypes: []string{QueryIssueTypeMissingWhereClause}},
+			want:   []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept := []string{}
+			for _, issue := range test.filter.Apply(issues) {
+				kept = append(kept, issue.IssueType)
+			}
+			assert.Equal(t, test.want, kept)
+		})
+	}
+
+	assert.Nil(t, Filter{MinSeverity: IssueSeverityHigh}.Apply(nil))
+}
@@ -25,8 +25,34 @@ const (
 )
 
 const (
-	QueryIssueTypeWhereClauseMissingIndex = "where_clause_missing_index"
-	QueryIssueTypeJoinClauseMissingIndex  = "join_clause_missing_index"
-	QueryIssueTypeColumnUpdatedInIndex    = "column_updated_in_index"
-	QueryIssueTypeClauseMissingIndex      = "clause_missing_index"
+	QueryIssueTypeWhereClauseMissingIndex        = "where_clause_missing_index"
+	QueryIssueTypeJoinClauseMissingIndex         = "join_clause_missing_index"
+	QueryIssueTypeClauseMissingIndex             = "clause_missing_index"
+	QueryIssueTypeCartesianJoin                  = "cartesian_join"
+	QueryIssueTypeJoinTypeMismatch               = "join_type_mismatch"
+	QueryIssueTypeCorrelatedSubquery             = "correlated_subquery"
+	QueryIssueTypeNotInNullable                  = "not_in_nullable"
+	QueryIssueTypeMaterializedCTE                = "materialized_cte"
+	QueryIssueTypeOrPredicateMissingIndex        = "or_predicate_missing_index"
+	QueryIssueTypeMissingWhereClause             = "missing_where_clause"
+	QueryIssueTypeLimitWithoutOrderBy            = "limit_without_order_by"
+	QueryIssueTypeInsertMissingColumnList        = "insert_missing_column_list"
+	QueryIssueTypeMissingNotNullColumn           = "missing_not_null_column"
+	QueryIssueTypeInsertBatchTooLarge            = "insert_batch_too_large"
+	QueryIssueTypeInsertSelectFullScan           = "insert_select_full_scan"
+	QueryIssueTypeUpsertTargetMissingUniqueIndex = "upsert_target_missing_unique_index"
+	QueryIssueTypeAmbiguousUpsert                = "ambiguous_upsert"
+	QueryIssueTypeWriteAmplification             = "write_amplification"
+	QueryIssueTypeLongHeldLock                   = "long_held_lock"
+	QueryIssueTypeDeadlockProne                  = "deadlock_prone"
+	QueryIssueTypeLockOrderInconsistency         = "lock_order_inconsistency"
+	QueryIssueTypeBlockingIndexBuild             = "blocking_index_build"
+	QueryIssueTypeTableRewrite                   = "table_rewrite"
+	QueryIssueTypeNotNullWithoutDefault          = "not_null_without_default"
+	QueryIssueTypeForeignKeyValidation           = "foreign_key_validation"
+	QueryIssueTypeBlockingAlter                  = "blocking_alter"
+	QueryIssueTypeBreaksDependentView            = "breaks_dependent_view"
+	QueryIssueTypeRowEstimateMismatch            = "row_estimate_mismatch"
+	QueryIssueTypeSortSpilledToDisk              = "sort_spilled_to_disk"
+	QueryIssueTypeComparisonTypeMismatch         = "comparison_type_mismatch"
 )
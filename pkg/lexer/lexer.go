@@ -0,0 +1,267 @@
+package lexer
+
+import (
+	"strings"
+)
+
+type TokenType int
+
+const (
+	// Word is a keyword or an unquoted identifier
+	Word TokenType = iota
+	// QuotedIdentifier is an identifier in double quotes or backticks
+	QuotedIdentifier
+	// String is a single quoted or dollar quoted string literal
+	String
+	Number
+	// Placeholder is a bind parameter: ?, $1 or :name
+	Placeholder
+	Punctuation
+	Comment
+)
+
+type Token struct {
+	Type  TokenType
+	Value string
+	// Pos is the byte offset of the token in the input
+	Pos int
+	// Unterminated is set on a string, quoted identifier or comment that
+	// reaches the end of the input without being closed
+	Unterminated bool
+}
+
+// Is returns true if the token is the given keyword, ignoring case.
+func (t Token) Is(keyword string) bool {
+	return t.Type == Word && strings.EqualFold(t.Value, keyword)
+}
+
+// IsPunctuation returns true if the token is the given punctuation.
+func (t Token) IsPunctuation(value string) bool {
+	return t.Type == Punctuation && t.Value == value
+}
+
+// End returns the byte offset just past the token.
+func (t Token) End() int {
+	return t.Pos + len(t.Value)
+}
+
+var multiCharOperators = []string{"->>", "::", "<=>", "<=", ">=", "<>", "!=", "||", "->", ":="}
+
+// Tokenize splits a SQL string into tokens, understanding both the MySQL and
+// Postgres quoting rules. Whitespace is dropped, but every token keeps its
+// offset so callers can slice the original input.
+func Tokenize(sql string) []Token {
+	tokens := []Token{}
+
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+
+		switch {
+		case isSpace(c):
+			i++
+
+		case c == '-' && strings.HasPrefix(sql[i:], "--"), c == '#':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			tokens = append(tokens, Token{Type: Comment, Value: sql[i : i+end], Pos: i})
+			i += end
+
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			token := Token{Type: Comment, Pos: i}
+			if end < 0 {
+				token.Value = sql[i:]
+				token.Unterminated = true
+			} else {
+				token.Value = sql[i : i+2+end+2]
+			}
+			tokens = append(tokens, token)
+			i += len(token.Value)
+
+		case c == '\'':
+			token := scanQuoted(sql, i, '\'', String)
+			tokens = append(tokens, token)
+			i += len(token.Value)
+
+		case (c == 'e' || c == 'E' || c == 'b' || c == 'B' || c == 'x' || c == 'X' || c == 'n' || c == 'N') && i+1 < len(sql) && sql[i+1] == '\'':
+			token := scanQuoted(sql, i+1, '\'', String)
+			token.Pos = i
+			token.Value = sql[i:i+1] + token.Value
+			tokens = append(tokens, token)
+			i += len(token.Value)
+
+		case c == '"' || c == '`':
+			token := scanQuoted(sql, i, c, QuotedIdentifier)
+			tokens = append(tokens, token)
+			i += len(token.Value)
+
+		case c == '$':
+			if i+1 < len(sql) && isDigit(sql[i+1]) {
+				end := i + 1
+				for end < len(sql) && isDigit(sql[end]) {
+					end++
+				}
+				tokens = append(tokens, Token{Type: Placeholder, Value: sql[i:end], Pos: i})
+				i = end
+				continue
+			}
+
+			if tag, ok := dollarQuoteTag(sql[i:]); ok {
+				token := Token{Type: String, Pos: i}
+				end := strings.Index(sql[i+len(tag):], tag)
+				if end < 0 {
+					token.Value = sql[i:]
+					token.Unterminated = true
+				} else {
+					token.Value = sql[i : i+len(tag)+end+len(tag)]
+				}
+				tokens = append(tokens, token)
+				i += len(token.Value)
+				continue
+			}
+
+			tokens = append(tokens, Token{Type: Punctuation, Value: "$", Pos: i})
+			i++
+
+		case c == '?':
+			tokens = append(tokens, Token{Type: Placeholder, Value: "?", Pos: i})
+			i++
+
+		case c == ':' && i+1 < len(sql) && isIdentifierStart(sql[i+1]) && (i == 0 || sql[i-1] != ':'):
+			end := i + 1
+			for end < len(sql) && isIdentifierPart(sql[end]) {
+				end++
+			}
+			tokens = append(tokens, Token{Type: Placeholder, Value: sql[i:end], Pos: i})
+			i = end
+
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			end := i
+			for end < len(sql) && (isDigit(sql[end]) || sql[end] == '.' || isHexDigit(sql[end]) || sql[end] == 'x' || sql[end] == 'X') {
+				end++
+			}
+			// exponent sign
+			for end < len(sql) && (sql[end] == '+' || sql[end] == '-') && (sql[end-1] == 'e' || sql[end-1] == 'E') {
+				end++
+				for end < len(sql) && isDigit(sql[end]) {
+					end++
+				}
+			}
+			tokens = append(tokens, Token{Type: Number, Value: sql[i:end], Pos: i})
+			i = end
+
+		case isIdentifierStart(c):
+			end := i
+			for end < len(sql) && isIdentifierPart(sql[end]) {
+				end++
+			}
+			tokens = append(tokens, Token{Type: Word, Value: sql[i:end], Pos: i})
+			i = end
+
+		default:
+			value := sql[i : i+1]
+			for _, operator := range multiCharOperators {
+				if strings.HasPrefix(sql[i:], operator) {
+					value = operator
+					break
+				}
+			}
+			tokens = append(tokens, Token{Type: Punctuation, Value: value, Pos: i})
+			i += len(value)
+		}
+	}
+
+	return tokens
+}
+
+// scanQuoted scans a quoted token starting at the opening quote. A doubled
+// quote is an escaped quote, and so is a backslash escape in single quoted
+// strings (MySQL, and Postgres E'...' strings).
+func scanQuoted(sql string, start int, quote byte, tokenType TokenType) Token {
+	i := start + 1
+	for i < len(sql) {
+		switch sql[i] {
+		case '\\':
+			if quote == '\'' {
+				i += 2
+				continue
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i += 2
+				continue
+			}
+			return Token{Type: tokenType, Value: sql[start : i+1], Pos: start}
+		}
+		i++
+	}
+
+	return Token{Type: tokenType, Value: sql[start:], Pos: start, Unterminated: true}
+}
+
+// dollarQuoteTag returns the opening tag ($$ or $tag$) of a Postgres dollar
+// quoted string at the start of s.
+func dollarQuoteTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1], true
+		}
+		if !isIdentifierPart(s[i]) || (i == 1 && isDigit(s[i])) {
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || isDigit(c) || c == '$'
+}
+
+// SplitStatements splits a SQL script into its statements on the semicolons
+// that aren't inside a string, quoted identifier or comment. Statements are
+// trimmed, and ones that only hold comments are dropped.
+func SplitStatements(sql string) []string {
+	statements := []string{}
+
+	start := 0
+	significant := false
+	for _, token := range Tokenize(sql) {
+		if token.Type == Punctuation && token.Value == ";" {
+			if significant {
+				statements = append(statements, strings.TrimSpace(sql[start:token.Pos]))
+			}
+			start = token.End()
+			significant = false
+			continue
+		}
+		if token.Type != Comment {
+			significant = true
+		}
+	}
+
+	if significant {
+		statements = append(statements, strings.TrimSpace(sql[start:]))
+	}
+
+	return statements
+}
@@ -0,0 +1,69 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "quotes and placeholders",
+			sql:  `select "a b", 'it''s' from t where x = $1 and y = ? and z = :name`,
+			want: []string{"select", `"a b"`, ",", `'it''s'`, "from", "t", "where", "x", "=", "$1", "and", "y", "=", "?", "and", "z", "=", ":name"},
+		},
+		{
+			name: "comments and casts",
+			sql:  "select x::int -- trailing\nfrom t /* block */",
+			want: []string{"select", "x", "::", "int", "-- trailing", "from", "t", "/* block */"},
+		},
+		{
+			name: "dollar quoting",
+			sql:  "select $tag$ it's; $$ $tag$, $$x$$",
+			want: []string{"select", "$tag$ it's; $$ $tag$", ",", "$$x$$"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, token := range Tokenize(tt.sql) {
+				got = append(got, token.Value)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTokenize_unterminated(t *testing.T) {
+	tokens := Tokenize("select 'abc")
+	assert.True(t, tokens[len(tokens)-1].Unterminated)
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "semicolons in strings and comments",
+			sql:  "begin;\nupdate t set a = 'x;y' where id = 1; -- done;\ncommit",
+			want: []string{"begin", "update t set a = 'x;y' where id = 1", "-- done;\ncommit"},
+		},
+		{
+			name: "empty statements",
+			sql:  ";; select 1;\n/* only a comment */;",
+			want: []string{"select 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SplitStatements(tt.sql))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+// Explain returns the plan MySQL picks for a query, without running it, in a
+// transaction that's rolled back. The parameters of the query are bound to the
+// values, which are SQL literals, when they're given, and to a representative
+// value of each parameter's type when they aren't, since MySQL can't plan a
+// query with unbound parameters.
+func Explain(db *dbtypes.DB, query string, values []string) (string, error) {
+	parameters, err := plan.InferParameters(query, db.Tables)
+	if err != nil {
+		return "", fmt.Errorf("infer parameters: %w", err)
+	}
+
+	note := ""
+	if len(parameters) > 0 {
+		if len(values) == 0 {
+			assigned := []string{}
+			for _, parameter := range parameters {
+				value := plan.RepresentativeValue(parameter.Type())
+				values = append(values, value)
+				assigned = append(assigned, fmt.Sprintf("%s = %s", parameter.Name, value))
+			}
+			note = "Planned with representative values: " + strings.Join(assigned, ", ")
+		}
+
+		query, err = plan.BindParameters(query, values)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// EXPLAIN doesn't run the statement, but the transaction is rolled back
+	// anyway, so nothing the statement could do is ever committed
+	tx, err := conn.Begin()
+	if err != nil {
+		return "", fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	lines := []string{}
+	if note != "" {
+		lines = append(lines, note)
+	}
+
+	// the tree format is only in MySQL 8.0.16 and later, older versions only
+	// have the tabular format
+	if tree, err := explainTree(tx, query); err == nil {
+		return strings.Join(append(lines, tree), "\n"), nil
+	}
+
+	rows, err := tx.Query("EXPLAIN " + query)
+	if err != nil {
+		return "", fmt.Errorf("explain: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("explain columns: %w", err)
+	}
+	lines = append(lines, strings.Join(columns, " | "))
+
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return "", fmt.Errorf("scan plan: %w", err)
+		}
+
+		fields := []string{}
+		for _, value := range values {
+			if value.Valid {
+				fields = append(fields, value.String)
+			} else {
+				fields = append(fields, "NULL")
+			}
+		}
+		lines = append(lines, strings.Join(fields, " | "))
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("read plan: %w", err)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func explainTree(tx *sql.Tx, query string) (string, error) {
+	tree := ""
+	if err := tx.QueryRow("EXPLAIN FORMAT=TREE " + query).Scan(&tree); err != nil {
+		return "", err
+	}
+	return tree, nil
+}
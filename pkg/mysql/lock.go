@@ -0,0 +1,88 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+// EstimateLocks returns the locks a statement takes in InnoDB. Locking reads
+// and writes take next-key locks: each index record they scan is locked along
+// with the gap before it. DDL takes a metadata lock on the table, which waits
+// for every open transaction that has used the table, and blocks every query
+// that arrives after it. An ALTER that copies the table also blocks writes
+// until the copy is done.
+func EstimateLocks(db *dbtypes.DB, query string) ([]plan.Lock, error) {
+	if ddl, ok := plan.ParseDDL(query); ok {
+		return ddlLocks(db, ddl), nil
+	}
+
+	locks, err := plan.EstimateLocks(query, db.Tables)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, lock := range locks {
+		if locks[i].Detail != "" {
+			continue
+		}
+		switch lock.Granularity {
+		case plan.LockGranularityRange:
+			locks[i].Detail = "next-key locks on the matching index records and the gaps before them"
+		case plan.LockGranularityTable:
+			locks[i].Detail = "no index serves the predicate, so every scanned row and gap is locked"
+		}
+	}
+
+	return locks, nil
+}
+
+func ddlLocks(db *dbtypes.DB, ddl *plan.DDLStatement) []plan.Lock {
+	if ddl.Table == "" {
+		return []plan.Lock{}
+	}
+
+	lock := plan.Lock{
+		Table:     ddl.Table,
+		TableLock: "exclusive metadata",
+	}
+	for _, table := range db.Tables {
+		if table.GetName() == ddl.Table {
+			lock.EstimatedRows = table.GetEstimatedRowCount()
+		}
+	}
+
+	switch ddl.Kind {
+	case "create index", "alter table":
+		// online DDL only needs the exclusive lock to start and finish the
+		// change, and allows reads and writes while it runs
+		lock.Detail = "held briefly at the start and end of the change, but it queues behind open transactions and blocks every query on the table while it waits"
+		if ddl.Kind != "alter table" {
+			break
+		}
+		// an ALTER that copies the table holds it until the copy is done
+		if reason := alterCopyReason(db, ddl); reason != "" {
+			lock.Mode = plan.LockModeExclusive
+			lock.Granularity = plan.LockGranularityTable
+			lock.Detail = fmt.Sprintf("the ALTER %s, so it copies the table and blocks writes for the whole statement", reason)
+		}
+	case "lock table":
+		lock.TableLock = strings.ToLower(ddl.LockMode)
+		lock.Mode = plan.LockModeExclusive
+		if strings.HasPrefix(lock.TableLock, "read") {
+			lock.Mode = plan.LockModeShared
+		}
+		lock.Granularity = plan.LockGranularityTable
+		lock.Detail = "held until UNLOCK TABLES"
+	case "analyze":
+		lock.TableLock = "shared metadata"
+	default:
+		lock.Mode = plan.LockModeExclusive
+		lock.Granularity = plan.LockGranularityTable
+		lock.Detail = "held for the whole statement"
+	}
+
+	return []plan.Lock{lock}
+}
@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateLocks_ddl(t *testing.T) {
+	db := &dbtypes.DB{
+		Tables: []dbtypes.Table{
+			MysqlTable{
+				TableName: "orders",
+				Columns: []MysqlColumn{
+					{ColumnName: "id", DataType: "int", ColumnType: "int(11)"},
+					{ColumnName: "status", DataType: "varchar", ColumnType: "varchar(32)"},
+				},
+				EstimatedRowCount: 1000000,
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		wantMode        string
+		wantGranularity string
+	}{
+		{
+			name:  "create index",
+			query: "create index orders_status on orders (status)",
+		},
+		{
+			name:  "add column in place",
+			query: "alter table orders add column region varchar(16) not null default ''",
+		},
+		{
+			name:            "column type change copies",
+			query:           "alter table orders modify id bigint not null auto_increment",
+			wantMode:        plan.LockModeExclusive,
+			wantGranularity: plan.LockGranularityTable,
+		},
+		{
+			name:            "primary key change copies",
+			query:           "alter table orders drop primary key",
+			wantMode:        plan.LockModeExclusive,
+			wantGranularity: plan.LockGranularityTable,
+		},
+		{
+			name:            "algorithm copy",
+			query:           "alter table orders add column region varchar(16), algorithm=copy",
+			wantMode:        plan.LockModeExclusive,
+			wantGranularity: plan.LockGranularityTable,
+		},
+		{
+			name:            "lock exclusive",
+			query:           "alter table orders add column region varchar(16), lock=exclusive",
+			wantMode:        plan.LockModeExclusive,
+			wantGranularity: plan.LockGranularityTable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locks, err := EstimateLocks(db, tt.query)
+			require.NoError(t, err)
+			require.Len(t, locks, 1)
+			assert.Equal(t, "exclusive metadata", locks[0].TableLock)
+			assert.Equal(t, tt.wantMode, locks[0].Mode)
+			assert.Equal(t, tt.wantGranularity, locks[0].Granularity)
+			assert.Equal(t, int64(1000000), locks[0].EstimatedRows)
+			if tt.wantMode != "" {
+				assert.Contains(t, locks[0].Detail, "blocks writes for the whole statement")
+			}
+		})
+	}
+}
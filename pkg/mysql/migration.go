@@ -0,0 +1,203 @@
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+// maxSingleByteLength is the longest varchar whose length fits in one byte.
+// Growing a varchar in place only works while it stays on the same side.
+const maxSingleByteLength = 255
+
+var displayWidthRegexp = regexp.MustCompile(`^((?:tiny|small|medium|big)?int)\(\d+\)`)
+
+// scanDDLStatementForIssues reports the ALTERs that InnoDB can't run with
+// ALGORITHM=INSTANT or INPLACE. Those copy the table, and writes are blocked
+// until the copy is done. Views are also checked, because MySQL stores a view
+// as the text of its query and doesn't notice when a rename breaks it.
+func scanDDLStatementForIssues(db *dbtypes.DB, ddl *plan.DDLStatement) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	switch ddl.Kind {
+	case "rename table":
+		queryIssues = append(queryIssues, scanForDependentViews(db, ddl.Table, "", "renaming "+ddl.Table)...)
+
+	case "drop table":
+		queryIssues = append(queryIssues, scanForDependentViews(db, ddl.Table, "", "dropping "+ddl.Table)...)
+
+	case "alter table":
+		rows := plan.EstimatedRowCount(db.Tables, ddl.Table)
+		severity := plan.MigrationSeverity(db.Tables, ddl.Table)
+
+		actions, addsPrimaryKey := parseAlterActions(ddl)
+		for i, action := range actions {
+			if reason := copyReason(db, ddl.Table, action, ddl.Actions[i], addsPrimaryKey); reason != "" {
+				queryIssues = append(queryIssues, issuetypes.QueryIssue{
+					IssueSeverity: severity,
+					IssueType:     issuetypes.QueryIssueTypeBlockingAlter,
+					Message: fmt.Sprintf("ALTER TABLE %s %s, so it can't run INPLACE or INSTANT and blocks writes while the table (~%d rows) is copied; consider an online schema change tool such as gh-ost or pt-online-schema-change",
+						ddl.Table, reason, rows),
+				})
+			}
+
+			switch action.Kind {
+			case plan.AlterActionRenameTable:
+				queryIssues = append(queryIssues, scanForDependentViews(db, ddl.Table, "", "renaming "+ddl.Table)...)
+			case plan.AlterActionRenameColumn:
+				queryIssues = append(queryIssues, scanForDependentViews(db, ddl.Table, action.Column, "renaming "+action.Column)...)
+			case plan.AlterActionDropColumn:
+				queryIssues = append(queryIssues, scanForDependentViews(db, ddl.Table, action.Column, "dropping "+action.Column)...)
+			case plan.AlterActionAlterColumnType:
+				if action.NewName != "" {
+					queryIssues = append(queryIssues, scanForDependentViews(db, ddl.Table, action.Column, "renaming "+action.Column)...)
+				}
+			}
+		}
+	}
+
+	return queryIssues
+}
+
+// parseAlterActions parses the actions of an ALTER TABLE, and returns whether
+// one of them adds a primary key.
+func parseAlterActions(ddl *plan.DDLStatement) ([]plan.AlterAction, bool) {
+	actions := []plan.AlterAction{}
+	addsPrimaryKey := false
+	for _, text := range ddl.Actions {
+		action := plan.ParseAlterAction(text)
+		if action.Kind == plan.AlterActionAddPrimaryKey {
+			addsPrimaryKey = true
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, addsPrimaryKey
+}
+
+// alterCopyReason returns why an ALTER TABLE copies the table, or an empty
+// string if all of its actions run in place.
+func alterCopyReason(db *dbtypes.DB, ddl *plan.DDLStatement) string {
+	actions, addsPrimaryKey := parseAlterActions(ddl)
+	for i, action := range actions {
+		if reason := copyReason(db, ddl.Table, action, ddl.Actions[i], addsPrimaryKey); reason != "" {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+// copyReason returns why an ALTER TABLE action needs ALGORITHM=COPY, or an
+// empty string if InnoDB runs it in place.
+func copyReason(db *dbtypes.DB, tableName string, action plan.AlterAction, text string, addsPrimaryKey bool) string {
+	lowered := strings.ToLower(text)
+
+	switch action.Kind {
+	case plan.AlterActionOption:
+		switch {
+		case action.Option == "algorithm" && action.Value == "copy":
+			return "asks for ALGORITHM=COPY"
+		case action.Option == "lock" && (action.Value == "shared" || action.Value == "exclusive"):
+			return fmt.Sprintf("asks for LOCK=%s", strings.ToUpper(action.Value))
+		}
+
+	case plan.AlterActionAlterColumnType:
+		column := plan.FindColumn(db.Tables, tableName, action.Column)
+		if column == nil {
+			return ""
+		}
+		if typeChangeCopies(column.GetColumnType(), action.Type) {
+			return fmt.Sprintf("changes the type of %s from %s to %s", action.Column, column.GetColumnType(), action.Type)
+		}
+
+	case plan.AlterActionAddColumn:
+		switch {
+		case strings.Contains(lowered, "auto_increment"):
+			return fmt.Sprintf("adds the AUTO_INCREMENT column %s", action.Column)
+		case strings.Contains(lowered, "generated always") && strings.Contains(lowered, "stored"),
+			strings.Contains(lowered, " as (") && strings.Contains(lowered, "stored"):
+			return fmt.Sprintf("adds the stored generated column %s", action.Column)
+		}
+
+	case plan.AlterActionAddForeignKey:
+		return "adds a foreign key while foreign_key_checks is enabled"
+
+	case plan.AlterActionDropPrimaryKey:
+		if !addsPrimaryKey {
+			return "drops the primary key without adding a new one"
+		}
+
+	case plan.AlterActionConvertCharset:
+		return "converts the character set"
+	}
+
+	return ""
+}
+
+// typeChangeCopies returns true if changing a column type copies the table.
+// Changes that only touch the nullability or the default keep the type, and
+// growing a varchar is done in place as long as its length prefix stays the
+// same size. The prefix depends on the length in bytes, so this assumes a
+// single byte character set.
+func typeChangeCopies(from string, to string) bool {
+	fromName, fromLength := normalizeType(from)
+	toName, toLength := normalizeType(to)
+
+	switch {
+	case fromName == toName && fromLength == toLength:
+		return false
+	case fromName == "varchar" && toName == "varchar" && toLength >= fromLength:
+		return (fromLength <= maxSingleByteLength) != (toLength <= maxSingleByteLength)
+	}
+
+	return true
+}
+
+// normalizeType returns the name and length of a column type, without the
+// display width MySQL 5.7 reports for integers.
+func normalizeType(columnType string) (string, int) {
+	columnType = strings.ToLower(strings.Join(strings.Fields(columnType), " "))
+	columnType = displayWidthRegexp.ReplaceAllString(columnType, "$1")
+
+	name, length := columnType, 0
+	if open := strings.Index(columnType, "("); open >= 0 {
+		name = strings.TrimSpace(columnType[:open])
+		arguments := strings.TrimSuffix(columnType[open+1:], ")")
+		if n, err := strconv.Atoi(strings.TrimSpace(strings.Split(arguments, ",")[0])); err == nil {
+			length = n
+		}
+	}
+
+	switch name {
+	case "integer":
+		name = "int"
+	case "bool":
+		name = "tinyint"
+	}
+
+	return name, length
+}
+
+// scanForDependentViews reports the views a change breaks. The change itself
+// succeeds, and the views fail the next time they're queried.
+func scanForDependentViews(db *dbtypes.DB, tableName string, columnName string, change string) []issuetypes.QueryIssue {
+	views := plan.DependentViews(db.Views, tableName, columnName)
+	if len(views) == 0 {
+		return []issuetypes.QueryIssue{}
+	}
+
+	return []issuetypes.QueryIssue{
+		{
+			IssueSeverity: issuetypes.IssueSeverityHigh,
+			IssueType:     issuetypes.QueryIssueTypeBreaksDependentView,
+			Message: fmt.Sprintf("%s breaks the views %s, which MySQL doesn't update; recreate them with the new definition",
+				change, strings.Join(views, ", ")),
+		},
+	}
+}
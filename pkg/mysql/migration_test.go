@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanDDLStatementForIssues(t *testing.T) {
+	db := &dbtypes.DB{
+		Tables: []dbtypes.Table{
+			MysqlTable{
+				TableName: "orders",
+				Columns: []MysqlColumn{
+					{ColumnName: "id", DataType: "int", ColumnType: "int(11)"},
+					{ColumnName: "status", DataType: "varchar", ColumnType: "varchar(32)"},
+				},
+				EstimatedRowCount: 1000000,
+			},
+		},
+		Views: []dbtypes.View{
+			{Name: "open_orders", Definition: "select `orders`.`id` AS `id` from `orders` where `orders`.`status` = 'open'"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantTypes []string
+	}{
+		{
+			name:      "add column",
+			query:     "alter table orders add column region varchar(16) not null default '', algorithm=instant",
+			wantTypes: []string{},
+		},
+		{
+			name:      "widen a varchar in place",
+			query:     "alter table orders modify status varchar(64) not null",
+			wantTypes: []string{},
+		},
+		{
+			name:      "varchar length prefix grows",
+			query:     "alter table orders modify status varchar(300)",
+			wantTypes: []string{issuetypes.QueryIssueTypeBlockingAlter},
+		},
+		{
+			name:      "integer type change",
+			query:     "alter table orders modify id bigint not null auto_increment",
+			wantTypes: []string{issuetypes.QueryIssueTypeBlockingAlter},
+		},
+		{
+			name:      "foreign key",
+			query:     "alter table orders add constraint orders_user_fk foreign key (user_id) references users (id)",
+			wantTypes: []string{issuetypes.QueryIssueTypeBlockingAlter},
+		},
+		{
+			name:      "rename column used by a view",
+			query:     "alter table orders rename column status to state",
+			wantTypes: []string{issuetypes.QueryIssueTypeBreaksDependentView},
+		},
+		{
+			name:      "rename table used by a view",
+			query:     "rename table orders to orders_old",
+			wantTypes: []string{issuetypes.QueryIssueTypeBreaksDependentView},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ddl, ok := plan.ParseDDL(tt.query)
+			require.True(t, ok)
+
+			issueTypes := []string{}
+			for _, issue := range scanDDLStatementForIssues(db, ddl) {
+				issueTypes = append(issueTypes, issue.IssueType)
+			}
+			assert.Equal(t, tt.wantTypes, issueTypes)
+		})
+	}
+}
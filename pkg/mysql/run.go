@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+)
+
+// Run executes a query the number of times in opts, each time in a
+// transaction that's rolled back, and returns the first rows of the first run
+// and how long every run took. Statements that implicitly commit, such as
+// DDL, can't be rolled back, so they shouldn't be run.
+func Run(db *dbtypes.DB, query string, opts dbtypes.RunOptions) (*dbtypes.RunResult, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result := &dbtypes.RunResult{}
+	for i := 0; i < max(opts.Runs, 1); i++ {
+		if err := runOnce(conn, query, opts, result, i == 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func runOnce(conn *sql.DB, query string, opts dbtypes.RunOptions, result *dbtypes.RunResult, keepRows bool) error {
+	ctx := context.Background()
+
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	start := time.Now()
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("columns: %w", err)
+	}
+
+	rowCount := int64(0)
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		rowCount++
+		if err := rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("scan row: %w", err)
+		}
+		if !keepRows || len(result.Rows) >= opts.MaxRows {
+			continue
+		}
+
+		row := []string{}
+		for _, value := range values {
+			if value.Valid {
+				row = append(row, value.String)
+			} else {
+				row = append(row, "NULL")
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read rows: %w", err)
+	}
+	result.Durations = append(result.Durations, time.Since(start))
+
+	if keepRows {
+		result.Columns = columns
+		result.RowCount = rowCount
+
+		// a statement that returns no rows reports the rows it changed
+		if len(columns) == 0 {
+			if err := tx.QueryRowContext(ctx, "SELECT ROW_COUNT()").Scan(&result.RowCount); err != nil {
+				return fmt.Errorf("row count: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -15,6 +15,12 @@ func LoadSchema(db *dbtypes.DB) error {
 		db.SchemaLoading = false
 	}()
 
+	serverVersion, err := getServerVersion(db)
+	if err != nil {
+		return err
+	}
+	db.ServerVersion = serverVersion
+
 	tables, err := listTables(db)
 	if err != nil {
 		return err
@@ -25,6 +31,16 @@ func LoadSchema(db *dbtypes.DB) error {
 		return err
 	}
 
+	indexes, err := listIndexes(db)
+	if err != nil {
+		return err
+	}
+
+	foreignKeys, err := listForeignKeys(db)
+	if err != nil {
+		return err
+	}
+
 	for i, table := range tables {
 		if _, ok := primaryKeys[table.GetName()]; !ok {
 			primaryKeys[table.GetName()] = []string{}
@@ -32,15 +48,38 @@ func LoadSchema(db *dbtypes.DB) error {
 
 		mysqlTable := tables[i].(MysqlTable)
 		mysqlTable.PrimaryKeys = primaryKeys[table.GetName()]
+		mysqlTable.Indexes = indexes[table.GetName()]
+		mysqlTable.ForeignKeys = foreignKeys[table.GetName()]
 		tables[i] = mysqlTable
 	}
 
+	views, err := listViews(db)
+	if err != nil {
+		return err
+	}
+
 	db.SchemaLoaded = true
 	db.Tables = tables
+	db.Views = views
 
 	return nil
 }
 
+func getServerVersion(db *dbtypes.DB) (string, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	serverVersion := ""
+	if err := conn.QueryRow("SELECT VERSION()").Scan(&serverVersion); err != nil {
+		return "", fmt.Errorf("scan server version: %w", err)
+	}
+
+	return serverVersion, nil
+}
+
 func listPrimaryKeys(db *dbtypes.DB) (map[string][]string, error) {
 	conn, err := connect(db.ConnectionURI)
 	if err != nil {
@@ -72,6 +111,122 @@ func listPrimaryKeys(db *dbtypes.DB) (map[string][]string, error) {
 	return primaryKeys, nil
 }
 
+func listIndexes(db *dbtypes.DB) (map[string][]dbtypes.Index, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query(`SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE, COLUMN_NAME
+FROM INFORMATION_SCHEMA.STATISTICS
+WHERE TABLE_SCHEMA = ? AND INDEX_NAME != 'PRIMARY'
+ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`, db.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := map[string][]dbtypes.Index{}
+	for rows.Next() {
+		tableName := ""
+		indexName := ""
+		nonUnique := 0
+		columnName := sql.NullString{}
+		if err := rows.Scan(&tableName, &indexName, &nonUnique, &columnName); err != nil {
+			return nil, fmt.Errorf("scan indexes: %w", err)
+		}
+
+		// functional key parts have no column name
+		if !columnName.Valid {
+			continue
+		}
+
+		tableIndexes := indexes[tableName]
+		if len(tableIndexes) == 0 || tableIndexes[len(tableIndexes)-1].Name != indexName {
+			tableIndexes = append(tableIndexes, dbtypes.Index{
+				Name:     indexName,
+				IsUnique: nonUnique == 0,
+			})
+		}
+
+		last := &tableIndexes[len(tableIndexes)-1]
+		last.Columns = append(last.Columns, columnName.String)
+		indexes[tableName] = tableIndexes
+	}
+
+	return indexes, nil
+}
+
+func listForeignKeys(db *dbtypes.DB) (map[string][]dbtypes.ForeignKey, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query(`SELECT TABLE_NAME, CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = ? AND REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`, db.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	foreignKeys := map[string][]dbtypes.ForeignKey{}
+	for rows.Next() {
+		tableName := ""
+		constraintName := ""
+		columnName := ""
+		referencedTable := ""
+		referencedColumn := ""
+		if err := rows.Scan(&tableName, &constraintName, &columnName, &referencedTable, &referencedColumn); err != nil {
+			return nil, fmt.Errorf("scan foreign keys: %w", err)
+		}
+
+		tableForeignKeys := foreignKeys[tableName]
+		if len(tableForeignKeys) == 0 || tableForeignKeys[len(tableForeignKeys)-1].Name != constraintName {
+			tableForeignKeys = append(tableForeignKeys, dbtypes.ForeignKey{
+				Name:            constraintName,
+				ReferencedTable: referencedTable,
+			})
+		}
+
+		last := &tableForeignKeys[len(tableForeignKeys)-1]
+		last.Columns = append(last.Columns, columnName)
+		last.ReferencedColumns = append(last.ReferencedColumns, referencedColumn)
+		foreignKeys[tableName] = tableForeignKeys
+	}
+
+	return foreignKeys, nil
+}
+
+func listViews(db *dbtypes.DB) ([]dbtypes.View, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query("SELECT TABLE_NAME, VIEW_DEFINITION FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = ?", db.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("query views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []dbtypes.View{}
+	for rows.Next() {
+		view := dbtypes.View{}
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			return nil, fmt.Errorf("scan views: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	return views, nil
+}
+
 func listTables(db *dbtypes.DB) ([]dbtypes.Table, error) {
 	// read the schema from mysql
 	conn, err := connect(db.ConnectionURI)
@@ -153,12 +308,13 @@ func indexesByTable(mysqlTables []MysqlTable) map[string][]Index {
 		})
 
 		// other indexes
-		// for _, index := range table.Indexes {
-		// 	indexesByTable[table.TableName] = append(indexesByTable[table.TableName], Index{
-		// 		Columns:      index.Columns,
-		// 		IsPrimaryKey: false,
-		// 		IsUnique:     index.IsUnique,
-		// 	})
+		for _, index := range mysqlTable.Indexes {
+			indexesByTable[mysqlTable.TableName] = append(indexesByTable[mysqlTable.TableName], Index{
+				Columns:      index.Columns,
+				IsPrimaryKey: false,
+				IsUnique:     index.IsUnique,
+			})
+		}
 	}
 
 	return indexesByTable
@@ -10,6 +10,8 @@ type MysqlTable struct {
 	TableName         string
 	Columns           []MysqlColumn
 	PrimaryKeys       []string
+	Indexes           []dbtypes.Index
+	ForeignKeys       []dbtypes.ForeignKey
 	EstimatedRowCount int64
 }
 
@@ -29,6 +31,14 @@ func (t MysqlTable) GetPrimaryKeys() []string {
 	return t.PrimaryKeys
 }
 
+func (t MysqlTable) GetIndexes() []dbtypes.Index {
+	return t.Indexes
+}
+
+func (t MysqlTable) GetForeignKeys() []dbtypes.ForeignKey {
+	return t.ForeignKeys
+}
+
 func (t MysqlTable) GetEstimatedRowCount() int64 {
 	return t.EstimatedRowCount
 }
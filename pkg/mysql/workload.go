@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/workload"
+)
+
+// WorkloadOrders are the measurements the statement digests can be ranked by.
+var WorkloadOrders = []string{
+	workload.OrderByTotalTime,
+	workload.OrderByMeanTime,
+	workload.OrderByCalls,
+	workload.OrderByRows,
+	workload.OrderByExaminedRatio,
+}
+
+// TopStatements returns the statement digests of the current database from
+// performance_schema with the highest total latency, mean latency, calls,
+// rows or rows examined for every row sent.
+func TopStatements(db *dbtypes.DB, opts workload.Options) ([]workload.Statement, error) {
+	if err := workload.ValidateOrderBy(opts.OrderBy, WorkloadOrders); err != nil {
+		return nil, err
+	}
+
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	orderBy := map[string]string{
+		workload.OrderByTotalTime:     "SUM_TIMER_WAIT",
+		workload.OrderByMeanTime:      "AVG_TIMER_WAIT",
+		workload.OrderByCalls:         "COUNT_STAR",
+		workload.OrderByRows:          "SUM_ROWS_SENT + SUM_ROWS_AFFECTED",
+		workload.OrderByExaminedRatio: "SUM_ROWS_EXAMINED / GREATEST(SUM_ROWS_SENT + SUM_ROWS_AFFECTED, 1)",
+	}[opts.OrderBy]
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = workload.DefaultLimit
+	}
+
+	// the timers are in picoseconds
+	query := fmt.Sprintf(`SELECT DIGEST, DIGEST_TEXT, COUNT_STAR, SUM_TIMER_WAIT, AVG_TIMER_WAIT,
+SUM_ROWS_SENT + SUM_ROWS_AFFECTED, SUM_ROWS_EXAMINED, SUM_NO_INDEX_USED
+FROM performance_schema.events_statements_summary_by_digest
+WHERE SCHEMA_NAME = ? AND DIGEST_TEXT IS NOT NULL
+ORDER BY %s DESC
+LIMIT ?`, orderBy)
+
+	rows, err := conn.Query(query, db.DatabaseName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query statement digests: %w", err)
+	}
+	defer rows.Close()
+
+	statements := []workload.Statement{}
+	for rows.Next() {
+		var totalPicoseconds, meanPicoseconds uint64
+		statement := workload.Statement{}
+		if err := rows.Scan(&statement.QueryID, &statement.Query, &statement.Calls, &totalPicoseconds, &meanPicoseconds,
+			&statement.Rows, &statement.RowsExamined, &statement.NoIndexUsed); err != nil {
+			return nil, fmt.Errorf("scan statement digests: %w", err)
+		}
+
+		statement.Query = digestQuery(statement.Query)
+		statement.TotalTime = time.Duration(totalPicoseconds / 1000)
+		statement.MeanTime = time.Duration(meanPicoseconds / 1000)
+		statements = append(statements, statement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read statement digests: %w", err)
+	}
+
+	return statements, nil
+}
+
+var insertValuesRegexp = regexp.MustCompile(`(?i)\(([^()]*)\)\s*VALUES\s*\(\.\.\.\)`)
+
+// digestQuery makes the text of a digest parseable. The digest collapses
+// value lists, such as the values of an IN list or the rows of an INSERT, to
+// (...), which stands for a list of parameters. The row of an INSERT gets a
+// parameter for each of its columns.
+func digestQuery(digestText string) string {
+	digestText = insertValuesRegexp.ReplaceAllStringFunc(digestText, func(match string) string {
+		columns := insertValuesRegexp.FindStringSubmatch(match)[1]
+		parameters := strings.Repeat("?, ", strings.Count(columns, ",")+1)
+		return strings.Replace(match, "(...)", "("+strings.TrimSuffix(parameters, ", ")+")", 1)
+	})
+	return strings.ReplaceAll(digestText, "(...)", "(?)")
+}
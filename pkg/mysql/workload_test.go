@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_digestQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		digestText string
+		want       string
+	}{
+		{
+			name:       "in list",
+			digestText: "SELECT * FROM `orders` WHERE `user_id` IN (...) AND `status` = ?",
+			want:       "SELECT * FROM `orders` WHERE `user_id` IN (?) AND `status` = ?",
+		},
+		{
+			name:       "insert rows",
+			digestText: "INSERT INTO `orders` ( `user_id` , `status` ) VALUES (...) /* , ... */",
+			want:       "INSERT INTO `orders` ( `user_id` , `status` ) VALUES (?, ?) /* , ... */",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, digestQuery(tt.digestText))
+		})
+	}
+}
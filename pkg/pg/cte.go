@@ -0,0 +1,43 @@
+package pg
+
+import (
+	"fmt"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+// scanCommonTableExpressionsForIssues reports CTEs that Postgres materializes.
+// Before Postgres 12 every CTE is an optimization fence: it's computed in full
+// and the outer query's predicates are never pushed into it.
+func scanCommonTableExpressionsForIssues(db *dbtypes.DB, query string) ([]issuetypes.QueryIssue, error) {
+	ctes, _, err := plan.SplitCommonTableExpressions(query)
+	if err != nil {
+		return nil, err
+	}
+
+	queryIssues := []issuetypes.QueryIssue{}
+
+	major := majorVersion(db.ServerVersion)
+	for _, cte := range ctes {
+		if major > 0 && major < 12 {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityMedium,
+				IssueType:     issuetypes.QueryIssueTypeMaterializedCTE,
+				Message:       fmt.Sprintf("CTE %q is always materialized on PostgreSQL %d, so filters from the outer query are not pushed into it; inline it as a subquery or upgrade to PostgreSQL 12+", cte.Name, major),
+			})
+			continue
+		}
+
+		if cte.Materialized == "materialized" {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityLow,
+				IssueType:     issuetypes.QueryIssueTypeMaterializedCTE,
+				Message:       fmt.Sprintf("CTE %q is declared MATERIALIZED, so filters from the outer query are not pushed into it", cte.Name),
+			})
+		}
+	}
+
+	return queryIssues, nil
+}
@@ -0,0 +1,398 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+const (
+	// filteredRowCount is the number of rows a scan has to discard with its
+	// filter before the missing index is reported
+	filteredRowCount = 1000
+	// filteredRowFraction is the fraction of the scanned rows a scan has to
+	// discard before the missing index is reported
+	filteredRowFraction = 0.9
+	// largeFilteredRowCount is the number of discarded rows above which the
+	// missing index is a high severity issue
+	largeFilteredRowCount = 100000
+	// misestimateFactor is how far off the planner's row estimate has to be
+	// before it's reported
+	misestimateFactor = 100
+	// misestimateRowCount is the number of rows, estimated or actual, below
+	// which a bad estimate doesn't matter
+	misestimateRowCount = 1000
+)
+
+// Explain returns the plan Postgres picks for a query, without running it, in a
+// transaction that's rolled back. The parameters of the query are bound to the
+// values, which are SQL literals, when they're given. Otherwise Postgres 16 and
+// later plan a generic plan, which doesn't depend on the values, and older
+// versions plan the query with a representative value of each parameter's type.
+func Explain(db *dbtypes.DB, query string, values []string) (string, error) {
+	parameters, err := plan.InferParameters(query, db.Tables)
+	if err != nil {
+		return "", fmt.Errorf("infer parameters: %w", err)
+	}
+
+	explain := "explain " + query
+	note := ""
+	switch {
+	case len(parameters) == 0:
+	case len(values) > 0:
+		bound, err := plan.BindParameters(query, values)
+		if err != nil {
+			return "", err
+		}
+		explain = "explain " + bound
+	case majorVersion(db.ServerVersion) >= 16:
+		explain = "explain (generic_plan) " + query
+		note = "Generic plan, for any value of the parameters"
+	default:
+		representative := []string{}
+		assigned := []string{}
+		for _, parameter := range parameters {
+			value := plan.RepresentativeValue(parameter.Type())
+			representative = append(representative, value)
+			assigned = append(assigned, fmt.Sprintf("%s = %s", parameter.Name, value))
+		}
+		bound, err := plan.BindParameters(query, representative)
+		if err != nil {
+			return "", err
+		}
+		explain = "explain " + bound
+		note = "Planned with representative values: " + strings.Join(assigned, ", ")
+	}
+
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(context.Background())
+
+	// EXPLAIN doesn't run the statement, but the transaction is rolled back
+	// anyway, so nothing the statement could do is ever committed
+	tx, err := conn.Begin(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	rows, err := tx.Query(context.Background(), explain)
+	if err != nil {
+		return "", fmt.Errorf("explain: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []string{}
+	if note != "" {
+		lines = append(lines, note)
+	}
+	for rows.Next() {
+		line := ""
+		if err := rows.Scan(&line); err != nil {
+			return "", fmt.Errorf("scan plan: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("read plan: %w", err)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// ExplainNode is a node of an EXPLAIN plan. The actual values are only set
+// when the plan comes from EXPLAIN ANALYZE, or auto_explain with log_analyze.
+type ExplainNode struct {
+	NodeType string
+	Relation string
+	Alias    string
+	Index    string
+
+	PlanRows    float64
+	HasActual   bool
+	ActualRows  float64
+	ActualLoops float64
+
+	Filter              string
+	RowsRemovedByFilter float64
+	SortSpaceType       string
+
+	Children []*ExplainNode
+}
+
+// ParseExplain parses a plan in the EXPLAIN text or JSON format. An
+// auto_explain dump starts with the text of the query, which is returned as
+// well.
+func ParseExplain(plan string) (*ExplainNode, string, error) {
+	trimmed := strings.TrimSpace(plan)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return parseJSONExplain(trimmed)
+	}
+	return parseTextExplain(plan)
+}
+
+type jsonExplainNode struct {
+	NodeType            string            `json:"Node Type"`
+	RelationName        string            `json:"Relation Name"`
+	Alias               string            `json:"Alias"`
+	IndexName           string            `json:"Index Name"`
+	PlanRows            float64           `json:"Plan Rows"`
+	ActualRows          *float64          `json:"Actual Rows"`
+	ActualLoops         float64           `json:"Actual Loops"`
+	Filter              string            `json:"Filter"`
+	RowsRemovedByFilter float64           `json:"Rows Removed by Filter"`
+	SortSpaceType       string            `json:"Sort Space Type"`
+	Plans               []jsonExplainNode `json:"Plans"`
+}
+
+type jsonExplain struct {
+	QueryText string          `json:"Query Text"`
+	Plan      jsonExplainNode `json:"Plan"`
+}
+
+func parseJSONExplain(plan string) (*ExplainNode, string, error) {
+	explain := jsonExplain{}
+	if strings.HasPrefix(plan, "[") {
+		explains := []jsonExplain{}
+		if err := json.Unmarshal([]byte(plan), &explains); err != nil {
+			return nil, "", fmt.Errorf("unmarshal plan: %w", err)
+		}
+		if len(explains) == 0 {
+			return nil, "", fmt.Errorf("empty plan")
+		}
+		explain = explains[0]
+	} else if err := json.Unmarshal([]byte(plan), &explain); err != nil {
+		return nil, "", fmt.Errorf("unmarshal plan: %w", err)
+	}
+
+	var convert func(node jsonExplainNode) *ExplainNode
+	convert = func(node jsonExplainNode) *ExplainNode {
+		converted := &ExplainNode{
+			NodeType:            node.NodeType,
+			Relation:            node.RelationName,
+			Alias:               node.Alias,
+			Index:               node.IndexName,
+			PlanRows:            node.PlanRows,
+			Filter:              node.Filter,
+			RowsRemovedByFilter: node.RowsRemovedByFilter,
+			SortSpaceType:       node.SortSpaceType,
+		}
+		if node.ActualRows != nil {
+			converted.HasActual = true
+			converted.ActualRows = *node.ActualRows
+			converted.ActualLoops = node.ActualLoops
+		}
+		for _, child := range node.Plans {
+			converted.Children = append(converted.Children, convert(child))
+		}
+		return converted
+	}
+
+	return convert(explain.Plan), explain.QueryText, nil
+}
+
+var (
+	explainCostRegexp   = regexp.MustCompile(`\(cost=[\d.]+\.\.[\d.]+ rows=(\d+)`)
+	explainActualRegexp = regexp.MustCompile(`\(actual (?:time=[\d.]+\.\.[\d.]+ )?rows=([\d.]+) loops=(\d+)\)`)
+	explainScanRegexp   = regexp.MustCompile(`^(.*?)(?: using (\S+))? on (\S+)(?: (\S+))?$`)
+)
+
+func parseTextExplain(plan string) (*ExplainNode, string, error) {
+	var root *ExplainNode
+	type level struct {
+		indent int
+		node   *ExplainNode
+	}
+	stack := []level{}
+	queryText := []string{}
+	inQueryText := false
+
+	for _, line := range strings.Split(plan, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		isNode := strings.Contains(trimmed, "(cost=") || strings.Contains(trimmed, "(actual") || strings.Contains(trimmed, "(never executed)")
+		switch {
+		case strings.HasPrefix(trimmed, "Query Text:"):
+			inQueryText = true
+			queryText = append(queryText, strings.TrimSpace(strings.TrimPrefix(trimmed, "Query Text:")))
+			continue
+		case inQueryText && !isNode:
+			queryText = append(queryText, trimmed)
+			continue
+		}
+		inQueryText = false
+
+		if !isNode {
+			if len(stack) > 0 {
+				parseExplainProperty(stack[len(stack)-1].node, trimmed)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "->") {
+			indent += 2
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "->"))
+		}
+		node := parseExplainNodeLine(trimmed)
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			if root != nil {
+				// a second top level node is an InitPlan or a trigger line
+				continue
+			}
+			root = node
+		} else {
+			parent := stack[len(stack)-1].node
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, level{indent: indent, node: node})
+	}
+
+	if root == nil {
+		return nil, "", fmt.Errorf("no plan nodes found")
+	}
+
+	return root, strings.Join(queryText, "\n"), nil
+}
+
+func parseExplainNodeLine(line string) *ExplainNode {
+	node := &ExplainNode{}
+
+	description := line
+	if open := strings.Index(line, "  ("); open >= 0 {
+		description = line[:open]
+	}
+	node.NodeType = description
+	if matches := explainScanRegexp.FindStringSubmatch(description); matches != nil {
+		node.NodeType = matches[1]
+		node.Index = matches[2]
+		node.Relation = matches[3]
+		node.Alias = matches[4]
+	}
+
+	if matches := explainCostRegexp.FindStringSubmatch(line); matches != nil {
+		node.PlanRows, _ = strconv.ParseFloat(matches[1], 64)
+	}
+	if matches := explainActualRegexp.FindStringSubmatch(line); matches != nil {
+		node.HasActual = true
+		node.ActualRows, _ = strconv.ParseFloat(matches[1], 64)
+		node.ActualLoops, _ = strconv.ParseFloat(matches[2], 64)
+	}
+
+	return node
+}
+
+func parseExplainProperty(node *ExplainNode, property string) {
+	name, value, ok := strings.Cut(property, ": ")
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+
+	switch name {
+	case "Filter":
+		node.Filter = value
+	case "Rows Removed by Filter":
+		node.RowsRemovedByFilter, _ = strconv.ParseFloat(value, 64)
+	case "Sort Method":
+		if strings.Contains(value, "Disk:") {
+			node.SortSpaceType = "Disk"
+		}
+	}
+}
+
+// ScanExplainForIssues reports the problems an executed plan shows: scans
+// that read many rows only to discard them, row estimates that are far off,
+// and sorts that spilled to disk.
+func ScanExplainForIssues(root *ExplainNode) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	var walk func(node *ExplainNode)
+	walk = func(node *ExplainNode) {
+		queryIssues = append(queryIssues, scanExplainNodeForIssues(node)...)
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return queryIssues
+}
+
+func scanExplainNodeForIssues(node *ExplainNode) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+	if !node.HasActual {
+		return queryIssues
+	}
+
+	loops := node.ActualLoops
+	if loops < 1 {
+		loops = 1
+	}
+
+	removed := node.RowsRemovedByFilter * loops
+	scanned := removed + node.ActualRows*loops
+	if strings.HasSuffix(node.NodeType, "Seq Scan") && removed >= filteredRowCount && removed >= scanned*filteredRowFraction {
+		severity := issuetypes.IssueSeverityMedium
+		if removed >= largeFilteredRowCount {
+			severity = issuetypes.IssueSeverityHigh
+		}
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: severity,
+			IssueType:     issuetypes.QueryIssueTypeWhereClauseMissingIndex,
+			Message: fmt.Sprintf("the plan scans %s and discards %.0f of %.0f rows with the filter %s; an index on the filtered columns avoids the scan",
+				node.Relation, removed, scanned, node.Filter),
+		})
+	}
+
+	estimated, actual := node.PlanRows, node.ActualRows
+	if (estimated >= misestimateRowCount || actual >= misestimateRowCount) &&
+		(actual >= estimated*misestimateFactor || estimated >= actual*misestimateFactor) {
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityLow,
+			IssueType:     issuetypes.QueryIssueTypeRowEstimateMismatch,
+			Message: fmt.Sprintf("the planner estimated %.0f rows for %s but got %.0f, so it may have picked the wrong plan; run ANALYZE%s, or create extended statistics for correlated columns",
+				estimated, describeExplainNode(node), actual, analyzeTarget(node)),
+		})
+	}
+
+	if node.SortSpaceType == "Disk" {
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityMedium,
+			IssueType:     issuetypes.QueryIssueTypeSortSpilledToDisk,
+			Message:       "the plan's sort didn't fit in work_mem and spilled to disk; raise work_mem for the query, or add an index that returns the rows in order",
+		})
+	}
+
+	return queryIssues
+}
+
+func describeExplainNode(node *ExplainNode) string {
+	if node.Relation != "" {
+		return fmt.Sprintf("the %s on %s", node.NodeType, node.Relation)
+	}
+	return "the " + node.NodeType
+}
+
+func analyzeTarget(node *ExplainNode) string {
+	if node.Relation != "" {
+		return " " + node.Relation
+	}
+	return ""
+}
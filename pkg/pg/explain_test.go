@@ -0,0 +1,107 @@
+package pg
+
+import (
+	"testing"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const textPlan = `Query Text: select *
+  from orders o join users u on u.id = o.user_id
+  where o.status = 'open' order by o.created_at
+Sort  (cost=20000.00..20000.10 rows=40 width=16) (actual time=1500.000..1510.000 rows=50000 loops=1)
+  Sort Key: o.created_at
+  Sort Method: external merge  Disk: 2048kB
+  ->  Hash Join  (cost=10.00..19999.00 rows=40 width=16) (actual time=0.100..1400.000 rows=50000 loops=1)
+        Hash Cond: (o.user_id = u.id)
+        ->  Seq Scan on orders o  (cost=0.00..18334.00 rows=50000 width=8) (actual time=0.010..1300.000 rows=50000 loops=1)
+              Filter: (status = 'open'::text)
+              Rows Removed by Filter: 950000
+        ->  Hash  (cost=5.00..5.00 rows=100 width=8) (actual time=0.050..0.050 rows=100 loops=1)
+              ->  Index Scan using users_pkey on users u  (cost=0.00..5.00 rows=100 width=8) (actual time=0.010..0.040 rows=100 loops=1)`
+
+func TestParseTextExplain(t *testing.T) {
+	root, query, err := ParseExplain(textPlan)
+	require.NoError(t, err)
+
+	assert.Equal(t, "select *\nfrom orders o join users u on u.id = o.user_id\nwhere o.status = 'open' order by o.created_at", query)
+	assert.Equal(t, "Sort", root.NodeType)
+	assert.Equal(t, "Disk", root.SortSpaceType)
+	require.Len(t, root.Children, 1)
+
+	join := root.Children[0]
+	assert.Equal(t, "Hash Join", join.NodeType)
+	require.Len(t, join.Children, 2)
+
+	scan := join.Children[0]
+	assert.Equal(t, "Seq Scan", scan.NodeType)
+	assert.Equal(t, "orders", scan.Relation)
+	assert.Equal(t, "o", scan.Alias)
+	assert.Equal(t, float64(950000), scan.RowsRemovedByFilter)
+	assert.Equal(t, "(status = 'open'::text)", scan.Filter)
+
+	index := join.Children[1].Children[0]
+	assert.Equal(t, "Index Scan", index.NodeType)
+	assert.Equal(t, "users_pkey", index.Index)
+	assert.Equal(t, "users", index.Relation)
+	assert.True(t, index.HasActual)
+	assert.Equal(t, float64(100), index.ActualRows)
+}
+
+func TestParseJSONExplain(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Limit", "Plan Rows": 10, "Actual Rows": 10, "Actual Loops": 1, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "orders", "Plan Rows": 10, "Actual Rows": 10, "Actual Loops": 1,
+		 "Filter": "(status = 'open'::text)", "Rows Removed by Filter": 5000}]}}]`
+
+	root, _, err := ParseExplain(plan)
+	require.NoError(t, err)
+	assert.Equal(t, "Limit", root.NodeType)
+	require.Len(t, root.Children, 1)
+	assert.Equal(t, "orders", root.Children[0].Relation)
+	assert.Equal(t, float64(5000), root.Children[0].RowsRemovedByFilter)
+
+	root, _, err = ParseExplain(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 10}}]`)
+	require.NoError(t, err)
+	assert.False(t, root.HasActual)
+}
+
+func TestScanExplainForIssues(t *testing.T) {
+	tests := []struct {
+		name  string
+		plan  string
+		types []string
+	}{
+		{
+			name:  "scan, misestimate and sort spill",
+			plan:  textPlan,
+			types: []string{issuetypes.QueryIssueTypeSortSpilledToDisk, issuetypes.QueryIssueTypeRowEstimateMismatch, issuetypes.QueryIssueTypeRowEstimateMismatch, issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+		},
+		{
+			name:  "plan without actual values",
+			plan:  `Seq Scan on orders  (cost=0.00..18334.00 rows=1000000 width=8)`,
+			types: []string{},
+		},
+		{
+			name: "selective filter",
+			plan: `Seq Scan on orders  (cost=0.00..18334.00 rows=900 width=8) (actual time=0.010..10.000 rows=900 loops=1)
+  Filter: (status = 'open'::text)
+  Rows Removed by Filter: 100`,
+			types: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, _, err := ParseExplain(tt.plan)
+			require.NoError(t, err)
+
+			types := []string{}
+			for _, queryIssue := range ScanExplainForIssues(root) {
+				types = append(types, queryIssue.IssueType)
+			}
+			assert.ElementsMatch(t, tt.types, types)
+		})
+	}
+}
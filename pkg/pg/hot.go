@@ -0,0 +1,73 @@
+package pg
+
+import (
+	"fmt"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+const (
+	// defaultFillFactor is the fillfactor of a table that doesn't set one.
+	// Full pages leave no room for the new version of an updated row, so HOT
+	// updates have to wait for pruning to free up space.
+	defaultFillFactor = 100
+
+	// hotUpdateRowCount is the number of rows an UPDATE changes above which a
+	// table with the default fillfactor is reported
+	hotUpdateRowCount = 1000
+)
+
+// scanWriteCostsForIssues adjusts the write cost model to the Postgres heap.
+// Postgres doesn't store rows in the primary key: an UPDATE writes a new row
+// version, and either every index gets an entry for it, or, when no indexed
+// column changes and the page has room, none does (a heap only tuple update).
+// A DELETE only marks the row, and vacuum removes the index entries later.
+func scanWriteCostsForIssues(db *dbtypes.DB, query string) ([]issuetypes.QueryIssue, error) {
+	costs, err := plan.EstimateWriteCosts(query, db.Tables)
+	if err != nil {
+		return nil, fmt.Errorf("estimate write costs: %w", err)
+	}
+
+	queryIssues := []issuetypes.QueryIssue{}
+
+	for i, cost := range costs {
+		switch cost.Statement {
+		case "UPDATE":
+			if len(cost.IndexesWritten) == 0 && !cost.MovesRow {
+				costs[i].HeapOnlyTuple = true
+
+				fillFactor := tableFillFactor(db, cost.Table)
+				if fillFactor >= defaultFillFactor && cost.EstimatedRows >= hotUpdateRowCount {
+					queryIssues = append(queryIssues, issuetypes.QueryIssue{
+						IssueSeverity: issuetypes.IssueSeverityLow,
+						IssueType:     issuetypes.QueryIssueTypeWriteAmplification,
+						Message: fmt.Sprintf("UPDATE of %s changes no indexed column and can be a HOT update, but the table has fillfactor %d, so pages have no free space for the new row versions; consider ALTER TABLE %s SET (fillfactor = 90)",
+							cost.Table, fillFactor, cost.Table),
+					})
+				}
+				continue
+			}
+
+			costs[i].IndexesWritten = cost.Indexes
+			costs[i].MovesRow = false
+			costs[i].Notes = append(costs[i].Notes, "an indexed column changes, so the update can't be HOT and every index gets an entry for the new row version")
+
+		case "DELETE":
+			costs[i].IndexesWritten = []string{}
+			costs[i].Notes = append(costs[i].Notes, "index entries are removed by vacuum")
+		}
+	}
+
+	return append(queryIssues, plan.ScanWriteCostsForIssues(costs)...), nil
+}
+
+func tableFillFactor(db *dbtypes.DB, tableName string) int {
+	for _, table := range db.Tables {
+		if postgresTable, ok := table.(PostgresTable); ok && postgresTable.GetName() == tableName && postgresTable.FillFactor > 0 {
+			return postgresTable.FillFactor
+		}
+	}
+	return defaultFillFactor
+}
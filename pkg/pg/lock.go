@@ -0,0 +1,163 @@
+package pg
+
+import (
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+// Postgres table lock modes, weakest first, see
+// https://www.postgresql.org/docs/current/explicit-locking.html
+const (
+	lockAccessShare          = "ACCESS SHARE"
+	lockRowShare             = "ROW SHARE"
+	lockRowExclusive         = "ROW EXCLUSIVE"
+	lockShareUpdateExclusive = "SHARE UPDATE EXCLUSIVE"
+	lockShare                = "SHARE"
+	lockShareRowExclusive    = "SHARE ROW EXCLUSIVE"
+	lockExclusive            = "EXCLUSIVE"
+	lockAccessExclusive      = "ACCESS EXCLUSIVE"
+)
+
+var tableLockStrength = map[string]int{
+	lockAccessShare:          1,
+	lockRowShare:             2,
+	lockRowExclusive:         3,
+	lockShareUpdateExclusive: 4,
+	lockShare:                5,
+	lockShareRowExclusive:    6,
+	lockExclusive:            7,
+	lockAccessExclusive:      8,
+}
+
+// EstimateLocks returns the locks a statement takes in Postgres. Row locks
+// are only taken on the rows a statement returns or changes; there are no
+// gap locks, so a scan that matches nothing locks nothing. DDL takes one of
+// the table lock modes, and the strong ones block reads and writes for as long
+// as the statement runs.
+func EstimateLocks(db *dbtypes.DB, query string) ([]plan.Lock, error) {
+	if ddl, ok := plan.ParseDDL(query); ok {
+		return ddlLocks(ddl, db.Tables), nil
+	}
+
+	locks, err := plan.EstimateLocks(query, db.Tables)
+	if err != nil {
+		return nil, err
+	}
+
+	clause, _ := plan.SplitLockingClause(query)
+
+	rowLocks := []plan.Lock{}
+	for _, lock := range locks {
+		switch {
+		case clause != nil:
+			lock.TableLock = lockRowShare
+		case lock.Mode == plan.LockModeExclusive:
+			lock.TableLock = lockRowExclusive
+		default:
+			// plain reads of the other tables of a write don't lock rows
+			continue
+		}
+
+		if lock.Granularity == plan.LockGranularityTable && lock.Detail == "" {
+			lock.Detail = "no index serves the predicate, so every row is scanned, but only the matching rows are locked"
+		}
+		lock.Granularity = plan.LockGranularityRow
+
+		rowLocks = append(rowLocks, lock)
+	}
+
+	return rowLocks, nil
+}
+
+// ddlLocks returns the table lock a schema change takes. An ALTER TABLE takes
+// the strongest lock any of its actions needs.
+func ddlLocks(ddl *plan.DDLStatement, tables []dbtypes.Table) []plan.Lock {
+	if ddl.Table == "" {
+		return []plan.Lock{}
+	}
+
+	mode := lockAccessExclusive
+	switch ddl.Kind {
+	case "create index":
+		mode = lockShare
+		if ddl.Concurrently {
+			mode = lockShareUpdateExclusive
+		}
+	case "alter table":
+		mode = ""
+		for _, action := range ddl.Actions {
+			actionMode := alterTableLockMode(action)
+			if tableLockStrength[actionMode] > tableLockStrength[mode] {
+				mode = actionMode
+			}
+		}
+	case "vacuum", "analyze":
+		mode = lockShareUpdateExclusive
+	case "reindex":
+		mode = lockShare
+		if ddl.Concurrently {
+			mode = lockShareUpdateExclusive
+		}
+	case "refresh materialized view":
+		if ddl.Concurrently {
+			mode = lockExclusive
+		}
+	case "create trigger":
+		mode = lockShareRowExclusive
+	case "lock table":
+		if ddl.LockMode != "" {
+			mode = ddl.LockMode
+		}
+	case "create table":
+		return []plan.Lock{}
+	}
+
+	lock := plan.Lock{
+		Table:     ddl.Table,
+		TableLock: mode,
+	}
+	for _, table := range tables {
+		if table.GetName() == ddl.Table {
+			lock.EstimatedRows = table.GetEstimatedRowCount()
+		}
+	}
+
+	// SHARE and stronger block writes, and ACCESS EXCLUSIVE blocks reads too
+	if tableLockStrength[mode] >= tableLockStrength[lockShare] {
+		lock.Mode = plan.LockModeExclusive
+		lock.Granularity = plan.LockGranularityTable
+		switch mode {
+		case lockAccessExclusive:
+			lock.Detail = "blocks reads and writes, and queues every later query on the table behind it while it waits"
+		default:
+			lock.Detail = "blocks writes to the table while it runs"
+		}
+	}
+
+	return []plan.Lock{lock}
+}
+
+// alterTableLockMode returns the lock an ALTER TABLE action takes.
+func alterTableLockMode(action string) string {
+	action = strings.ToLower(strings.Join(strings.Fields(action), " "))
+
+	switch {
+	case strings.HasPrefix(action, "add") && !strings.HasPrefix(action, "add column") && strings.Contains(action, "foreign key"):
+		return lockShareRowExclusive
+	case strings.HasPrefix(action, "validate constraint"),
+		strings.Contains(action, "set statistics"),
+		strings.HasPrefix(action, "cluster on"),
+		strings.HasPrefix(action, "set without cluster"),
+		strings.HasPrefix(action, "set ("),
+		strings.HasPrefix(action, "reset ("):
+		return lockShareUpdateExclusive
+	case strings.HasPrefix(action, "enable trigger"), strings.HasPrefix(action, "disable trigger"):
+		return lockShareRowExclusive
+	case strings.HasPrefix(action, "attach partition"), strings.HasPrefix(action, "detach partition") && strings.HasSuffix(action, "concurrently"):
+		return lockShareUpdateExclusive
+	}
+
+	return lockAccessExclusive
+}
@@ -0,0 +1,69 @@
+package pg
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateLocks_ddl(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		wantTableLock string
+		wantMode      string
+	}{
+		{
+			name:          "create index",
+			query:         "create index orders_status on orders (status)",
+			wantTableLock: lockShare,
+			wantMode:      plan.LockModeExclusive,
+		},
+		{
+			name:          "create index concurrently",
+			query:         "create index concurrently orders_status on orders (status)",
+			wantTableLock: lockShareUpdateExclusive,
+		},
+		{
+			name:          "add foreign key",
+			query:         "alter table orders add constraint orders_user_fk foreign key (user_id) references users (id)",
+			wantTableLock: lockShareRowExclusive,
+			wantMode:      plan.LockModeExclusive,
+		},
+		{
+			name:          "strongest action wins",
+			query:         "alter table orders validate constraint orders_user_fk, add column total int",
+			wantTableLock: lockAccessExclusive,
+			wantMode:      plan.LockModeExclusive,
+		},
+		{
+			name:          "validate constraint",
+			query:         "alter table orders validate constraint orders_user_fk",
+			wantTableLock: lockShareUpdateExclusive,
+		},
+		{
+			name:          "refresh concurrently",
+			query:         "refresh materialized view concurrently order_totals",
+			wantTableLock: lockExclusive,
+			wantMode:      plan.LockModeExclusive,
+		},
+		{
+			name:          "lock table default mode",
+			query:         "lock table orders",
+			wantTableLock: lockAccessExclusive,
+			wantMode:      plan.LockModeExclusive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locks, err := EstimateLocks(&dbtypes.DB{}, tt.query)
+			require.NoError(t, err)
+			require.Len(t, locks, 1)
+			assert.Equal(t, tt.wantTableLock, locks[0].TableLock)
+			assert.Equal(t, tt.wantMode, locks[0].Mode)
+		})
+	}
+}
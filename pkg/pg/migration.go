@@ -0,0 +1,262 @@
+package pg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+)
+
+// volatileFunctions are the functions that return a different value for each
+// row, so a column default that calls one has to be written to every row.
+var volatileFunctions = []string{
+	"random(", "clock_timestamp(", "timeofday(", "gen_random_uuid(", "uuid_generate_v1(",
+	"uuid_generate_v1mc(", "uuid_generate_v4(", "nextval(",
+}
+
+// typeAliases maps the names information_schema reports to the names used in
+// DDL.
+var typeAliases = map[string]string{
+	"character varying":           "varchar",
+	"character":                   "char",
+	"int":                         "integer",
+	"int4":                        "integer",
+	"int8":                        "bigint",
+	"int2":                        "smallint",
+	"decimal":                     "numeric",
+	"bool":                        "boolean",
+	"float8":                      "double precision",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+}
+
+// scanDDLStatementForIssues reports the schema changes that block queries on a
+// table for as long as they take to run, which grows with the size of the
+// table, and the changes that fail on a table with rows or with views.
+func scanDDLStatementForIssues(db *dbtypes.DB, ddl *plan.DDLStatement) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	rows := plan.EstimatedRowCount(db.Tables, ddl.Table)
+	severity := plan.MigrationSeverity(db.Tables, ddl.Table)
+
+	switch ddl.Kind {
+	case "create index":
+		if !ddl.Concurrently {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeBlockingIndexBuild,
+				Message: fmt.Sprintf("CREATE INDEX on %s (~%d rows) takes a SHARE lock that blocks writes until the index is built; use CREATE INDEX CONCURRENTLY",
+					ddl.Table, rows),
+			})
+		}
+
+	case "reindex":
+		if !ddl.Concurrently && ddl.Table != "" {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeBlockingIndexBuild,
+				Message: fmt.Sprintf("REINDEX of %s (~%d rows) blocks writes until every index is rebuilt; use REINDEX CONCURRENTLY",
+					ddl.Table, rows),
+			})
+		}
+
+	case "vacuum full", "cluster":
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: severity,
+			IssueType:     issuetypes.QueryIssueTypeTableRewrite,
+			Message: fmt.Sprintf("%s rewrites %s (~%d rows) under an ACCESS EXCLUSIVE lock that blocks reads and writes until it finishes; consider pg_repack",
+				strings.ToUpper(ddl.Kind), ddl.Table, rows),
+		})
+
+	case "drop table":
+		queryIssues = append(queryIssues, scanForDependentViews(db, ddl.Table, "", "DROP TABLE")...)
+
+	case "alter table":
+		for _, text := range ddl.Actions {
+			action := plan.ParseAlterAction(text)
+			queryIssues = append(queryIssues, scanAlterActionForIssues(db, ddl.Table, action, text, rows, severity)...)
+		}
+	}
+
+	return queryIssues
+}
+
+func scanAlterActionForIssues(db *dbtypes.DB, tableName string, action plan.AlterAction, text string, rows int64, severity string) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	switch action.Kind {
+	case plan.AlterActionAddColumn:
+		if reason := addColumnRewriteReason(db, action, text); reason != "" {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeTableRewrite,
+				Message: fmt.Sprintf("adding column %s to %s %s, so the table (~%d rows) is rewritten under an ACCESS EXCLUSIVE lock; add the column without the default and backfill it in batches",
+					action.Column, tableName, reason, rows),
+			})
+		}
+
+		if action.NotNull && action.Default == "" && rows > 0 {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityHigh,
+				IssueType:     issuetypes.QueryIssueTypeNotNullWithoutDefault,
+				Message: fmt.Sprintf("adding NOT NULL column %s without a default fails because %s already has rows; add a default, or add the column as nullable, backfill it and then set NOT NULL",
+					action.Column, tableName),
+			})
+		}
+
+	case plan.AlterActionAlterColumnType:
+		// for a column that isn't in the schema, whether the change rewrites
+		// the table is unknown
+		column := plan.FindColumn(db.Tables, tableName, action.Column)
+		if column != nil && typeChangeRewrites(column.GetDataType(), action.Type) {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeTableRewrite,
+				Message: fmt.Sprintf("changing the type of %s.%s to %s rewrites the table (~%d rows) and its indexes under an ACCESS EXCLUSIVE lock; add a new column, backfill it and swap them instead",
+					tableName, action.Column, action.Type, rows),
+			})
+		}
+		queryIssues = append(queryIssues, scanForDependentViews(db, tableName, action.Column, "changing the type of "+action.Column)...)
+
+	case plan.AlterActionSetNotNull:
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: severity,
+			IssueType:     issuetypes.QueryIssueTypeBlockingAlter,
+			Message: fmt.Sprintf("SET NOT NULL on %s.%s scans the table (~%d rows) under an ACCESS EXCLUSIVE lock; first add CHECK (%s IS NOT NULL) NOT VALID and validate it, and Postgres 12 and later skip the scan",
+				tableName, action.Column, rows, action.Column),
+		})
+
+	case plan.AlterActionAddForeignKey:
+		if !action.NotValid {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeForeignKeyValidation,
+				Message: fmt.Sprintf("adding a foreign key from %s to %s checks every row (~%d rows) while it holds a SHARE ROW EXCLUSIVE lock on both tables, which blocks writes; add it NOT VALID and run VALIDATE CONSTRAINT separately",
+					tableName, action.References, rows),
+			})
+		}
+
+	case plan.AlterActionAddConstraint:
+		if !action.NotValid {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeBlockingAlter,
+				Message: fmt.Sprintf("adding a constraint to %s checks every row (~%d rows) under an ACCESS EXCLUSIVE lock; add it NOT VALID and run VALIDATE CONSTRAINT separately",
+					tableName, rows),
+			})
+		}
+
+	case plan.AlterActionAddPrimaryKey, plan.AlterActionAddIndex:
+		if !action.UsingIndex {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeBlockingIndexBuild,
+				Message: fmt.Sprintf("adding a constraint to %s builds its index (~%d rows) under an ACCESS EXCLUSIVE lock; build a unique index CONCURRENTLY first and add the constraint USING INDEX",
+					tableName, rows),
+			})
+		}
+
+	case plan.AlterActionDropColumn:
+		queryIssues = append(queryIssues, scanForDependentViews(db, tableName, action.Column, "dropping "+action.Column)...)
+	}
+
+	return queryIssues
+}
+
+// addColumnRewriteReason returns why adding a column rewrites the table, or an
+// empty string if the column is only added to the catalog. Since Postgres 11
+// a constant default is stored once instead of in every row.
+func addColumnRewriteReason(db *dbtypes.DB, action plan.AlterAction, text string) string {
+	lowered := strings.ToLower(text)
+	defaultValue := strings.ToLower(strings.ReplaceAll(action.Default, " ", ""))
+
+	switch {
+	case strings.Contains(lowered, "generated always as") && strings.Contains(lowered, "stored"):
+		return "computes a stored generated value for every row"
+	case strings.HasSuffix(action.Type, "serial"):
+		return "assigns a sequence value to every row"
+	case defaultValue == "" || defaultValue == "null":
+		return ""
+	case containsAny(defaultValue, volatileFunctions):
+		return fmt.Sprintf("has the volatile default %s", action.Default)
+	}
+
+	if major := majorVersion(db.ServerVersion); major > 0 && major < 11 {
+		return fmt.Sprintf("has a default, and Postgres %d writes it to every row", major)
+	}
+
+	return ""
+}
+
+// typeChangeRewrites returns true if changing a column from one type to
+// another rewrites the table. Only a few changes are binary coercible, such as
+// making a varchar longer or changing it to text.
+func typeChangeRewrites(from string, to string) bool {
+	fromName, fromLength := normalizeType(from)
+	toName, toLength := normalizeType(to)
+
+	switch {
+	case fromName == toName && fromLength == toLength:
+		return false
+	case fromName == "varchar" && toName == "text":
+		return false
+	case (fromName == "varchar" || fromName == "text") && toName == "varchar" && toLength == 0:
+		return false
+	case fromName == toName && (fromName == "varchar" || fromName == "numeric") && fromLength > 0 && (toLength == 0 || toLength >= fromLength):
+		return false
+	}
+
+	return true
+}
+
+// normalizeType returns the name and length of a type, such as "varchar" and
+// 255 for "character varying (255)".
+func normalizeType(columnType string) (string, int) {
+	columnType = strings.ToLower(strings.TrimSpace(columnType))
+
+	name, length := columnType, 0
+	if open := strings.Index(columnType, "("); open >= 0 {
+		name = strings.TrimSpace(columnType[:open])
+		arguments := strings.TrimSuffix(strings.TrimSpace(columnType[open+1:]), ")")
+		if n, err := strconv.Atoi(strings.TrimSpace(strings.Split(arguments, ",")[0])); err == nil {
+			length = n
+		}
+	}
+
+	name = strings.Join(strings.Fields(name), " ")
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+
+	return name, length
+}
+
+// scanForDependentViews reports the views that make a change fail, because
+// Postgres doesn't drop or change a column or a table a view depends on.
+func scanForDependentViews(db *dbtypes.DB, tableName string, columnName string, change string) []issuetypes.QueryIssue {
+	views := plan.DependentViews(db.Views, tableName, columnName)
+	if len(views) == 0 {
+		return []issuetypes.QueryIssue{}
+	}
+
+	return []issuetypes.QueryIssue{
+		{
+			IssueSeverity: issuetypes.IssueSeverityHigh,
+			IssueType:     issuetypes.QueryIssueTypeBreaksDependentView,
+			Message: fmt.Sprintf("%s fails because the views %s depend on %s; drop and recreate them in the same transaction",
+				change, strings.Join(views, ", "), tableName),
+		},
+	}
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
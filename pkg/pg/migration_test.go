@@ -0,0 +1,111 @@
+package pg
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanDDLStatementForIssues(t *testing.T) {
+	db := &dbtypes.DB{
+		ServerVersion: "16.2",
+		Tables: []dbtypes.Table{
+			PostgresTable{
+				TableName: "orders",
+				Columns: []PostgresColumn{
+					{ColumnName: "id", DataType: "bigint"},
+					{ColumnName: "status", DataType: "character varying (32)"},
+					{ColumnName: "total", DataType: "integer"},
+				},
+				EstimatedRowCount: 1000000,
+			},
+			PostgresTable{TableName: "audit", EstimatedRowCount: 0},
+		},
+		Views: []dbtypes.View{
+			{Name: "order_totals", Definition: " SELECT orders.id, orders.total FROM orders;"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantTypes []string
+	}{
+		{
+			name:      "create index",
+			query:     "create index orders_status on orders (status)",
+			wantTypes: []string{issuetypes.QueryIssueTypeBlockingIndexBuild},
+		},
+		{
+			name:      "create index concurrently",
+			query:     "create index concurrently orders_status on orders (status)",
+			wantTypes: []string{},
+		},
+		{
+			name:      "constant default",
+			query:     "alter table orders add column archived boolean not null default false",
+			wantTypes: []string{},
+		},
+		{
+			name:      "volatile default",
+			query:     "alter table orders add column token uuid not null default gen_random_uuid()",
+			wantTypes: []string{issuetypes.QueryIssueTypeTableRewrite},
+		},
+		{
+			name:      "not null without a default",
+			query:     "alter table orders add column region text not null",
+			wantTypes: []string{issuetypes.QueryIssueTypeNotNullWithoutDefault},
+		},
+		{
+			name:      "not null without a default on an empty table",
+			query:     "alter table audit add column region text not null",
+			wantTypes: []string{},
+		},
+		{
+			name:      "widen a varchar",
+			query:     "alter table orders alter column status type varchar(64)",
+			wantTypes: []string{},
+		},
+		{
+			name:      "type change used by a view",
+			query:     "alter table orders alter column total type bigint",
+			wantTypes: []string{issuetypes.QueryIssueTypeTableRewrite, issuetypes.QueryIssueTypeBreaksDependentView},
+		},
+		{
+			name:      "type change of a column that isn't in the schema",
+			query:     "alter table orders alter column missing type bigint",
+			wantTypes: []string{},
+		},
+		{
+			name:      "foreign key",
+			query:     "alter table orders add constraint orders_user_fk foreign key (user_id) references users (id)",
+			wantTypes: []string{issuetypes.QueryIssueTypeForeignKeyValidation},
+		},
+		{
+			name:      "foreign key not valid",
+			query:     "alter table orders add constraint orders_user_fk foreign key (user_id) references users (id) not valid",
+			wantTypes: []string{},
+		},
+		{
+			name:      "rename keeps views working",
+			query:     "alter table orders rename column total to amount",
+			wantTypes: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ddl, ok := plan.ParseDDL(tt.query)
+			require.True(t, ok)
+
+			issueTypes := []string{}
+			for _, issue := range scanDDLStatementForIssues(db, ddl) {
+				issueTypes = append(issueTypes, issue.IssueType)
+			}
+			assert.Equal(t, tt.wantTypes, issueTypes)
+		})
+	}
+}
@@ -2,70 +2,148 @@ package pg
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/blastrain/vitess-sqlparser/sqlparser"
 	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/fingerprint"
 	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
 	"github.com/queryplan-ai/qp/pkg/plan"
 )
 
 func PlanQuery(db *dbtypes.DB, query string) (string, error) {
-	stmt, err := sqlparser.Parse(query)
+	issues, err := ScanQueryForIssues(db, query)
 	if err != nil {
 		return "", err
 	}
+	if issues == nil {
+		return "", nil
+	}
+
+	locks, err := EstimateLocks(db, query)
+	if err != nil {
+		return "", fmt.Errorf("estimate locks: %w", err)
+	}
+
+	var parameters []plan.Parameter
+	if _, isDDL := plan.ParseDDL(query); !isDDL {
+		parameters, err = plan.InferParameters(query, db.Tables)
+		if err != nil {
+			return "", fmt.Errorf("infer parameters: %w", err)
+		}
+	}
+
+	message := "No issues found"
+	if len(issues) > 0 {
+		message = formatIssues(issues)
+	}
+
+	for _, section := range []string{plan.FormatLocks(locks), plan.FormatParameters(parameters)} {
+		if section != "" {
+			message = strings.TrimSuffix(message, "\n") + "\n" + section
+		}
+	}
+
+	return message, nil
+}
+
+// ScanQueryForIssues returns the issues with a query, or nil if the statement
+// isn't one that's planned. Every issue has the fingerprint of the query as its
+// QueryID, so the issues of the same query with different parameters are
+// grouped.
+func ScanQueryForIssues(db *dbtypes.DB, query string) ([]issuetypes.QueryIssue, error) {
+	issues, err := scanStatementForIssues(db, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, isDDL := plan.ParseDDL(query); issues != nil && !isDDL {
+		comparisonIssues, err := plan.ScanComparisonTypesForIssues(query, db.Tables, plan.EnginePostgres)
+		if err != nil {
+			return nil, fmt.Errorf("scan comparison types for issues: %w", err)
+		}
+		issues = append(issues, comparisonIssues...)
+	}
+
+	issues = db.IssueFilter.Apply(issues)
+
+	queryID := fingerprint.Fingerprint(query)
+	for i := range issues {
+		issues[i].QueryID = queryID
+	}
+
+	return issues, nil
+}
+
+func scanStatementForIssues(db *dbtypes.DB, query string) ([]issuetypes.QueryIssue, error) {
+	if ddl, ok := plan.ParseDDL(query); ok {
+		return scanDDLStatementForIssues(db, ddl), nil
+	}
+
+	stmt, err := plan.Parse(query)
+	if err != nil {
+		return nil, err
+	}
 
 	switch stmt.(type) {
 	case *sqlparser.Select:
 		issues, err := plan.ScanSelectStatementForIssues(query, db.Tables)
 		if err != nil {
-			return "", fmt.Errorf("scan select statement for issues: %w", err)
+			return nil, fmt.Errorf("scan select statement for issues: %w", err)
 		}
 
-		if len(issues) > 0 {
-			return formatIssues(issues), nil
+		cteIssues, err := scanCommonTableExpressionsForIssues(db, query)
+		if err != nil {
+			return nil, fmt.Errorf("scan common table expressions for issues: %w", err)
 		}
+		issues = append(issues, cteIssues...)
 
-		return "No issues found", nil
+		return issues, nil
 
 	case *sqlparser.Update:
-		issues, err := plan.ScanUpdateStatementForIssues(query, db.Tables)
+		issues, err := plan.ScanUpdateStatementForIssues(query, db.Tables, plan.EnginePostgres)
 		if err != nil {
-			return "", fmt.Errorf("scan update statement for issues: %w", err)
+			return nil, fmt.Errorf("scan update statement for issues: %w", err)
 		}
 
-		if len(issues) > 0 {
-			return formatIssues(issues), nil
+		writeCostIssues, err := scanWriteCostsForIssues(db, query)
+		if err != nil {
+			return nil, fmt.Errorf("scan write costs for issues: %w", err)
 		}
+		issues = append(issues, writeCostIssues...)
 
-		return "No issues found", nil
+		return issues, nil
 
 	case *sqlparser.Insert:
 		issues, err := plan.ScanInsertStatementForIssues(query, db.Tables)
 		if err != nil {
-			return "", fmt.Errorf("scan insert statement for issues: %w", err)
+			return nil, fmt.Errorf("scan insert statement for issues: %w", err)
 		}
 
-		if len(issues) > 0 {
-			return formatIssues(issues), nil
+		writeCostIssues, err := scanWriteCostsForIssues(db, query)
+		if err != nil {
+			return nil, fmt.Errorf("scan write costs for issues: %w", err)
 		}
+		issues = append(issues, writeCostIssues...)
 
-		return "No issues found", nil
+		return issues, nil
 
 	case *sqlparser.Delete:
-		issues, err := plan.ScanDeleteStatementForIssues(query, db.Tables)
+		issues, err := plan.ScanDeleteStatementForIssues(query, db.Tables, plan.EnginePostgres)
 		if err != nil {
-			return "", fmt.Errorf("scan delete statement for issues: %w", err)
+			return nil, fmt.Errorf("scan delete statement for issues: %w", err)
 		}
 
-		if len(issues) > 0 {
-			return formatIssues(issues), nil
+		writeCostIssues, err := scanWriteCostsForIssues(db, query)
+		if err != nil {
+			return nil, fmt.Errorf("scan write costs for issues: %w", err)
 		}
+		issues = append(issues, writeCostIssues...)
 
-		return "No issues found", nil
+		return issues, nil
 	}
 
-	return "", nil
+	return nil, nil
 }
 
 func formatIssues(issues []issuetypes.QueryIssue) string {
@@ -0,0 +1,111 @@
+package pg
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+)
+
+// Run executes a query the number of times in opts, each time in a
+// transaction that's rolled back, and returns the first rows of the first run
+// and how long every run took.
+func Run(db *dbtypes.DB, query string, opts dbtypes.RunOptions) (*dbtypes.RunResult, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+
+	txOptions := pgx.TxOptions{}
+	if opts.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	result := &dbtypes.RunResult{}
+	for i := 0; i < max(opts.Runs, 1); i++ {
+		if err := runOnce(conn, query, txOptions, opts.MaxRows, result, i == 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func runOnce(conn *pgx.Conn, query string, txOptions pgx.TxOptions, maxRows int, result *dbtypes.RunResult, keepRows bool) error {
+	ctx := context.Background()
+
+	tx, err := conn.BeginTx(ctx, txOptions)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	start := time.Now()
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	rowCount := int64(0)
+	for rows.Next() {
+		rowCount++
+		if !keepRows || len(result.Rows) >= maxRows {
+			continue
+		}
+		values, err := rows.Values()
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("values: %w", err)
+		}
+		row := []string{}
+		for _, value := range values {
+			row = append(row, formatValue(value))
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows: %w", err)
+	}
+	result.Durations = append(result.Durations, time.Since(start))
+
+	if keepRows {
+		for _, field := range rows.FieldDescriptions() {
+			result.Columns = append(result.Columns, field.Name)
+		}
+		// a statement without RETURNING returns no rows, and its count is
+		// the rows it changed
+		result.RowCount = rowCount
+		if len(rows.FieldDescriptions()) == 0 {
+			result.RowCount = rows.CommandTag().RowsAffected()
+		}
+	}
+
+	return nil
+}
+
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(value)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", value[0:4], value[4:6], value[6:8], value[8:10], value[10:16])
+	case driver.Valuer:
+		// numerics, intervals and the other pgtype values are formatted as
+		// their text representation
+		if v, err := value.Value(); err == nil {
+			return formatValue(v)
+		}
+	case fmt.Stringer:
+		return value.String()
+	}
+	return fmt.Sprint(value)
+}
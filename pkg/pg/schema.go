@@ -17,26 +17,72 @@ func LoadSchema(db *dbtypes.DB) error {
 		db.SchemaLoading = false
 	}()
 
+	serverVersion, err := getServerVersion(db)
+	if err != nil {
+		return fmt.Errorf("get server version: %w", err)
+	}
+	db.ServerVersion = serverVersion
+
 	tables, err := listTables(db)
 	if err != nil {
 		return fmt.Errorf("list tables: %w", err)
 	}
 
+	views, err := listViews(db)
+	if err != nil {
+		return fmt.Errorf("list views: %w", err)
+	}
+
 	db.SchemaLoaded = true
 	db.Tables = tables
+	db.Views = views
 
 	return nil
 }
 
+func getServerVersion(db *dbtypes.DB) (string, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(context.Background())
+
+	var serverVersion string
+	if err := conn.QueryRow(context.Background(), "show server_version").Scan(&serverVersion); err != nil {
+		return "", fmt.Errorf("scan server version: %w", err)
+	}
+
+	return serverVersion, nil
+}
+
+// majorVersion returns the major version number of a server_version string
+// such as "16.2 (Debian 16.2-1.pgdg120+2)", or 0 if it's unknown.
+func majorVersion(serverVersion string) int {
+	major := 0
+	for _, c := range serverVersion {
+		if c < '0' || c > '9' {
+			break
+		}
+		major = major*10 + int(c-'0')
+	}
+	return major
+}
+
 func listTables(db *dbtypes.DB) ([]dbtypes.Table, error) {
 	conn, err := connect(db.ConnectionURI)
 	if err != nil {
 		return nil, err
 	}
 
-	query := "select table_name from information_schema.tables where table_catalog = $1 and table_schema = $2"
+	// reltuples is the planner's row estimate, and is -1 for tables that have
+	// never been analyzed
+	query := `select t.table_name, greatest(coalesce(c.reltuples, 0), 0)::bigint,
+  coalesce((select option_value from pg_options_to_table(c.reloptions) where option_name = 'fillfactor'), '100')::int
+from information_schema.tables t
+left join pg_class c on c.relname = t.table_name and c.relnamespace = to_regnamespace(t.table_schema)
+where t.table_catalog = $1 and t.table_schema = current_schema()`
 
-	rows, err := conn.Query(context.Background(), query, db.DatabaseName, "public")
+	rows, err := conn.Query(context.Background(), query, db.DatabaseName)
 	if err != nil {
 		return nil, fmt.Errorf("query tables: %w", err)
 	}
@@ -45,17 +91,31 @@ func listTables(db *dbtypes.DB) ([]dbtypes.Table, error) {
 	tables := []dbtypes.Table{}
 	for rows.Next() {
 		tableName := ""
-		if err := rows.Scan(&tableName); err != nil {
+		estimatedRowCount := int64(0)
+		fillFactor := 0
+		if err := rows.Scan(&tableName, &estimatedRowCount, &fillFactor); err != nil {
 			return nil, fmt.Errorf("scan tables: %w", err)
 		}
 
 		postgresTable := PostgresTable{
-			TableName: tableName,
+			TableName:         tableName,
+			EstimatedRowCount: estimatedRowCount,
+			FillFactor:        fillFactor,
 		}
 
 		tables = append(tables, postgresTable)
 	}
 
+	indexes, err := listIndexes(db)
+	if err != nil {
+		return nil, err
+	}
+
+	foreignKeys, err := listForeignKeys(db)
+	if err != nil {
+		return nil, err
+	}
+
 	// load columns for each table
 	for i, table := range tables {
 		postgresTable := tables[i].(PostgresTable)
@@ -72,19 +132,51 @@ func listTables(db *dbtypes.DB) ([]dbtypes.Table, error) {
 		}
 		postgresTable.PrimaryKeys = primaryKeys
 
+		postgresTable.Indexes = indexes[table.GetName()]
+		postgresTable.ForeignKeys = foreignKeys[table.GetName()]
+
 		tables[i] = postgresTable
 	}
 
 	return tables, nil
 }
 
+func listViews(db *dbtypes.DB) ([]dbtypes.View, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+
+	query := `select viewname, definition from pg_views where schemaname = current_schema()
+union all
+select matviewname, definition from pg_matviews where schemaname = current_schema()`
+
+	rows, err := conn.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("query views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []dbtypes.View{}
+	for rows.Next() {
+		view := dbtypes.View{}
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			return nil, fmt.Errorf("scan views: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	return views, nil
+}
+
 func listColumns(db *dbtypes.DB, tableName string) ([]PostgresColumn, error) {
 	conn, err := connect(db.ConnectionURI)
 	if err != nil {
 		return nil, err
 	}
 
-	query := "select column_name, data_type, character_maximum_length, column_default, is_nullable from information_schema.columns where table_name = $1 and table_catalog = $2"
+	query := "select column_name, data_type, character_maximum_length, column_default, is_nullable, is_identity, is_generated from information_schema.columns where table_name = $1 and table_catalog = $2"
 
 	rows, err := conn.Query(context.Background(), query, tableName, db.DatabaseName)
 	if err != nil {
@@ -99,13 +191,23 @@ func listColumns(db *dbtypes.DB, tableName string) ([]PostgresColumn, error) {
 		var maxLength sql.NullInt64
 		var isNullable string
 		var columnDefault sql.NullString
+		var isIdentity string
+		var isGenerated string
 
-		if err := rows.Scan(&postgresColumn.ColumnName, &postgresColumn.DataType, &maxLength, &columnDefault, &isNullable); err != nil {
+		if err := rows.Scan(&postgresColumn.ColumnName, &postgresColumn.DataType, &maxLength, &columnDefault, &isNullable, &isIdentity, &isGenerated); err != nil {
 			return nil, fmt.Errorf("scan columns: %w", err)
 		}
 
 		postgresColumn.IsNullable = isNullable == "YES"
 
+		// Extra follows the MySQL column, so that identity and generated
+		// columns are known to get a value without a default
+		if isIdentity == "YES" {
+			postgresColumn.Extra = "identity"
+		} else if isGenerated == "ALWAYS" {
+			postgresColumn.Extra = "generated"
+		}
+
 		if columnDefault.Valid {
 			value := stripOIDClass(columnDefault.String)
 			postgresColumn.ColumnDefault = &value
@@ -163,3 +265,135 @@ order by c.ordinal_position`
 	}
 	return primaryKeys, nil
 }
+
+// listIndexes returns the indexes of every table of the schema, by table.
+func listIndexes(db *dbtypes.DB) (map[string][]dbtypes.Index, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+
+	// only the first indnkeyatts columns are keys, the rest are INCLUDE
+	// columns. Partial indexes are left out, since they only serve queries
+	// that repeat their predicate. An expression key has attnum 0 and no
+	// column name.
+	query := `select t.relname, i.relname, ix.indisunique, coalesce(a.attname, '')
+from pg_index ix
+join pg_class t on t.oid = ix.indrelid
+join pg_class i on i.oid = ix.indexrelid
+join pg_namespace n on n.oid = t.relnamespace
+join lateral unnest(ix.indkey) with ordinality as k(attnum, ord) on true
+left join pg_attribute a on a.attrelid = t.oid and a.attnum = k.attnum and k.attnum > 0
+where n.nspname = current_schema() and not ix.indisprimary
+  and ix.indpred is null and k.ord <= ix.indnkeyatts
+order by t.relname, i.relname, k.ord`
+
+	rows, err := conn.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := map[string][]dbtypes.Index{}
+	expression := false
+	for rows.Next() {
+		var tableName, indexName, columnName string
+		var isUnique bool
+
+		if err := rows.Scan(&tableName, &indexName, &isUnique, &columnName); err != nil {
+			return nil, fmt.Errorf("scan indexes: %w", err)
+		}
+
+		tableIndexes := indexes[tableName]
+		if len(tableIndexes) == 0 || tableIndexes[len(tableIndexes)-1].Name != indexName {
+			tableIndexes = append(tableIndexes, dbtypes.Index{
+				Name:     indexName,
+				IsUnique: isUnique,
+			})
+			expression = false
+		}
+
+		// the index only serves the columns before its first expression,
+		// and isn't unique on them
+		last := &tableIndexes[len(tableIndexes)-1]
+		if columnName == "" {
+			expression = true
+			last.IsUnique = false
+		}
+		if !expression {
+			last.Columns = append(last.Columns, columnName)
+		}
+		indexes[tableName] = tableIndexes
+	}
+
+	for tableName, tableIndexes := range indexes {
+		indexes[tableName] = withColumns(tableIndexes)
+	}
+
+	return indexes, nil
+}
+
+// withColumns drops the indexes that start with an expression, which no
+// column can be looked up with.
+func withColumns(indexes []dbtypes.Index) []dbtypes.Index {
+	kept := []dbtypes.Index{}
+	for _, index := range indexes {
+		if len(index.Columns) > 0 {
+			kept = append(kept, index)
+		}
+	}
+	return kept
+}
+
+// listForeignKeys returns the foreign keys of every table of the schema, by
+// table.
+func listForeignKeys(db *dbtypes.DB) (map[string][]dbtypes.ForeignKey, error) {
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+
+	// conkey and confkey are the columns of both sides, in the same order
+	query := `select t.relname, c.conname, r.relname, a.attname, ra.attname
+from pg_constraint c
+join pg_class t on t.oid = c.conrelid
+join pg_class r on r.oid = c.confrelid
+join pg_namespace n on n.oid = t.relnamespace
+join lateral unnest(c.conkey, c.confkey) with ordinality as k(attnum, refattnum, ord) on true
+join pg_attribute a on a.attrelid = c.conrelid and a.attnum = k.attnum
+join pg_attribute ra on ra.attrelid = c.confrelid and ra.attnum = k.refattnum
+where c.contype = 'f' and n.nspname = current_schema()
+order by t.relname, c.conname, k.ord`
+
+	rows, err := conn.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	foreignKeys := map[string][]dbtypes.ForeignKey{}
+	for rows.Next() {
+		var tableName, constraintName, referencedTable, columnName, referencedColumn string
+
+		if err := rows.Scan(&tableName, &constraintName, &referencedTable, &columnName, &referencedColumn); err != nil {
+			return nil, fmt.Errorf("scan foreign keys: %w", err)
+		}
+
+		tableForeignKeys := foreignKeys[tableName]
+		if len(tableForeignKeys) == 0 || tableForeignKeys[len(tableForeignKeys)-1].Name != constraintName {
+			tableForeignKeys = append(tableForeignKeys, dbtypes.ForeignKey{
+				Name:            constraintName,
+				ReferencedTable: referencedTable,
+			})
+		}
+
+		last := &tableForeignKeys[len(tableForeignKeys)-1]
+		last.Columns = append(last.Columns, columnName)
+		last.ReferencedColumns = append(last.ReferencedColumns, referencedColumn)
+		foreignKeys[tableName] = tableForeignKeys
+	}
+
+	return foreignKeys, nil
+}
@@ -10,7 +10,12 @@ type PostgresTable struct {
 	TableName         string
 	Columns           []PostgresColumn
 	PrimaryKeys       []string
+	Indexes           []dbtypes.Index
+	ForeignKeys       []dbtypes.ForeignKey
 	EstimatedRowCount int64
+	// FillFactor is the percentage of each heap page filled by inserts, the
+	// rest is kept for updated rows
+	FillFactor int
 }
 
 func (t PostgresTable) GetName() string {
@@ -29,6 +34,14 @@ func (t PostgresTable) GetPrimaryKeys() []string {
 	return t.PrimaryKeys
 }
 
+func (t PostgresTable) GetIndexes() []dbtypes.Index {
+	return t.Indexes
+}
+
+func (t PostgresTable) GetForeignKeys() []dbtypes.ForeignKey {
+	return t.ForeignKeys
+}
+
 func (t PostgresTable) GetEstimatedRowCount() int64 {
 	return t.EstimatedRowCount
 }
@@ -0,0 +1,96 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/workload"
+)
+
+// WorkloadOrders are the measurements the statements of pg_stat_statements
+// can be ranked by.
+var WorkloadOrders = []string{
+	workload.OrderByTotalTime,
+	workload.OrderByMeanTime,
+	workload.OrderByCalls,
+	workload.OrderByRows,
+	workload.OrderByBlocksRead,
+}
+
+// TopStatements returns the statements of the current database from
+// pg_stat_statements with the highest total time, mean time, calls, rows or
+// shared blocks read.
+func TopStatements(db *dbtypes.DB, opts workload.Options) ([]workload.Statement, error) {
+	if err := workload.ValidateOrderBy(opts.OrderBy, WorkloadOrders); err != nil {
+		return nil, err
+	}
+
+	conn, err := connect(db.ConnectionURI)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+
+	installed := false
+	if err := conn.QueryRow(context.Background(), "select exists (select 1 from pg_extension where extname = 'pg_stat_statements')").Scan(&installed); err != nil {
+		return nil, fmt.Errorf("check for pg_stat_statements: %w", err)
+	}
+	if !installed {
+		return nil, fmt.Errorf("pg_stat_statements is not installed; add it to shared_preload_libraries and run CREATE EXTENSION pg_stat_statements")
+	}
+
+	// the timing columns were renamed in Postgres 13, when planning time was
+	// added
+	totalTime, meanTime := "total_exec_time", "mean_exec_time"
+	if major := majorVersion(db.ServerVersion); major > 0 && major < 13 {
+		totalTime, meanTime = "total_time", "mean_time"
+	}
+
+	orderBy := map[string]string{
+		workload.OrderByTotalTime:  totalTime,
+		workload.OrderByMeanTime:   meanTime,
+		workload.OrderByCalls:      "calls",
+		workload.OrderByRows:       "rows",
+		workload.OrderByBlocksRead: "shared_blks_read",
+	}[opts.OrderBy]
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = workload.DefaultLimit
+	}
+
+	query := fmt.Sprintf(`select queryid, query, calls, %s, %s, rows, shared_blks_read
+from pg_stat_statements
+where dbid = (select oid from pg_database where datname = current_database()) and queryid is not null
+order by %s desc
+limit $1`, totalTime, meanTime, orderBy)
+
+	rows, err := conn.Query(context.Background(), query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query pg_stat_statements: %w", err)
+	}
+	defer rows.Close()
+
+	statements := []workload.Statement{}
+	for rows.Next() {
+		var queryID int64
+		var totalMilliseconds, meanMilliseconds float64
+		statement := workload.Statement{}
+		if err := rows.Scan(&queryID, &statement.Query, &statement.Calls, &totalMilliseconds, &meanMilliseconds, &statement.Rows, &statement.SharedBlocksRead); err != nil {
+			return nil, fmt.Errorf("scan pg_stat_statements: %w", err)
+		}
+
+		statement.QueryID = strconv.FormatInt(queryID, 10)
+		statement.TotalTime = time.Duration(totalMilliseconds * float64(time.Millisecond))
+		statement.MeanTime = time.Duration(meanMilliseconds * float64(time.Millisecond))
+		statements = append(statements, statement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read pg_stat_statements: %w", err)
+	}
+
+	return statements, nil
+}
@@ -0,0 +1,160 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+)
+
+var uuidRegexp = regexp.MustCompile(`^(?i)\{?[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}\}?$`)
+
+// ScanComparisonTypesForIssues reports the columns a query compares with a
+// literal or a cast parameter of another type. When the value can't be
+// converted to the column's type, the database converts the column instead,
+// for every row, and an index on the column can't be used. The issues describe
+// what the engine does with the comparison.
+func ScanComparisonTypesForIssues(query string, tables []dbtypes.Table, engine string) ([]issuetypes.QueryIssue, error) {
+	stmt, err := Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("parse query: %w", err)
+	}
+
+	tableAliasLookup, tableNames, err := statementTables(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	casts := map[string]placeholder{}
+	for _, p := range placeholders(query) {
+		if p.cast != "" {
+			casts[p.key] = p
+		}
+	}
+
+	queryIssues := []issuetypes.QueryIssue{}
+	reported := map[string]bool{}
+	check := func(column *sqlparser.ColName, value sqlparser.Expr) {
+		tableName, err := resolveColumnTable(tableNames, column.Qualifier.Name.String(), column.Name.String(), tableAliasLookup, tables)
+		if err != nil {
+			return
+		}
+		col := FindColumn(tables, tableName, column.Name.String())
+		if col == nil {
+			return
+		}
+
+		// a column is reported once, not once for every value of an IN list
+		name := fmt.Sprintf("%s.%s", tableName, col.GetName())
+		queryIssue, ok := comparisonTypeIssue(name, col.GetDataType(), value, casts, engine)
+		if !ok || reported[name] {
+			return
+		}
+		reported[name] = true
+		queryIssues = append(queryIssues, queryIssue)
+	}
+
+	err = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.ComparisonExpr:
+			forEachComparedColumn(node, check)
+		case *sqlparser.RangeCond:
+			if column, ok := node.Left.(*sqlparser.ColName); ok {
+				check(column, node.From)
+				check(column, node.To)
+			}
+		}
+		return true, nil
+	}, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("walk: %w", err)
+	}
+
+	return queryIssues, nil
+}
+
+// comparisonTypeIssue returns the issue with comparing a column of a data type
+// with a value, if the value is of another type.
+func comparisonTypeIssue(column string, dataType string, value sqlparser.Expr, casts map[string]placeholder, engine string) (issuetypes.QueryIssue, bool) {
+	family := dataTypeFamily(dataType)
+
+	literal, ok := value.(*sqlparser.SQLVal)
+	if !ok {
+		return issuetypes.QueryIssue{}, false
+	}
+
+	switch literal.Type {
+	case sqlparser.IntVal, sqlparser.FloatVal:
+		if family != dataTypeFamilyString {
+			break
+		}
+		effect := "the types don't match"
+		switch engine {
+		case EngineMySQL:
+			effect = "MySQL converts the column to a number for every row, so its index can't be used"
+		case EnginePostgres:
+			effect = "Postgres has no operator to compare them and fails the query"
+		}
+		return issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityMedium,
+			IssueType:     issuetypes.QueryIssueTypeComparisonTypeMismatch,
+			Message: fmt.Sprintf("%s (%s) is compared with the number %s; %s. Quote the value",
+				column, dataType, literal.Val, effect),
+		}, true
+
+	case sqlparser.StrVal:
+		mismatch := ""
+		switch family {
+		case dataTypeFamilyNumeric:
+			if _, err := strconv.ParseFloat(string(literal.Val), 64); err != nil {
+				mismatch = "a number"
+			}
+		case dataTypeFamilyUUID:
+			if !uuidRegexp.Match(literal.Val) {
+				mismatch = "a uuid"
+			}
+		}
+		if mismatch == "" {
+			break
+		}
+		effect := "the comparison fails or never matches"
+		switch {
+		case engine == EngineMySQL && family == dataTypeFamilyNumeric:
+			effect = "MySQL converts it to its leading digits, or to 0, so the comparison matches the wrong rows"
+		case engine == EnginePostgres:
+			effect = "Postgres fails the query with an invalid input syntax error"
+		}
+		return issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityLow,
+			IssueType:     issuetypes.QueryIssueTypeComparisonTypeMismatch,
+			Message: fmt.Sprintf("%s (%s) is compared with '%s', which isn't %s; %s",
+				column, dataType, literal.Val, mismatch, effect),
+		}, true
+
+	case sqlparser.ValArg:
+		p, ok := casts[string(literal.Val[1:])]
+		if !ok {
+			break
+		}
+		castFamily := dataTypeFamily(p.cast)
+		if castFamily == family || castFamily == dataTypeFamilyOther || family == dataTypeFamilyOther {
+			break
+		}
+		// only Postgres has :: casts, but the parser reads them for either
+		effect := "comparing different types either fails or casts the column, which prevents using its index"
+		if engine == EnginePostgres {
+			effect = "Postgres fails the query when no implicit cast exists, and otherwise casts the column, which prevents using its index"
+		}
+		return issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityMedium,
+			IssueType:     issuetypes.QueryIssueTypeComparisonTypeMismatch,
+			Message: fmt.Sprintf("%s is cast to %s but compared with %s (%s); %s. Cast the parameter to %s",
+				p.token.Value, p.cast, column, dataType, effect, dataType),
+		}, true
+	}
+
+	return issuetypes.QueryIssue{}, false
+}
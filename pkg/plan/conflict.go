@@ -0,0 +1,123 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// OnConflict is the Postgres ON CONFLICT clause of an INSERT.
+type OnConflict struct {
+	// Columns is the conflict target, empty when the clause names a
+	// constraint or has no target
+	Columns    []string
+	Constraint string
+	// Action is "nothing" or "update"
+	Action string
+}
+
+// SplitOnConflict separates a Postgres ON CONFLICT clause from an INSERT,
+// since the parser only understands the MySQL ON DUPLICATE KEY UPDATE form.
+// The clause is nil when the query doesn't have one.
+func SplitOnConflict(query string) (*OnConflict, string, error) {
+	tokens := significantTokens(lexer.Tokenize(query))
+
+	start := -1
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.IsPunctuation("("):
+			depth++
+		case token.IsPunctuation(")"):
+			depth--
+		case depth == 0 && token.Is("on") && i+1 < len(tokens) && tokens[i+1].Is("conflict"):
+			start = i
+		}
+		if start >= 0 {
+			break
+		}
+	}
+	if start < 0 {
+		return nil, query, nil
+	}
+
+	onConflict := OnConflict{}
+
+	i := start + 2
+	if i < len(tokens) && tokens[i].IsPunctuation("(") {
+		closing := matchingParen(tokens, i)
+		if closing < 0 {
+			return nil, "", fmt.Errorf("unbalanced conflict target")
+		}
+		// only the plain columns of the target, not the expressions, collations
+		// or operator classes
+		targetDepth := 0
+		for j := i + 1; j < closing; j++ {
+			token := tokens[j]
+			switch {
+			case token.IsPunctuation("("):
+				targetDepth++
+			case token.IsPunctuation(")"):
+				targetDepth--
+			case targetDepth == 0 && (token.Type == lexer.Word || token.Type == lexer.QuotedIdentifier):
+				startsElement := j == i+1 || tokens[j-1].IsPunctuation(",")
+				if startsElement && !tokens[j+1].IsPunctuation("(") {
+					onConflict.Columns = append(onConflict.Columns, unquoteIdentifier(token.Value))
+				}
+			}
+		}
+		i = closing + 1
+	} else if i+2 < len(tokens) && tokens[i].Is("on") && tokens[i+1].Is("constraint") {
+		onConflict.Constraint = unquoteIdentifier(tokens[i+2].Value)
+		i += 3
+	}
+
+	// skip the predicate of a partial unique index
+	for i < len(tokens) && !tokens[i].Is("do") {
+		i++
+	}
+	if i+1 >= len(tokens) {
+		return nil, "", fmt.Errorf("expected DO NOTHING or DO UPDATE after ON CONFLICT")
+	}
+	onConflict.Action = strings.ToLower(tokens[i+1].Value)
+
+	// the clause runs to the end of the statement, or to a RETURNING clause
+	end := len(query)
+	depth = 0
+	for j := i + 2; j < len(tokens); j++ {
+		switch {
+		case tokens[j].IsPunctuation("("):
+			depth++
+		case tokens[j].IsPunctuation(")"):
+			depth--
+		case depth == 0 && (tokens[j].Is("returning") || tokens[j].IsPunctuation(";")):
+			end = tokens[j].Pos
+		}
+		if end < len(query) {
+			break
+		}
+	}
+
+	return &onConflict, strings.TrimSpace(query[:tokens[start].Pos]) + " " + query[end:], nil
+}
+
+// stripReturning removes a Postgres RETURNING clause from the end of an
+// INSERT, UPDATE or DELETE.
+func stripReturning(query string) string {
+	tokens := significantTokens(lexer.Tokenize(query))
+
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.IsPunctuation("("):
+			depth++
+		case token.IsPunctuation(")"):
+			depth--
+		case depth == 0 && token.Is("returning"):
+			return strings.TrimSpace(query[:token.Pos])
+		}
+	}
+
+	return query
+}
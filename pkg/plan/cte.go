@@ -0,0 +1,202 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// CommonTableExpression is one entry of a WITH clause.
+type CommonTableExpression struct {
+	Name      string
+	Columns   []string
+	Query     string
+	Recursive bool
+	// Materialized is "materialized", "not materialized" or empty when the
+	// query doesn't say
+	Materialized string
+}
+
+// SplitCommonTableExpressions separates the WITH clause from the statement it
+// applies to. The parser doesn't understand WITH, so the CTEs are returned as
+// separate queries along with the remaining statement.
+func SplitCommonTableExpressions(query string) ([]CommonTableExpression, string, error) {
+	tokens := significantTokens(lexer.Tokenize(query))
+	if len(tokens) == 0 || !tokens[0].Is("with") {
+		return nil, query, nil
+	}
+
+	ctes := []CommonTableExpression{}
+
+	i := 1
+	recursive := false
+	if i < len(tokens) && tokens[i].Is("recursive") {
+		recursive = true
+		i++
+	}
+
+	for {
+		if i >= len(tokens) || (tokens[i].Type != lexer.Word && tokens[i].Type != lexer.QuotedIdentifier) {
+			return nil, "", fmt.Errorf("expected common table expression name")
+		}
+
+		cte := CommonTableExpression{
+			Name:      unquoteIdentifier(tokens[i].Value),
+			Recursive: recursive,
+		}
+		i++
+
+		// optional column list
+		if i < len(tokens) && tokens[i].IsPunctuation("(") {
+			closing := matchingParen(tokens, i)
+			if closing < 0 {
+				return nil, "", fmt.Errorf("unbalanced column list in common table expression %q", cte.Name)
+			}
+			for _, token := range tokens[i+1 : closing] {
+				if token.Type == lexer.Word || token.Type == lexer.QuotedIdentifier {
+					cte.Columns = append(cte.Columns, unquoteIdentifier(token.Value))
+				}
+			}
+			i = closing + 1
+		}
+
+		if i >= len(tokens) || !tokens[i].Is("as") {
+			return nil, "", fmt.Errorf("expected AS after common table expression %q", cte.Name)
+		}
+		i++
+
+		if i+1 < len(tokens) && tokens[i].Is("not") && tokens[i+1].Is("materialized") {
+			cte.Materialized = "not materialized"
+			i += 2
+		} else if i < len(tokens) && tokens[i].Is("materialized") {
+			cte.Materialized = "materialized"
+			i++
+		}
+
+		if i >= len(tokens) || !tokens[i].IsPunctuation("(") {
+			return nil, "", fmt.Errorf("expected ( after common table expression %q", cte.Name)
+		}
+		closing := matchingParen(tokens, i)
+		if closing < 0 {
+			return nil, "", fmt.Errorf("unbalanced parentheses in common table expression %q", cte.Name)
+		}
+		cte.Query = strings.TrimSpace(query[tokens[i].End():tokens[closing].Pos])
+		ctes = append(ctes, cte)
+		i = closing + 1
+
+		if i < len(tokens) && tokens[i].IsPunctuation(",") {
+			i++
+			continue
+		}
+		break
+	}
+
+	if i >= len(tokens) {
+		return nil, "", fmt.Errorf("expected a statement after the WITH clause")
+	}
+
+	return ctes, query[tokens[i].Pos:], nil
+}
+
+// scanCommonTableExpressions plans the queries of a WITH clause, each in its
+// own scope, and returns their issues along with the tables with the CTEs
+// added, which behave like tables without indexes. A recursive CTE is in scope
+// in its own query, with its declared columns or the columns of its
+// non-recursive term.
+func scanCommonTableExpressions(ctes []CommonTableExpression, tables []dbtypes.Table, indexes map[string][]Index) ([]issuetypes.QueryIssue, []dbtypes.Table, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+	if len(ctes) == 0 {
+		return queryIssues, tables, nil
+	}
+
+	// the CTEs are added to a copy, so the caller's tables are never changed
+	tables = append([]dbtypes.Table{}, tables...)
+	for _, cte := range ctes {
+		cteStmt, err := parseStatement(cte.Query)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse common table expression %q: %w", cte.Name, err)
+		}
+
+		selectStmt, ok := cteStmt.(sqlparser.SelectStatement)
+		if !ok {
+			return nil, nil, fmt.Errorf("expected select statement in common table expression %q, got %T", cte.Name, cteStmt)
+		}
+
+		columns := cte.Columns
+		if len(columns) == 0 {
+			columns = selectOutputColumns(selectStmt, tables)
+		}
+		scanTables := tables
+		if cte.Recursive {
+			scanTables = append(scanTables, derivedTable{name: cte.Name, columns: columns})
+		}
+
+		issues, err := scanSelectStatement(selectStmt, nil, scanTables, indexes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scan common table expression %q: %w", cte.Name, err)
+		}
+		queryIssues = append(queryIssues, issues...)
+
+		tables = append(tables, derivedTable{name: cte.Name, columns: columns})
+	}
+
+	return queryIssues, tables, nil
+}
+
+// withCommonTableExpressions returns the statement that follows the WITH
+// clause of a query, the tables with the CTEs added, and the issues of the
+// CTEs' queries. A query without a WITH clause is returned as it is.
+func withCommonTableExpressions(query string, tables []dbtypes.Table) (string, []dbtypes.Table, []issuetypes.QueryIssue, error) {
+	ctes, body, err := SplitCommonTableExpressions(query)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("split common table expressions: %w", err)
+	}
+
+	queryIssues, tables, err := scanCommonTableExpressions(ctes, tables, indexesByTable(tables))
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return body, tables, queryIssues, nil
+}
+
+// significantTokens drops comments so callers can look at the tokens by
+// position.
+func significantTokens(tokens []lexer.Token) []lexer.Token {
+	significant := []lexer.Token{}
+	for _, token := range tokens {
+		if token.Type != lexer.Comment {
+			significant = append(significant, token)
+		}
+	}
+	return significant
+}
+
+// matchingParen returns the index of the token that closes the parenthesis at
+// tokens[open], or -1.
+func matchingParen(tokens []lexer.Token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].IsPunctuation("("):
+			depth++
+		case tokens[i].IsPunctuation(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func unquoteIdentifier(identifier string) string {
+	if len(identifier) >= 2 && (identifier[0] == '"' || identifier[0] == '`') {
+		return identifier[1 : len(identifier)-1]
+	}
+	return identifier
+}
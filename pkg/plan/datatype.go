@@ -0,0 +1,112 @@
+package plan
+
+import (
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+)
+
+const (
+	dataTypeFamilyNumeric  = "numeric"
+	dataTypeFamilyString   = "string"
+	dataTypeFamilyTemporal = "temporal"
+	dataTypeFamilyBoolean  = "boolean"
+	dataTypeFamilyBinary   = "binary"
+	dataTypeFamilyUUID     = "uuid"
+	dataTypeFamilyJSON     = "json"
+	dataTypeFamilyOther    = "other"
+)
+
+var dataTypeFamilies = map[string]string{
+	"tinyint":          dataTypeFamilyNumeric,
+	"smallint":         dataTypeFamilyNumeric,
+	"mediumint":        dataTypeFamilyNumeric,
+	"int":              dataTypeFamilyNumeric,
+	"integer":          dataTypeFamilyNumeric,
+	"bigint":           dataTypeFamilyNumeric,
+	"serial":           dataTypeFamilyNumeric,
+	"bigserial":        dataTypeFamilyNumeric,
+	"smallserial":      dataTypeFamilyNumeric,
+	"decimal":          dataTypeFamilyNumeric,
+	"numeric":          dataTypeFamilyNumeric,
+	"real":             dataTypeFamilyNumeric,
+	"float":            dataTypeFamilyNumeric,
+	"double":           dataTypeFamilyNumeric,
+	"double precision": dataTypeFamilyNumeric,
+	"bit":              dataTypeFamilyNumeric,
+	"year":             dataTypeFamilyNumeric,
+
+	"char":              dataTypeFamilyString,
+	"varchar":           dataTypeFamilyString,
+	"character":         dataTypeFamilyString,
+	"character varying": dataTypeFamilyString,
+	"text":              dataTypeFamilyString,
+	"tinytext":          dataTypeFamilyString,
+	"mediumtext":        dataTypeFamilyString,
+	"longtext":          dataTypeFamilyString,
+	"enum":              dataTypeFamilyString,
+	"set":               dataTypeFamilyString,
+	"citext":            dataTypeFamilyString,
+	"name":              dataTypeFamilyString,
+
+	"date":                        dataTypeFamilyTemporal,
+	"datetime":                    dataTypeFamilyTemporal,
+	"timestamp":                   dataTypeFamilyTemporal,
+	"time":                        dataTypeFamilyTemporal,
+	"timestamp without time zone": dataTypeFamilyTemporal,
+	"timestamp with time zone":    dataTypeFamilyTemporal,
+	"time without time zone":      dataTypeFamilyTemporal,
+	"time with time zone":         dataTypeFamilyTemporal,
+	"interval":                    dataTypeFamilyTemporal,
+
+	"boolean": dataTypeFamilyBoolean,
+	"bool":    dataTypeFamilyBoolean,
+
+	"binary":     dataTypeFamilyBinary,
+	"varbinary":  dataTypeFamilyBinary,
+	"blob":       dataTypeFamilyBinary,
+	"tinyblob":   dataTypeFamilyBinary,
+	"mediumblob": dataTypeFamilyBinary,
+	"longblob":   dataTypeFamilyBinary,
+	"bytea":      dataTypeFamilyBinary,
+
+	"uuid": dataTypeFamilyUUID,
+
+	"json":  dataTypeFamilyJSON,
+	"jsonb": dataTypeFamilyJSON,
+}
+
+// dataTypeFamily groups a column data type (as reported by GetDataType) into
+// a family of types that can be compared to each other without a cast.
+func dataTypeFamily(dataType string) string {
+	normalized := strings.ToLower(dataType)
+	if i := strings.Index(normalized, "("); i >= 0 {
+		normalized = normalized[:i]
+	}
+	normalized = strings.TrimSuffix(strings.TrimSpace(normalized), " unsigned")
+
+	if family, ok := dataTypeFamilies[normalized]; ok {
+		return family
+	}
+
+	return dataTypeFamilyOther
+}
+
+// FindColumn returns the column in the named table, or nil if either the table
+// or the column isn't in the schema. Column names are compared without case,
+// as both engines do.
+func FindColumn(tables []dbtypes.Table, tableName string, columnName string) dbtypes.Column {
+	for _, t := range tables {
+		if t.GetName() != tableName {
+			continue
+		}
+
+		for _, col := range t.GetColumns() {
+			if strings.EqualFold(col.GetName(), columnName) {
+				return col
+			}
+		}
+	}
+
+	return nil
+}
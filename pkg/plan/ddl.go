@@ -0,0 +1,287 @@
+package plan
+
+import (
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// statementKeywords are the words other statements start with, which can't be
+// the unquoted name of a table.
+var statementKeywords = map[string]bool{
+	"select": true, "insert": true, "update": true, "delete": true, "with": true,
+	"values": true, "explain": true, "analyze": true, "analyse": true,
+}
+
+// DDLStatement is a schema change. The parser loses most of the detail of DDL,
+// so these are read from the tokens instead.
+type DDLStatement struct {
+	// Kind is the statement, such as "create index" or "alter table"
+	Kind  string
+	Table string
+	Index string
+
+	Unique       bool
+	Concurrently bool
+	// Actions are the subcommands of an ALTER TABLE, such as "add column x int"
+	Actions []string
+	// LockMode is the mode of a LOCK TABLE statement, empty when the
+	// statement uses the default
+	LockMode string
+}
+
+// ParseDDL reads a DDL statement. The second return value is false if the
+// query isn't DDL.
+func ParseDDL(query string) (*DDLStatement, bool) {
+	tokens := significantTokens(lexer.Tokenize(query))
+	if len(tokens) > 0 && tokens[len(tokens)-1].IsPunctuation(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) < 2 {
+		return nil, false
+	}
+
+	r := ddlReader{query: query, tokens: tokens}
+	ddl := DDLStatement{}
+
+	switch {
+	case r.accept("create"):
+		ddl.Unique = r.accept("unique")
+		r.accept("fulltext")
+		r.accept("spatial")
+
+		switch {
+		case r.accept("index"):
+			ddl.Kind = "create index"
+			ddl.Concurrently = r.accept("concurrently")
+			r.acceptAll("if", "not", "exists")
+			if !r.peek("on") {
+				ddl.Index = r.identifier()
+			}
+			if r.accept("on") {
+				r.accept("only")
+				ddl.Table = r.identifier()
+			}
+		case r.accept("table"):
+			ddl.Kind = "create table"
+			r.acceptAll("if", "not", "exists")
+			ddl.Table = r.identifier()
+		case r.accept("trigger"):
+			ddl.Kind = "create trigger"
+			r.skipTo("on")
+			ddl.Table = r.identifier()
+		default:
+			return nil, false
+		}
+
+	case r.accept("alter"):
+		if !r.accept("table") {
+			return nil, false
+		}
+		ddl.Kind = "alter table"
+		r.acceptAll("if", "exists")
+		r.accept("only")
+		ddl.Table = r.identifier()
+		ddl.Actions = r.list()
+
+	case r.accept("drop"):
+		switch {
+		case r.accept("index"):
+			ddl.Kind = "drop index"
+			ddl.Concurrently = r.accept("concurrently")
+			r.acceptAll("if", "exists")
+			ddl.Index = r.identifier()
+			if r.accept("on") {
+				ddl.Table = r.identifier()
+			}
+		case r.accept("table"):
+			ddl.Kind = "drop table"
+			r.acceptAll("if", "exists")
+			ddl.Table = r.identifier()
+		default:
+			return nil, false
+		}
+
+	case r.accept("truncate"):
+		ddl.Kind = "truncate"
+		r.accept("table")
+		r.accept("only")
+		ddl.Table = r.identifier()
+
+	case r.accept("rename"):
+		if !r.accept("table") {
+			return nil, false
+		}
+		ddl.Kind = "rename table"
+		ddl.Table = r.identifier()
+
+	case r.accept("vacuum"):
+		ddl.Kind = "vacuum"
+		if r.accept("full") {
+			ddl.Kind = "vacuum full"
+		}
+		r.skipParens()
+		ddl.Table = r.identifier()
+
+	case r.accept("analyze") || r.accept("analyse"):
+		// Postgres: ANALYZE [(options)] [VERBOSE] table, MySQL: ANALYZE
+		// [NO_WRITE_TO_BINLOG | LOCAL] TABLE table
+		ddl.Kind = "analyze"
+		r.skipParens()
+		r.accept("verbose")
+		if !r.accept("no_write_to_binlog") {
+			r.accept("local")
+		}
+		r.accept("table")
+		if !r.done() && r.tokens[r.i].Type == lexer.Word && statementKeywords[strings.ToLower(r.tokens[r.i].Value)] {
+			return nil, false
+		}
+		ddl.Table = r.identifier()
+		if ddl.Table == "" {
+			return nil, false
+		}
+
+	case r.accept("reindex"):
+		ddl.Kind = "reindex"
+		r.skipParens()
+		isIndex := r.accept("index")
+		if !isIndex && !r.accept("table") {
+			return nil, false
+		}
+		ddl.Concurrently = r.accept("concurrently")
+		if isIndex {
+			ddl.Index = r.identifier()
+		} else {
+			ddl.Table = r.identifier()
+		}
+
+	case r.accept("cluster"):
+		ddl.Kind = "cluster"
+		ddl.Table = r.identifier()
+
+	case r.accept("refresh"):
+		if !r.acceptAll("materialized", "view") {
+			return nil, false
+		}
+		ddl.Kind = "refresh materialized view"
+		ddl.Concurrently = r.accept("concurrently")
+		ddl.Table = r.identifier()
+
+	case r.accept("lock"):
+		ddl.Kind = "lock table"
+		if !r.accept("table") {
+			r.accept("tables")
+		}
+		r.accept("only")
+		ddl.Table = r.identifier()
+		r.accept("in")
+
+		// Postgres: IN <mode> MODE, MySQL: READ or WRITE
+		words := []string{}
+		for !r.done() && !r.peek("mode") && !r.tokens[r.i].IsPunctuation(",") {
+			words = append(words, strings.ToUpper(r.next().Value))
+		}
+		ddl.LockMode = strings.Join(words, " ")
+
+	default:
+		return nil, false
+	}
+
+	return &ddl, true
+}
+
+// ddlReader steps through the tokens of a DDL statement.
+type ddlReader struct {
+	query  string
+	tokens []lexer.Token
+	i      int
+}
+
+func (r *ddlReader) done() bool {
+	return r.i >= len(r.tokens)
+}
+
+func (r *ddlReader) next() lexer.Token {
+	token := r.tokens[r.i]
+	r.i++
+	return token
+}
+
+func (r *ddlReader) peek(keyword string) bool {
+	return !r.done() && r.tokens[r.i].Is(keyword)
+}
+
+func (r *ddlReader) accept(keyword string) bool {
+	if r.peek(keyword) {
+		r.i++
+		return true
+	}
+	return false
+}
+
+// acceptAll accepts a sequence of keywords, or none of them.
+func (r *ddlReader) acceptAll(keywords ...string) bool {
+	for offset, keyword := range keywords {
+		if r.i+offset >= len(r.tokens) || !r.tokens[r.i+offset].Is(keyword) {
+			return false
+		}
+	}
+	r.i += len(keywords)
+	return true
+}
+
+func (r *ddlReader) skipTo(keyword string) {
+	for !r.done() && !r.accept(keyword) {
+		r.i++
+	}
+}
+
+func (r *ddlReader) skipParens() {
+	if !r.done() && r.tokens[r.i].IsPunctuation("(") {
+		if closing := matchingParen(r.tokens, r.i); closing >= 0 {
+			r.i = closing + 1
+		}
+	}
+}
+
+// identifier reads a possibly schema qualified name, and returns it without
+// the schema.
+func (r *ddlReader) identifier() string {
+	name := ""
+	for !r.done() && (r.tokens[r.i].Type == lexer.Word || r.tokens[r.i].Type == lexer.QuotedIdentifier) {
+		name = unquoteIdentifier(r.next().Value)
+		if r.done() || !r.tokens[r.i].IsPunctuation(".") {
+			break
+		}
+		r.i++
+	}
+	return name
+}
+
+// list returns the rest of the statement split on the commas outside of
+// parentheses.
+func (r *ddlReader) list() []string {
+	items := []string{}
+	if r.done() {
+		return items
+	}
+
+	start := r.tokens[r.i].Pos
+	depth := 0
+	for ; !r.done(); r.i++ {
+		token := r.tokens[r.i]
+		switch {
+		case token.IsPunctuation("("):
+			depth++
+		case token.IsPunctuation(")"):
+			depth--
+		case depth == 0 && token.IsPunctuation(","):
+			items = append(items, strings.TrimSpace(r.query[start:token.Pos]))
+			start = token.End()
+		}
+	}
+
+	last := r.tokens[len(r.tokens)-1]
+	items = append(items, strings.TrimSpace(r.query[start:last.End()]))
+	return items
+}
@@ -0,0 +1,78 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDDL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  *DDLStatement
+	}{
+		{
+			name:  "create index concurrently",
+			query: "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS users_email ON public.users (email);",
+			want:  &DDLStatement{Kind: "create index", Table: "users", Index: "users_email", Unique: true, Concurrently: true},
+		},
+		{
+			name:  "alter table with several actions",
+			query: "alter table orders add column total numeric(10, 2) not null default 0, add constraint orders_user_fk foreign key (user_id) references users (id)",
+			want: &DDLStatement{Kind: "alter table", Table: "orders", Actions: []string{
+				"add column total numeric(10, 2) not null default 0",
+				"add constraint orders_user_fk foreign key (user_id) references users (id)",
+			}},
+		},
+		{
+			name:  "vacuum full with options",
+			query: "vacuum full (verbose) orders",
+			want:  &DDLStatement{Kind: "vacuum full", Table: "orders"},
+		},
+		{
+			name:  "postgres lock table",
+			query: "lock table orders in share row exclusive mode",
+			want:  &DDLStatement{Kind: "lock table", Table: "orders", LockMode: "SHARE ROW EXCLUSIVE"},
+		},
+		{
+			name:  "mysql lock tables",
+			query: "lock tables orders write",
+			want:  &DDLStatement{Kind: "lock table", Table: "orders", LockMode: "WRITE"},
+		},
+		{
+			name:  "postgres analyze",
+			query: "analyze (verbose) public.orders",
+			want:  &DDLStatement{Kind: "analyze", Table: "orders"},
+		},
+		{
+			name:  "mysql analyze table",
+			query: "ANALYZE NO_WRITE_TO_BINLOG TABLE users",
+			want:  &DDLStatement{Kind: "analyze", Table: "users"},
+		},
+		{
+			name:  "analyze of a statement",
+			query: "analyze delete from users",
+		},
+		{
+			name:  "analyze without a table",
+			query: "analyze (verbose)",
+		},
+		{
+			name:  "not ddl",
+			query: "select * from users",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseDDL(tt.query)
+			if tt.want == nil {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
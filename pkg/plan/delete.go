@@ -8,17 +8,41 @@ import (
 	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
 )
 
-func ScanDeleteStatementForIssues(query string, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error) {
-	_, err := parseDeleteStatement(query)
+// ScanDeleteStatementForIssues returns the issues with a DELETE, described for
+// the engine.
+func ScanDeleteStatementForIssues(query string, tables []dbtypes.Table, engine string) ([]issuetypes.QueryIssue, error) {
+	query, tables, queryIssues, err := withCommonTableExpressions(query, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteStatement, err := parseDeleteStatement(query, tables)
 	if err != nil {
 		return nil, fmt.Errorf("parse delete statement: %w", err)
 	}
 
-	return nil, nil
+	issues, err := scanWriteFilterForIssues("DELETE", deleteStatement.Tables, deleteStatement.Filter, tables, indexesByTable(tables), engine)
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	issues, err = scanJoinGraphForIssues(deleteStatement.joinGraph, indexesByTable(tables), tables)
+	if err != nil {
+		return nil, fmt.Errorf("scan join graph for issues: %w", err)
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	return queryIssues, nil
 }
 
-func parseDeleteStatement(query string) (*DeleteStatement, error) {
-	stmt, err := sqlparser.Parse(query)
+func parseDeleteStatement(query string, tables []dbtypes.Table) (*DeleteStatement, error) {
+	query, tables, _, err := withCommonTableExpressions(query, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := parseStatement(query)
 	if err != nil {
 		return nil, fmt.Errorf("parse delete statement: %w", err)
 	}
@@ -33,23 +57,54 @@ func parseDeleteStatement(query string) (*DeleteStatement, error) {
 	}
 
 	// Extract table names
-	result.Tables = extractDeleteTableNames(deleteStmt)
+	tableAliasLookup, tableNames, err := extractTableExprs(deleteStmt.TableExprs)
+	if err != nil {
+		return nil, fmt.Errorf("extract tables: %w", err)
+	}
+	result.Sources = tableNames
+
+	result.Tables, err = extractDeleteTableNames(deleteStmt, tableAliasLookup, tableNames)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Filter, err = parseWriteFilter(deleteStmt.Where, deleteStmt.OrderBy, deleteStmt.Limit, newScope(nil, tableAliasLookup, tableNames), tables)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tableNames) > 1 {
+		result.Filter.Joined = true
+		result.joinGraph = buildJoinGraph(deleteStmt.TableExprs, whereExpr(deleteStmt.Where), tables)
+	}
 
 	return &result, nil
 }
 
 type DeleteStatement struct {
 	Tables []string // List of tables being deleted from
+	// Sources are all the tables in the statement, including the ones that are
+	// only joined to pick the rows
+	Sources []string
+	Filter  *writeFilter
+
+	joinGraph *joinGraph
 }
 
-func extractDeleteTableNames(deleteStmt *sqlparser.Delete) []string {
-	var tables []string
-	for _, tableExpr := range deleteStmt.TableExprs {
-		switch expr := tableExpr.(type) {
-		case *sqlparser.AliasedTableExpr:
-			tableName := sqlparser.String(expr.Expr)
-			tables = append(tables, tableName)
+// extractDeleteTableNames returns the tables rows are deleted from. A
+// multi-table DELETE names them, by table name or alias, before FROM.
+func extractDeleteTableNames(deleteStmt *sqlparser.Delete, tableAliasLookup map[string]string, tableNames []string) ([]string, error) {
+	if len(deleteStmt.Targets) == 0 {
+		return tableNames, nil
+	}
+
+	tables := []string{}
+	for _, target := range deleteStmt.Targets {
+		tableName, ok := tableAliasLookup[target.Name.String()]
+		if !ok {
+			return nil, fmt.Errorf("delete target %q not found", target.Name.String())
 		}
+		tables = appendIfMissing(tables, tableName)
 	}
-	return tables
+	return tables, nil
 }
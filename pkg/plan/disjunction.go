@@ -0,0 +1,120 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+)
+
+// scanDisjunctionsForIssues looks at each OR in the top level of a predicate.
+// An OR across different columns can only use indexes when every branch has
+// one (index merge in MySQL, a bitmap OR in Postgres); a single unindexed
+// branch turns the whole predicate into a full scan.
+func scanDisjunctionsForIssues(whereExpr sqlparser.Expr, s *scope, tables []dbtypes.Table, indexes map[string][]Index) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	for _, conjunct := range splitAnd(whereExpr) {
+		branches := splitOr(conjunct)
+		if len(branches) < 2 {
+			continue
+		}
+
+		columnsByBranch := []map[string][]string{}
+		for _, branch := range branches {
+			columns := map[string][]string{}
+			if err := walkPredicate(branch, s, tables, columns); err != nil {
+				// a branch we can't attribute can't be evaluated either
+				columns = nil
+			}
+			columnsByBranch = append(columnsByBranch, columns)
+		}
+
+		// an OR of the same column is really an IN list and is covered by the
+		// where clause index check
+		if sameColumns(columnsByBranch) {
+			continue
+		}
+
+		unindexedBranches := []string{}
+		suggestions := []string{}
+		for i, columns := range columnsByBranch {
+			if len(columns) == 0 {
+				continue
+			}
+
+			indexed := false
+			missing := []string{}
+			for table, tableColumns := range columns {
+				if _, known := indexes[table]; !known {
+					indexed = true
+					continue
+				}
+
+				unindexed := unindexedColumns(indexes[table], tableColumns)
+				if len(unindexed) < len(tableColumns) {
+					indexed = true
+					continue
+				}
+				missing = append(missing, fmt.Sprintf("%s(%s)", table, strings.Join(tableColumns, ", ")))
+			}
+
+			if !indexed {
+				sort.Strings(missing)
+				unindexedBranches = append(unindexedBranches, sqlparser.String(branches[i]))
+				suggestions = append(suggestions, missing...)
+			}
+		}
+
+		if len(unindexedBranches) == 0 {
+			continue
+		}
+
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityMedium,
+			IssueType:     issuetypes.QueryIssueTypeOrPredicateMissingIndex,
+			Message: fmt.Sprintf("OR predicate %q can only use indexes if every branch is indexed, but no index serves %s; add an index on %s, or rewrite the query as a UNION ALL with one SELECT per branch",
+				sqlparser.String(conjunct), strings.Join(unindexedBranches, " and "), strings.Join(suggestions, " and ")),
+		})
+	}
+
+	return queryIssues, nil
+}
+
+// splitOr flattens a tree of OR expressions into its branches.
+func splitOr(expr sqlparser.Expr) []sqlparser.Expr {
+	switch expr := expr.(type) {
+	case *sqlparser.OrExpr:
+		return append(splitOr(expr.Left), splitOr(expr.Right)...)
+	case *sqlparser.ParenExpr:
+		if _, ok := expr.Expr.(*sqlparser.OrExpr); ok {
+			return splitOr(expr.Expr)
+		}
+	}
+
+	return []sqlparser.Expr{expr}
+}
+
+func sameColumns(columnsByBranch []map[string][]string) bool {
+	first := ""
+	for i, columns := range columnsByBranch {
+		keys := []string{}
+		for table, tableColumns := range columns {
+			sorted := append([]string{}, tableColumns...)
+			sort.Strings(sorted)
+			keys = append(keys, table+"("+strings.Join(sorted, ",")+")")
+		}
+		sort.Strings(keys)
+
+		key := strings.Join(keys, ";")
+		if i == 0 {
+			first = key
+		} else if key != first {
+			return false
+		}
+	}
+	return true
+}
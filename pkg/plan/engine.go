@@ -0,0 +1,8 @@
+package plan
+
+// The engines a statement is planned for. The plan is the same for both, but
+// some issues describe what the engine does differently.
+const (
+	EngineMySQL    = "mysql"
+	EnginePostgres = "postgres"
+)
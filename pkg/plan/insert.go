@@ -2,23 +2,74 @@ package plan
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/blastrain/vitess-sqlparser/sqlparser"
 	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
 	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
 )
 
+const (
+	// maxInsertBatchRows is the number of rows in a single multi-row VALUES
+	// list above which the statement is reported as too large
+	maxInsertBatchRows = 1000
+)
+
 func ScanInsertStatementForIssues(query string, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error) {
-	_, err := parseInsertStatement(query)
+	query, tables, queryIssues, err := withCommonTableExpressions(query, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	insertStatement, err := parseInsertStatement(query)
 	if err != nil {
 		return nil, fmt.Errorf("parse insert statement: %w", err)
 	}
 
-	return nil, nil
+	table := findTable(tables, insertStatement.Table)
+	if table == nil {
+		return queryIssues, nil
+	}
+
+	issues, err := scanInsertStatementForColumns(insertStatement, table, tables)
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	issues, err = scanInsertStatementForBatchSize(insertStatement)
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	issues, err = scanInsertStatementForSource(insertStatement, tables, indexesByTable(tables))
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	issues, err = scanInsertStatementForConflicts(insertStatement, table, tables, indexesByTable(tables))
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	return queryIssues, nil
 }
 
 func parseInsertStatement(query string) (*InsertStatement, error) {
-	stmt, err := sqlparser.Parse(query)
+	_, query, err := SplitCommonTableExpressions(query)
+	if err != nil {
+		return nil, fmt.Errorf("split common table expressions: %w", err)
+	}
+
+	onConflict, body, err := SplitOnConflict(query)
+	if err != nil {
+		return nil, fmt.Errorf("split on conflict: %w", err)
+	}
+
+	stmt, err := parseStatement(body)
 	if err != nil {
 		return nil, fmt.Errorf("parse insert statement: %w", err)
 	}
@@ -29,9 +80,10 @@ func parseInsertStatement(query string) (*InsertStatement, error) {
 	}
 
 	result := InsertStatement{
-		Table:   "",
-		Columns: []string{},
-		Values:  [][]string{},
+		Table:      "",
+		Columns:    []string{},
+		Values:     [][]string{},
+		OnConflict: onConflict,
 	}
 
 	// Extract table name
@@ -44,14 +96,21 @@ func parseInsertStatement(query string) (*InsertStatement, error) {
 
 	// Extract values - assuming simple cases for now
 	// For handling more complex cases like subqueries, adjust accordingly
-	if values, ok := insertStmt.Rows.(sqlparser.Values); ok {
-		for _, valTuple := range values {
+	switch rows := insertStmt.Rows.(type) {
+	case sqlparser.Values:
+		for _, valTuple := range rows {
 			var valueSet []string
 			for _, val := range valTuple {
 				valueSet = append(valueSet, sqlparser.String(val))
 			}
 			result.Values = append(result.Values, valueSet)
 		}
+	case sqlparser.SelectStatement:
+		result.Select = rows
+	}
+
+	for _, updateExpr := range insertStmt.OnDup {
+		result.OnDuplicateKeyUpdate = append(result.OnDuplicateKeyUpdate, updateExpr.Name.Name.String())
 	}
 
 	return &result, nil
@@ -61,4 +120,236 @@ type InsertStatement struct {
 	Table   string
 	Columns []string
 	Values  [][]string // Each slice within this slice represents a row of values
+
+	// Select is the source of an INSERT ... SELECT
+	Select sqlparser.SelectStatement
+
+	// OnDuplicateKeyUpdate is the list of columns set by a MySQL upsert
+	OnDuplicateKeyUpdate []string
+	// OnConflict is the Postgres upsert clause
+	OnConflict *OnConflict
+}
+
+// insertedColumns returns the columns that the statement provides a value
+// for. Without a column list, the values fill the columns in table order.
+func insertedColumns(insertStatement *InsertStatement, table dbtypes.Table, tables []dbtypes.Table) []string {
+	if len(insertStatement.Columns) > 0 {
+		return insertStatement.Columns
+	}
+
+	width := 0
+	if len(insertStatement.Values) > 0 {
+		width = len(insertStatement.Values[0])
+	} else if insertStatement.Select != nil {
+		width = len(selectOutputColumns(insertStatement.Select, tables))
+	}
+
+	columns := []string{}
+	for i, column := range table.GetColumns() {
+		if width > 0 && i >= width {
+			break
+		}
+		columns = append(columns, column.GetName())
+	}
+	return columns
+}
+
+// hasImplicitValue returns true if the database fills in the column when an
+// INSERT omits it, without a declared default.
+func hasImplicitValue(column dbtypes.Column) bool {
+	extra := strings.ToLower(column.GetExtra())
+	return strings.Contains(extra, "auto_increment") || strings.Contains(extra, "identity") || strings.Contains(extra, "generated")
+}
+
+func scanInsertStatementForColumns(insertStatement *InsertStatement, table dbtypes.Table, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	if len(insertStatement.Columns) == 0 {
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityMedium,
+			IssueType:     issuetypes.QueryIssueTypeInsertMissingColumnList,
+			Message:       fmt.Sprintf("INSERT INTO %s has no column list, so it depends on the column order of the table and breaks when a column is added", insertStatement.Table),
+		})
+	}
+
+	inserted := insertedColumns(insertStatement, table, tables)
+
+	missing := []string{}
+	for _, column := range table.GetColumns() {
+		if contains(inserted, column.GetName()) {
+			continue
+		}
+		if column.GetIsNullable() || column.GetColumnDefault() != nil || hasImplicitValue(column) {
+			continue
+		}
+		missing = append(missing, column.GetName())
+	}
+
+	if len(missing) > 0 {
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityHigh,
+			IssueType:     issuetypes.QueryIssueTypeMissingNotNullColumn,
+			Message:       fmt.Sprintf("INSERT INTO %s omits NOT NULL columns without a default: %s", insertStatement.Table, strings.Join(missing, ", ")),
+		})
+	}
+
+	return queryIssues, nil
+}
+
+func scanInsertStatementForBatchSize(insertStatement *InsertStatement) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	if len(insertStatement.Values) > maxInsertBatchRows {
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityMedium,
+			IssueType:     issuetypes.QueryIssueTypeInsertBatchTooLarge,
+			Message: fmt.Sprintf("INSERT INTO %s has %d rows in one VALUES list; batches above %d rows hold locks longer, grow the replication lag and can exceed max_allowed_packet",
+				insertStatement.Table, len(insertStatement.Values), maxInsertBatchRows),
+		})
+	}
+
+	return queryIssues, nil
+}
+
+// scanInsertStatementForSource reports an INSERT ... SELECT that reads a large
+// table without an index. The source rows are read (and under InnoDB, share
+// locked) in the same statement as the write.
+func scanInsertStatementForSource(insertStatement *InsertStatement, tables []dbtypes.Table, indexes map[string][]Index) ([]issuetypes.QueryIssue, error) {
+	if insertStatement.Select == nil {
+		return []issuetypes.QueryIssue{}, nil
+	}
+
+	queryIssues, err := scanSelectStatement(insertStatement.Select, nil, tables, indexes)
+	if err != nil {
+		return nil, fmt.Errorf("scan select statement: %w", err)
+	}
+
+	selectStmt, ok := insertStatement.Select.(*sqlparser.Select)
+	if !ok {
+		return dedupeIssues(queryIssues), nil
+	}
+
+	tableAliasLookup, tableNames, err := extractTableExprs(selectStmt.From)
+	if err != nil {
+		return nil, fmt.Errorf("extract tables: %w", err)
+	}
+	if len(tableNames) == 0 {
+		return dedupeIssues(queryIssues), nil
+	}
+
+	filter, err := parseWriteFilter(selectStmt.Where, selectStmt.OrderBy, selectStmt.Limit, newScope(nil, tableAliasLookup, tableNames), tables)
+	if err != nil {
+		return nil, err
+	}
+
+	// the first table drives the scan, the others are reached through the
+	// join predicates which are checked with the select
+	source := findTable(tables, tableNames[0])
+	if source == nil {
+		return dedupeIssues(queryIssues), nil
+	}
+
+	estimate, fullScan := estimateAffectedRows(source, filter, indexes[source.GetName()])
+	if fullScan && source.GetEstimatedRowCount() >= largeTableRowCount {
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityHigh,
+			IssueType:     issuetypes.QueryIssueTypeInsertSelectFullScan,
+			Message: fmt.Sprintf("INSERT INTO %s ... SELECT reads every row of %s (~%d rows) to insert ~%d; the whole read happens inside the write, and under InnoDB every row read is share locked",
+				insertStatement.Table, source.GetName(), source.GetEstimatedRowCount(), estimate),
+		})
+	}
+
+	return dedupeIssues(queryIssues), nil
+}
+
+// uniqueKeys returns the primary key and the unique indexes of a table.
+func uniqueKeys(indexes []Index) []Index {
+	keys := []Index{}
+	for _, index := range indexes {
+		if index.IsUnique && len(index.Columns) > 0 {
+			keys = append(keys, index)
+		}
+	}
+	return keys
+}
+
+func indexDescription(index Index) string {
+	if index.IsPrimaryKey {
+		return fmt.Sprintf("the primary key (%s)", strings.Join(index.Columns, ", "))
+	}
+	return fmt.Sprintf("%s (%s)", index.Name, strings.Join(index.Columns, ", "))
+}
+
+func scanInsertStatementForConflicts(insertStatement *InsertStatement, table dbtypes.Table, tables []dbtypes.Table, indexesByTable map[string][]Index) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	keys := uniqueKeys(indexesByTable[table.GetName()])
+	inserted := insertedColumns(insertStatement, table, tables)
+
+	// MySQL checks every unique key, so the upsert only fires for keys whose
+	// columns are all inserted, and picks an arbitrary row when several match
+	if len(insertStatement.OnDuplicateKeyUpdate) > 0 {
+		matching := []string{}
+		for _, key := range keys {
+			if containsAll(inserted, key.Columns) {
+				matching = append(matching, indexDescription(key))
+			}
+		}
+
+		switch {
+		case len(matching) == 0:
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityMedium,
+				IssueType:     issuetypes.QueryIssueTypeUpsertTargetMissingUniqueIndex,
+				Message:       fmt.Sprintf("ON DUPLICATE KEY UPDATE never fires: the inserted columns of %s don't cover any unique key", insertStatement.Table),
+			})
+		case len(matching) > 1:
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityLow,
+				IssueType:     issuetypes.QueryIssueTypeAmbiguousUpsert,
+				Message: fmt.Sprintf("ON DUPLICATE KEY UPDATE on %s can match %s; when they match different rows only one is updated, and the statement is unsafe for statement based replication",
+					insertStatement.Table, strings.Join(matching, " or ")),
+			})
+		}
+	}
+
+	// Postgres requires a unique index on exactly the conflict target
+	onConflict := insertStatement.OnConflict
+	if onConflict != nil && len(onConflict.Columns) > 0 {
+		matched := false
+		for _, key := range keys {
+			if len(key.Columns) == len(onConflict.Columns) && containsAll(onConflict.Columns, key.Columns) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityHigh,
+				IssueType:     issuetypes.QueryIssueTypeUpsertTargetMissingUniqueIndex,
+				Message:       fmt.Sprintf("ON CONFLICT (%s) doesn't match a unique index on %s; the INSERT fails", strings.Join(onConflict.Columns, ", "), insertStatement.Table),
+			})
+		}
+	}
+
+	if onConflict != nil && onConflict.Constraint != "" {
+		matched := onConflict.Constraint == table.GetName()+"_pkey"
+		for _, key := range keys {
+			if key.Name == onConflict.Constraint {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityMedium,
+				IssueType:     issuetypes.QueryIssueTypeUpsertTargetMissingUniqueIndex,
+				Message:       fmt.Sprintf("ON CONFLICT ON CONSTRAINT %s doesn't name a unique index on %s", onConflict.Constraint, insertStatement.Table),
+			})
+		}
+	}
+
+	return queryIssues, nil
 }
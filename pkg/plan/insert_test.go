@@ -0,0 +1,119 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanInsertStatementForIssues(t *testing.T) {
+	largeBatch := "insert into users (email) values " + strings.Repeat("('a'), ", maxInsertBatchRows) + "('a')"
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "column list",
+			query: "insert into users (email, name) values ('a', 'b')",
+			want:  []string{},
+		},
+		{
+			name:  "no column list",
+			query: "insert into users values (1, 'a', 'b')",
+			want:  []string{issuetypes.QueryIssueTypeInsertMissingColumnList},
+		},
+		{
+			name:  "omitted not null column",
+			query: "insert into users (name) values ('b')",
+			want:  []string{issuetypes.QueryIssueTypeMissingNotNullColumn},
+		},
+		{
+			name:  "large batch",
+			query: largeBatch,
+			want:  []string{issuetypes.QueryIssueTypeInsertBatchTooLarge},
+		},
+		{
+			name:  "insert select from a large table",
+			query: "insert into users (email) select user_ref from orders",
+			want:  []string{issuetypes.QueryIssueTypeInsertSelectFullScan},
+		},
+		{
+			name:  "insert select with an index",
+			query: "insert into users (email) select user_ref from orders where user_id = 1",
+			want:  []string{},
+		},
+		{
+			name:  "on duplicate key matching a unique index",
+			query: "insert into users (email, name) values ('a', 'b') on duplicate key update name = values(name)",
+			want:  []string{},
+		},
+		{
+			name:  "on duplicate key without a unique key",
+			query: "insert into orders (user_id, user_ref, status) values (1, 'a', 'b') on duplicate key update status = values(status)",
+			want:  []string{issuetypes.QueryIssueTypeUpsertTargetMissingUniqueIndex},
+		},
+		{
+			name:  "on duplicate key matching several unique keys",
+			query: "insert into users (id, email) values (1, 'a') on duplicate key update email = values(email)",
+			want:  []string{issuetypes.QueryIssueTypeAmbiguousUpsert},
+		},
+		{
+			name:  "on conflict matching a unique index",
+			query: "insert into users (email, name) values ('a', 'b') on conflict (email) do update set name = excluded.name returning id",
+			want:  []string{},
+		},
+		{
+			name:  "on conflict without a unique index",
+			query: "insert into users (email, name) values ('a', 'b') on conflict (name) do nothing",
+			want:  []string{issuetypes.QueryIssueTypeUpsertTargetMissingUniqueIndex},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScanInsertStatementForIssues(tt.query, testSchema())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, issueTypes(got), fmt.Sprintf("%v", got))
+		})
+	}
+}
+
+func TestSplitOnConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		want     *OnConflict
+		wantBody string
+	}{
+		{
+			name:     "no clause",
+			query:    "insert into users (email) values ('a')",
+			wantBody: "insert into users (email) values ('a')",
+		},
+		{
+			name:     "columns",
+			query:    "insert into users (email) values ('a') on conflict (email, lower(name)) where name is not null do nothing",
+			want:     &OnConflict{Columns: []string{"email"}, Action: "nothing"},
+			wantBody: "insert into users (email) values ('a') ",
+		},
+		{
+			name:     "constraint",
+			query:    "insert into users (email) values ('a') on conflict on constraint users_email do update set email = excluded.email returning id",
+			want:     &OnConflict{Constraint: "users_email", Action: "update"},
+			wantBody: "insert into users (email) values ('a') returning id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, body, err := SplitOnConflict(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
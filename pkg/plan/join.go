@@ -0,0 +1,320 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+)
+
+// joinGraph connects every table reference in a FROM clause (keyed by alias,
+// so self joins are separate nodes) with the predicates that relate them,
+// whether those come from an ON clause or from the WHERE clause.
+type joinGraph struct {
+	aliases      []string
+	tableByAlias map[string]string
+	edges        []joinEdge
+}
+
+type joinEdge struct {
+	leftAlias  string
+	rightAlias string
+
+	// leftColumn and rightColumn are only set when both sides of the predicate
+	// are plain columns compared with "="
+	leftColumn  string
+	rightColumn string
+}
+
+func buildJoinGraph(from sqlparser.TableExprs, where sqlparser.Expr, tables []dbtypes.Table) *joinGraph {
+	graph := &joinGraph{
+		aliases:      []string{},
+		tableByAlias: map[string]string{},
+		edges:        []joinEdge{},
+	}
+
+	predicates := []sqlparser.Expr{}
+	for _, tableExpr := range from {
+		predicates = append(predicates, graph.addTableExpr(tableExpr)...)
+	}
+
+	if where != nil {
+		predicates = append(predicates, where)
+	}
+
+	for _, predicate := range predicates {
+		for _, conjunct := range splitAnd(predicate) {
+			graph.addPredicate(conjunct, tables)
+		}
+	}
+
+	return graph
+}
+
+// addTableExpr registers the table references in tableExpr and returns the
+// ON predicates found along the way.
+func (g *joinGraph) addTableExpr(tableExpr sqlparser.TableExpr) []sqlparser.Expr {
+	switch expr := tableExpr.(type) {
+	case *sqlparser.AliasedTableExpr:
+		tableName := sqlparser.String(expr.Expr)
+		if _, ok := expr.Expr.(*sqlparser.Subquery); ok && !expr.As.IsEmpty() {
+			tableName = expr.As.String()
+		}
+		alias := expr.As.String()
+		if alias == "" {
+			alias = tableName
+		}
+
+		if _, exists := g.tableByAlias[alias]; !exists {
+			g.aliases = append(g.aliases, alias)
+		}
+		g.tableByAlias[alias] = tableName
+
+		return nil
+
+	case *sqlparser.ParenTableExpr:
+		predicates := []sqlparser.Expr{}
+		for _, inner := range expr.Exprs {
+			predicates = append(predicates, g.addTableExpr(inner)...)
+		}
+		return predicates
+
+	case *sqlparser.JoinTableExpr:
+		firstLeft := len(g.aliases)
+		predicates := g.addTableExpr(expr.LeftExpr)
+		firstRight := len(g.aliases)
+		predicates = append(predicates, g.addTableExpr(expr.RightExpr)...)
+
+		if expr.On != nil {
+			predicates = append(predicates, expr.On)
+		} else if (strings.HasPrefix(expr.Join, "natural") || expr.Join == sqlparser.JoinStr) && firstRight > firstLeft && len(g.aliases) > firstRight {
+			// a natural join is connected on its common columns. The parser
+			// reads CROSS JOIN as a plain join, so one without an ON clause
+			// asks for every combination of rows on purpose, unlike a comma
+			// join whose predicate was left out of the WHERE clause
+			g.edges = append(g.edges, joinEdge{
+				leftAlias:  g.aliases[firstLeft],
+				rightAlias: g.aliases[firstRight],
+			})
+		}
+
+		return predicates
+	}
+
+	return nil
+}
+
+func (g *joinGraph) addPredicate(predicate sqlparser.Expr, tables []dbtypes.Table) {
+	comparison, ok := predicate.(*sqlparser.ComparisonExpr)
+	if !ok {
+		return
+	}
+
+	leftAliases := g.referencedAliases(comparison.Left, tables)
+	rightAliases := g.referencedAliases(comparison.Right, tables)
+
+	for _, leftAlias := range leftAliases {
+		for _, rightAlias := range rightAliases {
+			if leftAlias == rightAlias {
+				continue
+			}
+
+			edge := joinEdge{
+				leftAlias:  leftAlias,
+				rightAlias: rightAlias,
+			}
+
+			leftCol, leftIsCol := comparison.Left.(*sqlparser.ColName)
+			rightCol, rightIsCol := comparison.Right.(*sqlparser.ColName)
+			if comparison.Operator == sqlparser.EqualStr && leftIsCol && rightIsCol {
+				edge.leftColumn = leftCol.Name.String()
+				edge.rightColumn = rightCol.Name.String()
+			}
+
+			g.edges = append(g.edges, edge)
+		}
+	}
+}
+
+// referencedAliases returns the aliases of the tables whose columns are used
+// in expr. Columns that can't be attributed to exactly one table are skipped.
+func (g *joinGraph) referencedAliases(expr sqlparser.Expr, tables []dbtypes.Table) []string {
+	aliases := []string{}
+
+	sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, nil
+		case *sqlparser.ColName:
+			if alias := g.resolveAlias(node, tables); alias != "" {
+				aliases = appendIfMissing(aliases, alias)
+			}
+		}
+		return true, nil
+	}, expr)
+
+	return aliases
+}
+
+func (g *joinGraph) resolveAlias(col *sqlparser.ColName, tables []dbtypes.Table) string {
+	qualifier := col.Qualifier.Name.String()
+	if qualifier != "" {
+		if _, exists := g.tableByAlias[qualifier]; exists {
+			return qualifier
+		}
+		return ""
+	}
+
+	match := ""
+	for _, alias := range g.aliases {
+		if contains(columnNamesForTable(g.tableByAlias[alias], tables), col.Name.String()) {
+			if match != "" {
+				return ""
+			}
+			match = alias
+		}
+	}
+
+	return match
+}
+
+// components groups the aliases into sets that are connected by at least one
+// predicate.
+func (g *joinGraph) components() [][]string {
+	parent := map[string]string{}
+	for _, alias := range g.aliases {
+		parent[alias] = alias
+	}
+
+	var find func(alias string) string
+	find = func(alias string) string {
+		if parent[alias] != alias {
+			parent[alias] = find(parent[alias])
+		}
+		return parent[alias]
+	}
+
+	for _, edge := range g.edges {
+		parent[find(edge.leftAlias)] = find(edge.rightAlias)
+	}
+
+	components := [][]string{}
+	componentByRoot := map[string]int{}
+	for _, alias := range g.aliases {
+		root := find(alias)
+		i, exists := componentByRoot[root]
+		if !exists {
+			i = len(components)
+			componentByRoot[root] = i
+			components = append(components, []string{})
+		}
+		components[i] = append(components[i], alias)
+	}
+
+	return components
+}
+
+func scanJoinGraphForIssues(graph *joinGraph, indexesByTable map[string][]Index, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	if graph == nil {
+		return queryIssues, nil
+	}
+
+	// tables that are not connected by any predicate produce a cartesian product
+	components := graph.components()
+	if len(components) > 1 {
+		groups := []string{}
+		for _, component := range components {
+			groups = append(groups, strings.Join(component, ", "))
+		}
+
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityHigh,
+			IssueType:     issuetypes.QueryIssueTypeCartesianJoin,
+			Message:       fmt.Sprintf("no join predicate connects (%s); this is a cross join that returns every combination of rows", strings.Join(groups, ") and (")),
+		})
+	}
+
+	reported := map[string]bool{}
+	for _, edge := range graph.edges {
+		if edge.leftColumn == "" || edge.rightColumn == "" {
+			continue
+		}
+
+		leftTable := graph.tableByAlias[edge.leftAlias]
+		rightTable := graph.tableByAlias[edge.rightAlias]
+		leftColumn := FindColumn(tables, leftTable, edge.leftColumn)
+		rightColumn := FindColumn(tables, rightTable, edge.rightColumn)
+
+		// joins on columns of different types need a cast on one side, which
+		// prevents using an index on that side
+		if leftColumn != nil && rightColumn != nil {
+			leftFamily := dataTypeFamily(leftColumn.GetDataType())
+			rightFamily := dataTypeFamily(rightColumn.GetDataType())
+			if leftFamily != rightFamily && leftFamily != dataTypeFamilyOther && rightFamily != dataTypeFamilyOther {
+				key := fmt.Sprintf("type:%s.%s=%s.%s", leftTable, edge.leftColumn, rightTable, edge.rightColumn)
+				if !reported[key] {
+					reported[key] = true
+					queryIssues = append(queryIssues, issuetypes.QueryIssue{
+						IssueSeverity: issuetypes.IssueSeverityMedium,
+						IssueType:     issuetypes.QueryIssueTypeJoinTypeMismatch,
+						Message: fmt.Sprintf("join compares %s.%s (%s) with %s.%s (%s); the implicit cast prevents index use",
+							leftTable, edge.leftColumn, leftColumn.GetDataType(), rightTable, edge.rightColumn, rightColumn.GetDataType()),
+					})
+				}
+			}
+		}
+
+		_, leftKnown := indexesByTable[leftTable]
+		_, rightKnown := indexesByTable[rightTable]
+		leftIndexed := !leftKnown || isColumnIndexed(indexesByTable[leftTable], edge.leftColumn)
+		rightIndexed := !rightKnown || isColumnIndexed(indexesByTable[rightTable], edge.rightColumn)
+
+		severity := issuetypes.IssueSeverityLow
+		if !leftIndexed && !rightIndexed {
+			severity = issuetypes.IssueSeverityMedium
+		}
+
+		unindexed := []string{}
+		if !leftIndexed {
+			unindexed = append(unindexed, fmt.Sprintf("%s.%s", leftTable, edge.leftColumn))
+		}
+		if !rightIndexed {
+			unindexed = append(unindexed, fmt.Sprintf("%s.%s", rightTable, edge.rightColumn))
+		}
+		sort.Strings(unindexed)
+
+		for _, column := range unindexed {
+			if reported["key:"+column] {
+				continue
+			}
+			reported["key:"+column] = true
+
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeJoinClauseMissingIndex,
+				Message:       fmt.Sprintf("join clause uses a column that is not a key: %s", column),
+			})
+		}
+	}
+
+	return queryIssues, nil
+}
+
+// splitAnd flattens a tree of AND expressions into its conjuncts.
+func splitAnd(expr sqlparser.Expr) []sqlparser.Expr {
+	switch expr := expr.(type) {
+	case *sqlparser.AndExpr:
+		return append(splitAnd(expr.Left), splitAnd(expr.Right)...)
+	case *sqlparser.ParenExpr:
+		if _, ok := expr.Expr.(*sqlparser.AndExpr); ok {
+			return splitAnd(expr.Expr)
+		}
+	}
+
+	return []sqlparser.Expr{expr}
+}
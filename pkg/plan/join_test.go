@@ -0,0 +1,158 @@
+package plan
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTable struct {
+	name        string
+	columns     []testColumn
+	primaryKeys []string
+	indexes     []dbtypes.Index
+	rowCount    int64
+}
+
+func (t testTable) GetName() string {
+	return t.name
+}
+
+func (t testTable) GetColumns() []dbtypes.Column {
+	var cols []dbtypes.Column
+	for _, c := range t.columns {
+		cols = append(cols, c)
+	}
+	return cols
+}
+
+func (t testTable) GetPrimaryKeys() []string {
+	return t.primaryKeys
+}
+
+func (t testTable) GetIndexes() []dbtypes.Index {
+	return t.indexes
+}
+
+func (t testTable) GetForeignKeys() []dbtypes.ForeignKey {
+	return nil
+}
+
+func (t testTable) GetEstimatedRowCount() int64 {
+	return t.rowCount
+}
+
+type testColumn struct {
+	name          string
+	dataType      string
+	isNullable    bool
+	columnDefault *string
+	extra         string
+}
+
+func (c testColumn) GetName() string           { return c.name }
+func (c testColumn) GetDataType() string       { return c.dataType }
+func (c testColumn) GetColumnType() string     { return c.dataType }
+func (c testColumn) GetIsNullable() bool       { return c.isNullable }
+func (c testColumn) GetColumnKey() string      { return "" }
+func (c testColumn) GetColumnDefault() *string { return c.columnDefault }
+func (c testColumn) GetExtra() string          { return c.extra }
+
+func testSchema() []dbtypes.Table {
+	return []dbtypes.Table{
+		testTable{
+			name: "users",
+			columns: []testColumn{
+				{name: "id", dataType: "int", extra: "auto_increment"},
+				{name: "email", dataType: "varchar"},
+				{name: "name", dataType: "varchar", isNullable: true},
+			},
+			primaryKeys: []string{"id"},
+			indexes: []dbtypes.Index{
+				{Name: "users_email", Columns: []string{"email"}, IsUnique: true},
+			},
+			rowCount: 10000,
+		},
+		testTable{
+			name: "orders",
+			columns: []testColumn{
+				{name: "id", dataType: "int", extra: "auto_increment"},
+				{name: "user_id", dataType: "int"},
+				{name: "user_ref", dataType: "varchar"},
+				{name: "status", dataType: "varchar"},
+			},
+			primaryKeys: []string{"id"},
+			indexes: []dbtypes.Index{
+				{Name: "orders_user_id", Columns: []string{"user_id"}},
+			},
+			rowCount: 1000000,
+		},
+	}
+}
+
+func issueTypes(issues []issuetypes.QueryIssue) []string {
+	types := []string{}
+	for _, issue := range issues {
+		types = append(types, issue.IssueType)
+	}
+	return types
+}
+
+func Test_scanJoinGraphForIssues(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "joined on keys",
+			query: "select * from users u join orders o on o.user_id = u.id",
+			want:  []string{},
+		},
+		{
+			name:  "comma join connected in where",
+			query: "select * from users u, orders o where o.user_id = u.id and o.status = 'new'",
+			want:  []string{},
+		},
+		{
+			name:  "comma join without predicate",
+			query: "select * from users, orders where users.id = 1",
+			want:  []string{issuetypes.QueryIssueTypeCartesianJoin},
+		},
+		{
+			name:  "explicit cross join",
+			query: "select * from users cross join orders where users.id = 1",
+			want:  []string{},
+		},
+		{
+			name:  "cross join and an unconnected comma join",
+			query: "select * from users u cross join orders o, users u2",
+			want:  []string{issuetypes.QueryIssueTypeCartesianJoin},
+		},
+		{
+			name:  "join on a non key column",
+			query: "select * from users u join orders o on o.status = u.name",
+			want:  []string{issuetypes.QueryIssueTypeJoinClauseMissingIndex, issuetypes.QueryIssueTypeJoinClauseMissingIndex},
+		},
+		{
+			name:  "join on mismatched types",
+			query: "select * from users u join orders o on o.user_ref = u.id",
+			want:  []string{issuetypes.QueryIssueTypeJoinTypeMismatch, issuetypes.QueryIssueTypeJoinClauseMissingIndex},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := testSchema()
+
+			selectStatement, err := parseSelectStatement(tt.query, tables)
+			require.NoError(t, err)
+
+			got, err := scanJoinGraphForIssues(selectStatement.joinGraph, indexesByTable(tables), tables)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, issueTypes(got))
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package plan
+
+import (
+	"fmt"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+)
+
+const (
+	LockModeShared    = "shared"
+	LockModeExclusive = "exclusive"
+)
+
+const (
+	// LockGranularityRow locks the rows that match a unique key
+	LockGranularityRow = "row"
+	// LockGranularityRange locks an index range, including the gaps between
+	// the rows so no other transaction can insert into it
+	LockGranularityRange = "range"
+	// LockGranularityTable locks every row of the table, which is what a
+	// locking read or write does when no index serves its predicate
+	LockGranularityTable = "table"
+)
+
+// Lock is a lock a statement takes and holds until the end of its
+// transaction.
+type Lock struct {
+	Table string
+	// Mode and Granularity describe the row locks, and are empty for a
+	// statement that only takes a table lock
+	Mode          string
+	Granularity   string
+	EstimatedRows int64
+	// TableLock is the engine's name for the lock on the table itself, such
+	// as Postgres' ROW EXCLUSIVE
+	TableLock string
+	// Detail explains how the engine takes the lock
+	Detail string
+}
+
+func (l Lock) String() string {
+	description := ""
+	if l.Mode != "" {
+		description = fmt.Sprintf("%s %s lock on %s (~%d rows)", l.Mode, l.Granularity, l.Table, l.EstimatedRows)
+		if l.TableLock != "" {
+			description += fmt.Sprintf(" (%s table lock)", l.TableLock)
+		}
+	} else {
+		description = fmt.Sprintf("%s table lock on %s", l.TableLock, l.Table)
+	}
+
+	if l.Detail != "" {
+		description += "; " + l.Detail
+	}
+
+	return description
+}
+
+// LockEstimator returns the locks a statement takes.
+type LockEstimator func(query string) ([]Lock, error)
+
+// EstimateLocks returns the locks a statement takes under InnoDB's rules for
+// the default REPEATABLE READ isolation level: plain reads don't lock, and
+// locking reads and writes lock every index record they scan, along with the
+// gap before it.
+func EstimateLocks(query string, tables []dbtypes.Table) ([]Lock, error) {
+	stmt, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := indexesByTable(tables)
+
+	switch stmt := stmt.(type) {
+	case *sqlparser.Select:
+		_, body, err := SplitCommonTableExpressions(query)
+		if err != nil {
+			return nil, err
+		}
+
+		clause, _ := SplitLockingClause(body)
+		if clause == nil {
+			return []Lock{}, nil
+		}
+
+		mode := LockModeShared
+		if clause.Exclusive() {
+			mode = LockModeExclusive
+		}
+		locks, err := selectLocks(stmt, mode, clause.Tables, tables, indexes)
+		if err != nil {
+			return nil, err
+		}
+		for i := range locks {
+			switch {
+			case clause.SkipLocked:
+				locks[i].Detail = "SKIP LOCKED skips the rows other transactions hold instead of waiting"
+			case clause.NoWait:
+				locks[i].Detail = "NOWAIT fails instead of waiting for rows other transactions hold"
+			}
+		}
+		return locks, nil
+
+	case *sqlparser.Insert:
+		insertStatement, err := parseInsertStatement(query)
+		if err != nil {
+			return nil, fmt.Errorf("parse insert statement: %w", err)
+		}
+
+		locks := []Lock{
+			{
+				Table:         insertStatement.Table,
+				Mode:          LockModeExclusive,
+				Granularity:   LockGranularityRow,
+				EstimatedRows: int64(len(insertStatement.Values)),
+			},
+		}
+
+		// the rows read by INSERT ... SELECT are share locked
+		if selectStmt, ok := insertStatement.Select.(*sqlparser.Select); ok {
+			sourceLocks, err := selectLocks(selectStmt, LockModeShared, nil, tables, indexes)
+			if err != nil {
+				return nil, err
+			}
+			locks = append(locks, sourceLocks...)
+		}
+		return locks, nil
+
+	case *sqlparser.Update:
+		updateStatement, err := parseUpdateStatement(query, tables)
+		if err != nil {
+			return nil, fmt.Errorf("parse update statement: %w", err)
+		}
+		return writeLocks(updateStatement.Tables, updateStatement.Sources, updateStatement.Filter, tables, indexes), nil
+
+	case *sqlparser.Delete:
+		deleteStatement, err := parseDeleteStatement(query, tables)
+		if err != nil {
+			return nil, fmt.Errorf("parse delete statement: %w", err)
+		}
+		return writeLocks(deleteStatement.Tables, deleteStatement.Sources, deleteStatement.Filter, tables, indexes), nil
+	}
+
+	return []Lock{}, nil
+}
+
+// selectLocks returns the locks a locking read takes on the tables in its FROM
+// clause, or only on the tables named by the aliases in only.
+func selectLocks(selectStmt *sqlparser.Select, mode string, only []string, tables []dbtypes.Table, indexes map[string][]Index) ([]Lock, error) {
+	tableAliasLookup, tableNames, err := extractTableExprs(selectStmt.From)
+	if err != nil {
+		return nil, fmt.Errorf("extract tables: %w", err)
+	}
+
+	filter, err := parseWriteFilter(selectStmt.Where, selectStmt.OrderBy, selectStmt.Limit, newScope(nil, tableAliasLookup, tableNames), tables)
+	if err != nil {
+		return nil, err
+	}
+	filter.Joined = len(tableNames) > 1
+
+	locked := tableNames
+	if len(only) > 0 {
+		locked = []string{}
+		for _, alias := range only {
+			if tableName, ok := tableAliasLookup[alias]; ok {
+				locked = appendIfMissing(locked, tableName)
+			}
+		}
+	}
+
+	locks := []Lock{}
+	for _, tableName := range locked {
+		locks = append(locks, tableLock(tableName, mode, filter, tables, indexes))
+	}
+	return locks, nil
+}
+
+// writeLocks returns the exclusive locks on the tables a statement writes, and
+// the shared locks on the tables it only reads to find the rows.
+func writeLocks(targets []string, sources []string, filter *writeFilter, tables []dbtypes.Table, indexes map[string][]Index) []Lock {
+	locks := []Lock{}
+	for _, tableName := range targets {
+		locks = append(locks, tableLock(tableName, LockModeExclusive, filter, tables, indexes))
+	}
+	for _, tableName := range sources {
+		if !contains(targets, tableName) {
+			locks = append(locks, tableLock(tableName, LockModeShared, filter, tables, indexes))
+		}
+	}
+	return locks
+}
+
+func tableLock(tableName string, mode string, filter *writeFilter, tables []dbtypes.Table, indexes map[string][]Index) Lock {
+	lock := Lock{
+		Table:       tableName,
+		Mode:        mode,
+		Granularity: LockGranularityRange,
+	}
+
+	table := findTable(tables, tableName)
+	if table == nil {
+		return lock
+	}
+
+	estimate, fullScan := estimateAffectedRows(table, filter, indexes[tableName])
+	switch {
+	case fullScan && !filter.Joined:
+		// every scanned row is locked, not only the ones that match
+		lock.Granularity = LockGranularityTable
+		lock.EstimatedRows = table.GetEstimatedRowCount()
+	case matchesUniqueKey(indexes[tableName], filter.Equality[tableName]):
+		lock.Granularity = LockGranularityRow
+		lock.EstimatedRows = estimate
+	default:
+		lock.EstimatedRows = estimate
+	}
+
+	return lock
+}
+
+// FormatLocks returns the locks as a summary for the output of a plan, or an
+// empty string when the statement takes no locks.
+func FormatLocks(locks []Lock) string {
+	if len(locks) == 0 {
+		return ""
+	}
+
+	formatted := "Locks:\n"
+	for _, lock := range locks {
+		formatted += "  " + lock.String() + "\n"
+	}
+
+	return formatted
+}
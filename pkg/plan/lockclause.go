@@ -0,0 +1,101 @@
+package plan
+
+import (
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+const (
+	LockStrengthUpdate      = "update"
+	LockStrengthNoKeyUpdate = "no key update"
+	LockStrengthShare       = "share"
+	LockStrengthKeyShare    = "key share"
+)
+
+// LockingClause is the locking clause at the end of a SELECT: FOR UPDATE,
+// FOR SHARE and the Postgres variants, or MySQL's LOCK IN SHARE MODE.
+type LockingClause struct {
+	Strength string
+	// Tables are the tables named with OF, empty when every table is locked
+	Tables     []string
+	NoWait     bool
+	SkipLocked bool
+}
+
+// Exclusive returns true if the clause blocks other writers of the rows.
+func (c LockingClause) Exclusive() bool {
+	return c.Strength == LockStrengthUpdate || c.Strength == LockStrengthNoKeyUpdate
+}
+
+// SplitLockingClause separates the locking clause from a SELECT. The parser
+// only understands FOR UPDATE and LOCK IN SHARE MODE without any options. The
+// clause is nil when the query doesn't have one.
+func SplitLockingClause(query string) (*LockingClause, string) {
+	tokens := significantTokens(lexer.Tokenize(query))
+
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.IsPunctuation("("):
+			depth++
+			continue
+		case token.IsPunctuation(")"):
+			depth--
+			continue
+		}
+		if depth != 0 {
+			continue
+		}
+
+		next := func(offset int, keyword string) bool {
+			return i+offset < len(tokens) && tokens[i+offset].Is(keyword)
+		}
+
+		clause := LockingClause{}
+		j := i
+		switch {
+		case token.Is("lock") && next(1, "in") && next(2, "share") && next(3, "mode"):
+			clause.Strength = LockStrengthShare
+			j = i + 4
+		case token.Is("for") && next(1, "update"):
+			clause.Strength = LockStrengthUpdate
+			j = i + 2
+		case token.Is("for") && next(1, "no") && next(2, "key") && next(3, "update"):
+			clause.Strength = LockStrengthNoKeyUpdate
+			j = i + 4
+		case token.Is("for") && next(1, "share"):
+			clause.Strength = LockStrengthShare
+			j = i + 2
+		case token.Is("for") && next(1, "key") && next(2, "share"):
+			clause.Strength = LockStrengthKeyShare
+			j = i + 3
+		default:
+			continue
+		}
+
+		rest := ""
+	options:
+		for ; j < len(tokens); j++ {
+			switch {
+			case tokens[j].Is("limit"), tokens[j].Is("offset"):
+				// Postgres accepts the locking clause before LIMIT
+				rest = " " + query[tokens[j].Pos:]
+				break options
+			case tokens[j].Is("of"), tokens[j].IsPunctuation(","):
+				if j+1 < len(tokens) && (tokens[j+1].Type == lexer.Word || tokens[j+1].Type == lexer.QuotedIdentifier) {
+					clause.Tables = append(clause.Tables, unquoteIdentifier(tokens[j+1].Value))
+					j++
+				}
+			case tokens[j].Is("nowait"):
+				clause.NoWait = true
+			case tokens[j].Is("skip"):
+				clause.SkipLocked = true
+			}
+		}
+
+		return &clause, strings.TrimSpace(strings.TrimSpace(query[:token.Pos]) + rest)
+	}
+
+	return nil, query
+}
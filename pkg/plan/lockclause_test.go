@@ -0,0 +1,53 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLockingClause(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantClause *LockingClause
+		wantQuery  string
+	}{
+		{
+			name:      "no clause",
+			query:     "select * from users where id = 1",
+			wantQuery: "select * from users where id = 1",
+		},
+		{
+			name:       "for update",
+			query:      "select * from users where id = 1 for update",
+			wantClause: &LockingClause{Strength: LockStrengthUpdate},
+			wantQuery:  "select * from users where id = 1",
+		},
+		{
+			name:       "mysql share mode",
+			query:      "select * from users where id = 1 lock in share mode",
+			wantClause: &LockingClause{Strength: LockStrengthShare},
+			wantQuery:  "select * from users where id = 1",
+		},
+		{
+			name:       "tables and options before limit",
+			query:      "select * from orders o join users u on u.id = o.user_id for no key update of o skip locked limit 10",
+			wantClause: &LockingClause{Strength: LockStrengthNoKeyUpdate, Tables: []string{"o"}, SkipLocked: true},
+			wantQuery:  "select * from orders o join users u on u.id = o.user_id limit 10",
+		},
+		{
+			name:       "clause in a subquery is left alone",
+			query:      "select * from users where id in (select user_id from orders for share) for key share nowait",
+			wantClause: &LockingClause{Strength: LockStrengthKeyShare, NoWait: true},
+			wantQuery:  "select * from users where id in (select user_id from orders for share)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, query := SplitLockingClause(tt.query)
+			assert.Equal(t, tt.wantClause, clause)
+			assert.Equal(t, tt.wantQuery, query)
+		})
+	}
+}
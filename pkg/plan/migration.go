@@ -0,0 +1,310 @@
+package plan
+
+import (
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+const (
+	AlterActionAddColumn          = "add column"
+	AlterActionDropColumn         = "drop column"
+	AlterActionAlterColumnType    = "alter column type"
+	AlterActionSetNotNull         = "set not null"
+	AlterActionRenameColumn       = "rename column"
+	AlterActionRenameTable        = "rename table"
+	AlterActionAddForeignKey      = "add foreign key"
+	AlterActionAddPrimaryKey      = "add primary key"
+	AlterActionDropPrimaryKey     = "drop primary key"
+	AlterActionAddConstraint      = "add constraint"
+	AlterActionAddIndex           = "add index"
+	AlterActionValidateConstraint = "validate constraint"
+	AlterActionConvertCharset     = "convert character set"
+	// AlterActionOption is a MySQL clause such as ALGORITHM=INPLACE or
+	// LOCK=NONE
+	AlterActionOption = "option"
+)
+
+// AlterAction is one subcommand of an ALTER TABLE.
+type AlterAction struct {
+	Kind   string
+	Column string
+	// NewName is the new name of a renamed column or table
+	NewName string
+
+	// Type, NotNull and Default describe an added or changed column
+	Type    string
+	NotNull bool
+	Default string
+
+	// References is the table a foreign key points to
+	References string
+	NotValid   bool
+	// UsingIndex is set on a constraint that is added with an existing index
+	UsingIndex bool
+
+	// Option and Value are the name and value of an option, such as
+	// "algorithm" and "inplace"
+	Option string
+	Value  string
+}
+
+// columnConstraintKeywords end the type of a column definition.
+var columnConstraintKeywords = []string{
+	"not", "null", "default", "primary", "unique", "references", "check", "constraint",
+	"generated", "collate", "auto_increment", "comment", "first", "after", "on", "using",
+	"character", "charset",
+}
+
+// ParseAlterAction reads one of the Actions of an ALTER TABLE. MySQL and
+// Postgres spell most actions differently, so both are mapped onto the same
+// kinds.
+func ParseAlterAction(action string) AlterAction {
+	r := ddlReader{query: action, tokens: significantTokens(lexer.Tokenize(action))}
+	parsed := AlterAction{}
+
+	switch {
+	case r.accept("add"):
+		switch {
+		case r.peek("constraint"), r.peek("primary"), r.peek("foreign"), r.peek("unique"), r.peek("check"),
+			r.peek("index"), r.peek("key"), r.peek("fulltext"), r.peek("spatial"):
+			if r.accept("constraint") {
+				r.identifier()
+			}
+			switch {
+			case r.acceptAll("primary", "key"):
+				parsed.Kind = AlterActionAddPrimaryKey
+			case r.acceptAll("foreign", "key"):
+				parsed.Kind = AlterActionAddForeignKey
+				r.skipTo("references")
+				parsed.References = r.identifier()
+			case r.peek("index"), r.peek("key"), r.peek("fulltext"), r.peek("spatial"), r.peek("unique"):
+				parsed.Kind = AlterActionAddIndex
+			default:
+				parsed.Kind = AlterActionAddConstraint
+			}
+			parsed.NotValid = r.containsAll("not", "valid")
+			parsed.UsingIndex = r.containsAll("using", "index")
+		default:
+			r.accept("column")
+			r.acceptAll("if", "not", "exists")
+			parsed.Kind = AlterActionAddColumn
+			parsed.Column = r.identifier()
+			r.columnDefinition(&parsed)
+		}
+
+	case r.accept("drop"):
+		switch {
+		case r.acceptAll("primary", "key"):
+			parsed.Kind = AlterActionDropPrimaryKey
+		case r.peek("constraint"), r.peek("index"), r.peek("key"), r.peek("foreign"), r.peek("check"), r.peek("default"):
+			parsed.Kind = "drop " + strings.ToLower(r.next().Value)
+		default:
+			r.accept("column")
+			r.acceptAll("if", "exists")
+			parsed.Kind = AlterActionDropColumn
+			parsed.Column = r.identifier()
+		}
+
+	case r.accept("alter"):
+		r.accept("column")
+		parsed.Column = r.identifier()
+		switch {
+		case r.accept("type"), r.acceptAll("set", "data", "type"):
+			parsed.Kind = AlterActionAlterColumnType
+			parsed.Type = r.columnType()
+		case r.acceptAll("set", "not", "null"):
+			parsed.Kind = AlterActionSetNotNull
+		default:
+			parsed.Kind = "alter column"
+		}
+
+	case r.accept("modify"):
+		r.accept("column")
+		parsed.Kind = AlterActionAlterColumnType
+		parsed.Column = r.identifier()
+		r.columnDefinition(&parsed)
+
+	case r.accept("change"):
+		r.accept("column")
+		parsed.Kind = AlterActionAlterColumnType
+		parsed.Column = r.identifier()
+		if newName := r.identifier(); newName != parsed.Column {
+			parsed.NewName = newName
+		}
+		r.columnDefinition(&parsed)
+
+	case r.accept("rename"):
+		switch {
+		case r.accept("to"), r.accept("as"):
+			parsed.Kind = AlterActionRenameTable
+			parsed.NewName = r.identifier()
+		case r.peek("index"), r.peek("key"), r.peek("constraint"):
+			parsed.Kind = "rename " + strings.ToLower(r.next().Value)
+		default:
+			r.accept("column")
+			parsed.Kind = AlterActionRenameColumn
+			parsed.Column = r.identifier()
+			r.accept("to")
+			parsed.NewName = r.identifier()
+		}
+
+	case r.acceptAll("validate", "constraint"):
+		parsed.Kind = AlterActionValidateConstraint
+
+	case r.acceptAll("convert", "to"):
+		parsed.Kind = AlterActionConvertCharset
+
+	case r.accept("algorithm"), r.accept("lock"):
+		parsed.Kind = AlterActionOption
+		parsed.Option = strings.ToLower(r.tokens[r.i-1].Value)
+		if !r.done() && r.tokens[r.i].IsPunctuation("=") {
+			r.i++
+		}
+		if !r.done() {
+			parsed.Value = strings.ToLower(r.next().Value)
+		}
+
+	default:
+		words := []string{}
+		for !r.done() && r.tokens[r.i].Type == lexer.Word && len(words) < 2 {
+			words = append(words, strings.ToLower(r.next().Value))
+		}
+		parsed.Kind = strings.Join(words, " ")
+	}
+
+	return parsed
+}
+
+// columnType reads the type of a column definition, stopping at the first
+// constraint.
+func (r *ddlReader) columnType() string {
+	if r.done() {
+		return ""
+	}
+
+	start := r.tokens[r.i].Pos
+	end := start
+	for !r.done() {
+		token := r.tokens[r.i]
+		if token.Type == lexer.Word && contains(columnConstraintKeywords, strings.ToLower(token.Value)) {
+			break
+		}
+		if token.IsPunctuation("(") {
+			closing := matchingParen(r.tokens, r.i)
+			if closing < 0 {
+				break
+			}
+			r.i = closing
+			token = r.tokens[closing]
+		}
+		end = token.End()
+		r.i++
+	}
+
+	return strings.ToLower(strings.TrimSpace(r.query[start:end]))
+}
+
+// columnDefinition reads the type and the constraints of a column.
+func (r *ddlReader) columnDefinition(action *AlterAction) {
+	action.Type = r.columnType()
+
+	for !r.done() {
+		switch {
+		case r.acceptAll("not", "null"):
+			action.NotNull = true
+		case r.acceptAll("primary", "key"):
+			action.NotNull = true
+		case r.accept("default"):
+			start := r.tokens[r.i-1].End()
+			end := start
+			for !r.done() {
+				token := r.tokens[r.i]
+				if token.Type == lexer.Word && contains(columnConstraintKeywords, strings.ToLower(token.Value)) {
+					break
+				}
+				if token.IsPunctuation("(") {
+					if closing := matchingParen(r.tokens, r.i); closing >= 0 {
+						r.i = closing
+						token = r.tokens[closing]
+					}
+				}
+				end = token.End()
+				r.i++
+			}
+			action.Default = strings.TrimSpace(r.query[start:end])
+		case r.accept("references"):
+			action.References = r.identifier()
+		default:
+			r.i++
+		}
+	}
+}
+
+// containsAll returns true if the keywords appear in sequence anywhere in the
+// rest of the statement.
+func (r *ddlReader) containsAll(keywords ...string) bool {
+	for i := r.i; i+len(keywords) <= len(r.tokens); i++ {
+		matched := true
+		for offset, keyword := range keywords {
+			if !r.tokens[i+offset].Is(keyword) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// DependentViews returns the views whose definition uses a table, or a column
+// of the table when column isn't empty.
+func DependentViews(views []dbtypes.View, table string, column string) []string {
+	dependent := []string{}
+	for _, view := range views {
+		usesTable, usesColumn := false, column == ""
+		for _, token := range lexer.Tokenize(view.Definition) {
+			if token.Type != lexer.Word && token.Type != lexer.QuotedIdentifier {
+				continue
+			}
+			name := unquoteIdentifier(token.Value)
+			if strings.EqualFold(name, table) {
+				usesTable = true
+			}
+			if column != "" && strings.EqualFold(name, column) {
+				usesColumn = true
+			}
+		}
+		if usesTable && usesColumn {
+			dependent = append(dependent, view.Name)
+		}
+	}
+	return dependent
+}
+
+// MigrationSeverity returns the severity of a schema change that blocks
+// queries on a table while it runs, which depends on how long it runs.
+func MigrationSeverity(tables []dbtypes.Table, tableName string) string {
+	table := findTable(tables, tableName)
+	switch {
+	case table == nil, table.GetEstimatedRowCount() == 0:
+		return issuetypes.IssueSeverityLow
+	case table.GetEstimatedRowCount() >= largeTableRowCount:
+		return issuetypes.IssueSeverityHigh
+	default:
+		return issuetypes.IssueSeverityMedium
+	}
+}
+
+// EstimatedRowCount returns the estimated number of rows in a table, or 0
+// if the table isn't in the schema.
+func EstimatedRowCount(tables []dbtypes.Table, tableName string) int64 {
+	if table := findTable(tables, tableName); table != nil {
+		return table.GetEstimatedRowCount()
+	}
+	return 0
+}
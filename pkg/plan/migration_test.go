@@ -0,0 +1,68 @@
+package plan
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlterAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		want   AlterAction
+	}{
+		{
+			name:   "add column with a default",
+			action: "add column created_at timestamp with time zone not null default now()",
+			want:   AlterAction{Kind: AlterActionAddColumn, Column: "created_at", Type: "timestamp with time zone", NotNull: true, Default: "now()"},
+		},
+		{
+			name:   "postgres type change",
+			action: "alter column status set data type varchar(64)",
+			want:   AlterAction{Kind: AlterActionAlterColumnType, Column: "status", Type: "varchar(64)"},
+		},
+		{
+			name:   "mysql change column",
+			action: "change `status` `state` varchar(64) not null",
+			want:   AlterAction{Kind: AlterActionAlterColumnType, Column: "status", NewName: "state", Type: "varchar(64)", NotNull: true},
+		},
+		{
+			name:   "foreign key not valid",
+			action: "add constraint orders_user_fk foreign key (user_id) references public.users (id) not valid",
+			want:   AlterAction{Kind: AlterActionAddForeignKey, References: "users", NotValid: true},
+		},
+		{
+			name:   "unique constraint using an index",
+			action: "add constraint users_email unique using index users_email_idx",
+			want:   AlterAction{Kind: AlterActionAddIndex, UsingIndex: true},
+		},
+		{
+			name:   "rename column",
+			action: "rename column name to full_name",
+			want:   AlterAction{Kind: AlterActionRenameColumn, Column: "name", NewName: "full_name"},
+		},
+		{
+			name:   "algorithm option",
+			action: "ALGORITHM=INPLACE",
+			want:   AlterAction{Kind: AlterActionOption, Option: "algorithm", Value: "inplace"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseAlterAction(tt.action))
+		})
+	}
+}
+
+func TestDependentViews(t *testing.T) {
+	views := []dbtypes.View{
+		{Name: "active_users", Definition: "SELECT users.id, users.email FROM users WHERE users.name IS NOT NULL"},
+		{Name: "open_orders", Definition: "select `orders`.`id` AS `id` from `orders` where `orders`.`status` = 'open'"},
+	}
+
+	assert.Equal(t, []string{"active_users"}, DependentViews(views, "users", ""))
+	assert.Equal(t, []string{"open_orders"}, DependentViews(views, "orders", "status"))
+	assert.Equal(t, []string{}, DependentViews(views, "orders", "user_id"))
+}
@@ -0,0 +1,449 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// castTypeWords are the words that continue a multi-word type name after a ::
+// cast, such as double precision or timestamp with time zone.
+var castTypeWords = map[string]bool{
+	"precision": true, "varying": true, "with": true, "without": true, "time": true, "zone": true,
+}
+
+// Parameter is a bind parameter of a query, and the type it's inferred to
+// have from the column it's compared with or assigned to.
+type Parameter struct {
+	// Name is how the query writes the parameter: $1, :name, or ? followed
+	// by its position
+	Name string
+	// Table and Column are the column the parameter is compared with or
+	// assigned to, if any
+	Table  string
+	Column string
+	// DataType is the type of the column, or the type of a LIMIT or OFFSET
+	DataType string
+	// Cast is the type the query casts the parameter to, with $1::int
+	Cast string
+
+	key string
+}
+
+// Type returns the type the parameter is sent as: its cast if it has one,
+// otherwise the type of its column.
+func (p Parameter) Type() string {
+	if p.Cast != "" {
+		return p.Cast
+	}
+	return p.DataType
+}
+
+func (p Parameter) String() string {
+	description := p.Name
+	if p.Column != "" {
+		description += fmt.Sprintf(": %s.%s", p.Table, p.Column)
+	} else if p.DataType != "" {
+		description += ": " + p.DataType
+	}
+	if p.Column != "" && p.DataType != "" {
+		description += fmt.Sprintf(" (%s)", p.DataType)
+	}
+	if p.Cast != "" {
+		description += fmt.Sprintf(", cast to %s", p.Cast)
+	}
+	if p.Column == "" && p.DataType == "" && p.Cast == "" {
+		description += ": unknown type"
+	}
+	return description
+}
+
+// FormatParameters returns the parameters as a section of a plan.
+func FormatParameters(parameters []Parameter) string {
+	if len(parameters) == 0 {
+		return ""
+	}
+
+	formatted := "Parameters:\n"
+	for _, parameter := range parameters {
+		formatted += "  " + parameter.String() + "\n"
+	}
+
+	return formatted
+}
+
+// placeholder is a bind parameter where it appears in a query.
+type placeholder struct {
+	token lexer.Token
+	// key is the name of the bind variable the parser sees: v1 for the
+	// first ? and for $1, and the name of a :name parameter
+	key string
+	// cast is the type of a :: cast that follows the parameter, and end is
+	// the offset past the cast
+	cast string
+	end  int
+}
+
+// placeholders returns the bind parameters of a query in the order they
+// appear. A ? is numbered by its position, the way the parser numbers it.
+func placeholders(query string) []placeholder {
+	tokens := lexer.Tokenize(query)
+
+	found := []placeholder{}
+	positional := 0
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Type != lexer.Placeholder {
+			continue
+		}
+
+		p := placeholder{token: token, end: token.End()}
+		switch {
+		case token.Value == "?":
+			positional++
+			p.key = fmt.Sprintf("v%d", positional)
+		case strings.HasPrefix(token.Value, "$"):
+			p.key = "v" + token.Value[1:]
+		default:
+			p.key = strings.TrimPrefix(token.Value, ":")
+		}
+
+		if i+2 < len(tokens) && tokens[i+1].IsPunctuation("::") && tokens[i+2].Type == lexer.Word {
+			j := i + 2
+			words := []string{strings.ToLower(tokens[j].Value)}
+			p.end = tokens[j].End()
+			for j+1 < len(tokens) && tokens[j+1].Type == lexer.Word && castTypeWords[strings.ToLower(tokens[j+1].Value)] {
+				j++
+				words = append(words, strings.ToLower(tokens[j].Value))
+				p.end = tokens[j].End()
+			}
+			// a length, precision or array suffix: varchar(10), int[]
+			for _, pair := range [][2]string{{"(", ")"}, {"[", "]"}} {
+				if j+1 < len(tokens) && tokens[j+1].IsPunctuation(pair[0]) {
+					for j+1 < len(tokens) && !tokens[j+1].IsPunctuation(pair[1]) {
+						j++
+					}
+					if j+1 < len(tokens) {
+						j++
+						p.end = tokens[j].End()
+					}
+				}
+			}
+			p.cast = strings.TrimSpace(strings.Join(words, " ") + query[tokens[i+2+len(words)-1].End():p.end])
+			i = j
+		}
+
+		found = append(found, p)
+	}
+
+	return found
+}
+
+// parameterOrder returns the keys of the distinct parameters: the numbered
+// parameters by their number, which is their position for a ?, followed by
+// the named parameters in the order they first appear.
+func parameterOrder(found []placeholder) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, p := range found {
+		if !seen[p.key] {
+			seen[p.key] = true
+			keys = append(keys, p.key)
+		}
+	}
+
+	number := func(key string) int {
+		if !strings.HasPrefix(key, "v") {
+			return -1
+		}
+		n, err := strconv.Atoi(key[1:])
+		if err != nil {
+			return -1
+		}
+		return n
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := number(keys[i]), number(keys[j])
+		if a < 0 || b < 0 {
+			return a >= 0 && b < 0
+		}
+		return a < b
+	})
+
+	return keys
+}
+
+// stripParameterCasts removes the :: casts of bind parameters, which the
+// parser doesn't support. The casts are kept on the Parameters InferParameters
+// returns.
+func stripParameterCasts(query string) string {
+	var b strings.Builder
+	last := 0
+	for _, p := range placeholders(query) {
+		if p.cast == "" {
+			continue
+		}
+		b.WriteString(query[last:p.token.End()])
+		last = p.end
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// InferParameters returns the bind parameters of a query in the order they
+// appear, with the type of the column each one is compared with or assigned
+// to, in a WHERE or ON clause, a SET, the VALUES of an INSERT, or a LIMIT.
+// Parameters that can't be matched with a column are returned without a type.
+func InferParameters(query string, tables []dbtypes.Table) ([]Parameter, error) {
+	found := placeholders(query)
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	parameters := []Parameter{}
+	byKey := map[string]int{}
+	for _, key := range parameterOrder(found) {
+		byKey[key] = len(parameters)
+		parameters = append(parameters, Parameter{key: key})
+	}
+	for _, p := range found {
+		parameter := &parameters[byKey[p.key]]
+		if parameter.Name == "" {
+			parameter.Name = p.token.Value
+			if parameter.Name == "?" {
+				parameter.Name = "?" + strings.TrimPrefix(p.key, "v")
+			}
+		}
+		if parameter.Cast == "" {
+			parameter.Cast = p.cast
+		}
+	}
+
+	stmt, err := Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("parse query: %w", err)
+	}
+
+	tableAliasLookup, tableNames, err := statementTables(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	bind := func(column *sqlparser.ColName, value sqlparser.Expr) {
+		key, ok := bindVariable(value)
+		if !ok {
+			return
+		}
+		i, ok := byKey[key]
+		if !ok || parameters[i].Column != "" {
+			return
+		}
+
+		tableName, err := resolveColumnTable(tableNames, column.Qualifier.Name.String(), column.Name.String(), tableAliasLookup, tables)
+		if err != nil {
+			return
+		}
+		parameters[i].Table = tableName
+		parameters[i].Column = column.Name.String()
+		if col := FindColumn(tables, tableName, column.Name.String()); col != nil {
+			parameters[i].DataType = col.GetDataType()
+		}
+	}
+	bindType := func(value sqlparser.Expr, dataType string) {
+		if key, ok := bindVariable(value); ok {
+			if i, ok := byKey[key]; ok && parameters[i].DataType == "" {
+				parameters[i].DataType = dataType
+			}
+		}
+	}
+
+	err = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.ComparisonExpr:
+			forEachComparedColumn(node, bind)
+		case *sqlparser.RangeCond:
+			if column, ok := node.Left.(*sqlparser.ColName); ok {
+				bind(column, node.From)
+				bind(column, node.To)
+			}
+		case *sqlparser.UpdateExpr:
+			bind(node.Name, node.Expr)
+		case *sqlparser.Insert:
+			if rows, ok := node.Rows.(sqlparser.Values); ok {
+				for _, row := range rows {
+					for i, value := range row {
+						if i < len(node.Columns) {
+							bind(&sqlparser.ColName{Name: node.Columns[i], Qualifier: node.Table}, value)
+						}
+					}
+				}
+			}
+		case *sqlparser.Limit:
+			if node == nil {
+				break
+			}
+			bindType(node.Rowcount, "bigint")
+			bindType(node.Offset, "bigint")
+		}
+		return true, nil
+	}, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("walk: %w", err)
+	}
+
+	return parameters, nil
+}
+
+// forEachComparedColumn calls fn with the column and the value of a comparison
+// of a column with a value, on either side, and with each value of an IN list.
+func forEachComparedColumn(comparison *sqlparser.ComparisonExpr, fn func(column *sqlparser.ColName, value sqlparser.Expr)) {
+	left, right := comparison.Left, comparison.Right
+	column, ok := left.(*sqlparser.ColName)
+	if !ok {
+		if column, ok = right.(*sqlparser.ColName); !ok {
+			return
+		}
+		right = left
+	}
+
+	if tuple, ok := right.(sqlparser.ValTuple); ok {
+		for _, value := range tuple {
+			fn(column, value)
+		}
+		return
+	}
+	fn(column, right)
+}
+
+// bindVariable returns the name of the bind variable an expression is.
+func bindVariable(expr sqlparser.Expr) (string, bool) {
+	value, ok := expr.(*sqlparser.SQLVal)
+	if !ok || value.Type != sqlparser.ValArg {
+		return "", false
+	}
+	return strings.TrimPrefix(string(value.Val), ":"), true
+}
+
+// statementTables returns the alias lookup and the names of every table a
+// statement reads or writes, including the tables of its subqueries.
+func statementTables(stmt sqlparser.Statement) (map[string]string, []string, error) {
+	tableAliasLookup := map[string]string{}
+	tableNames := []string{}
+	add := func(tableExprs sqlparser.TableExprs) error {
+		aliases, names, err := extractTableExprs(tableExprs)
+		if err != nil {
+			return err
+		}
+		for alias, name := range aliases {
+			tableAliasLookup[alias] = name
+		}
+		for _, name := range names {
+			tableNames = appendIfMissing(tableNames, name)
+		}
+		return nil
+	}
+
+	err := sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Select:
+			return true, add(node.From)
+		case *sqlparser.Update:
+			return true, add(node.TableExprs)
+		case *sqlparser.Delete:
+			return true, add(node.TableExprs)
+		case *sqlparser.Insert:
+			name := node.Table.Name.String()
+			tableAliasLookup[name] = name
+			tableNames = appendIfMissing(tableNames, name)
+		}
+		return true, nil
+	}, stmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("extract tables: %w", err)
+	}
+
+	return tableAliasLookup, tableNames, nil
+}
+
+// HasParameters returns true if a query has bind parameters.
+func HasParameters(query string) bool {
+	return len(placeholders(query)) > 0
+}
+
+// BindParameters returns the query with its bind parameters replaced by the
+// values, which are SQL literals, in the order of the parameters InferParameters
+// returns: $1 takes the first value no matter where it appears. A cast on a
+// parameter is kept.
+func BindParameters(query string, values []string) (string, error) {
+	found := placeholders(query)
+
+	order := map[string]int{}
+	for i, key := range parameterOrder(found) {
+		order[key] = i
+	}
+	if len(order) != len(values) {
+		return "", fmt.Errorf("the query has %d parameters, but %d values were given", len(order), len(values))
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, p := range found {
+		b.WriteString(query[last:p.token.Pos])
+		b.WriteString(values[order[p.key]])
+		last = p.token.End()
+	}
+	b.WriteString(query[last:])
+
+	return b.String(), nil
+}
+
+// ParameterLiteral returns a value given for a parameter as a SQL literal.
+// Numbers, NULL, true and false and values that are already quoted are kept,
+// anything else is quoted as a string.
+func ParameterLiteral(value string) string {
+	switch strings.ToLower(value) {
+	case "null", "true", "false":
+		return value
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// RepresentativeValue returns a literal of a data type to plan a parameter of
+// that type with, when no value is given.
+func RepresentativeValue(dataType string) string {
+	switch dataTypeFamily(dataType) {
+	case dataTypeFamilyNumeric:
+		return "1"
+	case dataTypeFamilyString:
+		return "'a'"
+	case dataTypeFamilyTemporal:
+		if strings.Contains(strings.ToLower(dataType), "interval") {
+			return "'1 day'"
+		}
+		if strings.HasPrefix(strings.ToLower(dataType), "time") && !strings.HasPrefix(strings.ToLower(dataType), "timestamp") {
+			return "'00:00:00'"
+		}
+		return "'2000-01-01 00:00:00'"
+	case dataTypeFamilyBoolean:
+		return "true"
+	case dataTypeFamilyUUID:
+		return "'00000000-0000-0000-0000-000000000000'"
+	case dataTypeFamilyJSON:
+		return "'{}'"
+	case dataTypeFamilyBinary:
+		return "''"
+	}
+	// an untyped string literal takes the type of what it's compared with
+	return "'1'"
+}
@@ -0,0 +1,158 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferParameters(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "mysql placeholders",
+			query: "select * from users u join orders o on o.user_id = u.id where u.email = ? and o.status in (?, ?) limit ?",
+			want:  []string{"?1: users.email (varchar)", "?2: orders.status (varchar)", "?3: orders.status (varchar)", "?4: bigint"},
+		},
+		{
+			name:  "postgres placeholders used twice",
+			query: "select * from orders where user_id = $1 or id = $1 and status = $2",
+			want:  []string{"$1: orders.user_id (int)", "$2: orders.status (varchar)"},
+		},
+		{
+			name:  "reversed comparison and between",
+			query: "select * from orders where $1 = status and id between $2 and $3",
+			want:  []string{"$1: orders.status (varchar)", "$2: orders.id (int)", "$3: orders.id (int)"},
+		},
+		{
+			name:  "casts",
+			query: "select * from orders where status = $1::text and id > $2::bigint",
+			want:  []string{"$1: orders.status (varchar), cast to text", "$2: orders.id (int), cast to bigint"},
+		},
+		{
+			name:  "update",
+			query: "update orders set status = ? where id = ?",
+			want:  []string{"?1: orders.status (varchar)", "?2: orders.id (int)"},
+		},
+		{
+			name:  "insert",
+			query: "insert into users (email, name) values ($1, $2)",
+			want:  []string{"$1: users.email (varchar)", "$2: users.name (varchar)"},
+		},
+		{
+			name:  "named parameter in an expression",
+			query: "select * from orders where id + 1 = :next",
+			want:  []string{":next: unknown type"},
+		},
+		{
+			name:  "no parameters",
+			query: "select * from orders where id = 1",
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parameters, err := InferParameters(tt.query, testSchema())
+			require.NoError(t, err)
+
+			got := []string{}
+			for _, parameter := range parameters {
+				got = append(got, parameter.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBindParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "question marks",
+			query:  "select * from orders where id = ? and status = ?",
+			values: []string{"1", "'open'"},
+			want:   "select * from orders where id = 1 and status = 'open'",
+		},
+		{
+			name:   "positional parameters keep their casts",
+			query:  "select * from orders where status = $2::text and (id = $1 or user_id = $1)",
+			values: []string{"7", "'open'"},
+			want:   "select * from orders where status = 'open'::text and (id = 7 or user_id = 7)",
+		},
+		{
+			name:    "too few values",
+			query:   "select * from orders where id = $1 and status = $2",
+			values:  []string{"1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BindParameters(tt.query, tt.values)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParameterLiteral(t *testing.T) {
+	assert.Equal(t, "42", ParameterLiteral("42"))
+	assert.Equal(t, "-1.5", ParameterLiteral("-1.5"))
+	assert.Equal(t, "NULL", ParameterLiteral("NULL"))
+	assert.Equal(t, "'open'", ParameterLiteral("open"))
+	assert.Equal(t, "'open'", ParameterLiteral("'open'"))
+	assert.Equal(t, "'it''s'", ParameterLiteral("it's"))
+}
+
+func TestScanComparisonTypesForIssues(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "string column compared with a number", query: "select * from orders where status = 1", want: 1},
+		{name: "string column in a list of numbers", query: "select * from orders o where o.user_ref in (1, 2)", want: 1},
+		{name: "number column compared with a number string", query: "select * from orders where id = '1'", want: 0},
+		{name: "number column compared with text", query: "select * from orders where id = 'abc'", want: 1},
+		{name: "parameter cast to another type", query: "select * from orders where user_id = $1::text", want: 1},
+		{name: "parameter cast to the same type", query: "select * from orders where user_id = $1::bigint", want: 0},
+		{name: "matching types", query: "select * from orders where status = 'open' and id between 1 and 10", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryIssues, err := ScanComparisonTypesForIssues(tt.query, testSchema(), EnginePostgres)
+			require.NoError(t, err)
+			assert.Len(t, queryIssues, tt.want)
+		})
+	}
+}
+
+func TestScanComparisonTypesForIssues_engineWording(t *testing.T) {
+	query := "select * from orders where status = 1"
+
+	queryIssues, err := ScanComparisonTypesForIssues(query, testSchema(), EngineMySQL)
+	require.NoError(t, err)
+	require.Len(t, queryIssues, 1)
+	assert.Equal(t, "orders.status (varchar) is compared with the number 1; MySQL converts the column to a number for every row, so its index can't be used. Quote the value", queryIssues[0].Message)
+
+	queryIssues, err = ScanComparisonTypesForIssues(query, testSchema(), EnginePostgres)
+	require.NoError(t, err)
+	require.Len(t, queryIssues, 1)
+	assert.Equal(t, "orders.status (varchar) is compared with the number 1; Postgres has no operator to compare them and fails the query. Quote the value", queryIssues[0].Message)
+}
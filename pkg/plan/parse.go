@@ -0,0 +1,53 @@
+package plan
+
+import (
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// Parse parses a query after setting aside the syntax the vitess parser
+// doesn't support, such as a leading WITH clause, a Postgres ON CONFLICT
+// clause or a FOR SHARE locking clause. The statement is only meant to tell
+// what kind of query this is; the Scan functions parse the query themselves.
+func Parse(query string) (sqlparser.Statement, error) {
+	_, body, err := SplitCommonTableExpressions(query)
+	if err != nil {
+		return nil, err
+	}
+
+	_, body, err = SplitOnConflict(body)
+	if err != nil {
+		return nil, err
+	}
+
+	_, body = SplitLockingClause(body)
+
+	return parseStatement(body)
+}
+
+// parseStatement parses a statement after rewriting the Postgres syntax for
+// writes and parameters that has a MySQL equivalent, and dropping a RETURNING
+// clause and the casts of parameters.
+func parseStatement(query string) (sqlparser.Statement, error) {
+	return sqlparser.Parse(stripReturning(rewriteWriteJoins(rewritePositionalParameters(stripParameterCasts(query)))))
+}
+
+// rewritePositionalParameters rewrites Postgres $1 parameters, which is also
+// how pg_stat_statements writes constants, as the :v1 bind variables the
+// parser understands.
+func rewritePositionalParameters(query string) string {
+	var b strings.Builder
+	last := 0
+	for _, token := range lexer.Tokenize(query) {
+		if token.Type != lexer.Placeholder || !strings.HasPrefix(token.Value, "$") {
+			continue
+		}
+		b.WriteString(query[last:token.Pos])
+		b.WriteString(":v" + token.Value[1:])
+		last = token.End()
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
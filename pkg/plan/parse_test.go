@@ -0,0 +1,28 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rewritePositionalParameters(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			query: "select * from users where id = $1 and email in ($2, $3)",
+			want:  "select * from users where id = :v1 and email in (:v2, :v3)",
+		},
+		{
+			query: "select '$1', $$ $2 $$ from users where id = ?",
+			want:  "select '$1', $$ $2 $$ from users where id = ?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, rewritePositionalParameters(tt.query))
+		})
+	}
+}
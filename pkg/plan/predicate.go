@@ -0,0 +1,203 @@
+package plan
+
+import (
+	"fmt"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+)
+
+// scope holds the tables visible to one SELECT. Subqueries get their own scope
+// with the enclosing query as the parent, so a column that isn't found in the
+// subquery's tables resolves against the outer query.
+type scope struct {
+	parent           *scope
+	tableAliasLookup map[string]string
+	tableNames       []string
+}
+
+func newScope(parent *scope, tableAliasLookup map[string]string, tableNames []string) *scope {
+	return &scope{
+		parent:           parent,
+		tableAliasLookup: tableAliasLookup,
+		tableNames:       tableNames,
+	}
+}
+
+func (s *scope) resolveColumn(qualifier string, column string, tables []dbtypes.Table) (string, error) {
+	tableName, err := s.resolveLocalColumn(qualifier, column, tables)
+	if err == nil {
+		return tableName, nil
+	}
+
+	if s.parent != nil {
+		if tableName, parentErr := s.parent.resolveColumn(qualifier, column, tables); parentErr == nil {
+			return tableName, nil
+		}
+	}
+
+	return "", err
+}
+
+// resolveLocalColumn resolves a column against the tables of this scope only,
+// without falling back to the enclosing query.
+func (s *scope) resolveLocalColumn(qualifier string, column string, tables []dbtypes.Table) (string, error) {
+	return resolveColumnTable(s.tableNames, qualifier, column, s.tableAliasLookup, tables)
+}
+
+// walkPredicate descends through a predicate expression and records every
+// column an index could serve in where, keyed by the table the column belongs
+// to. Columns passed to a function aren't recorded, see functionColumns, and
+// neither are the columns of subqueries, which are planned in their own scope.
+func walkPredicate(expr sqlparser.Expr, s *scope, tables []dbtypes.Table, where map[string][]string) error {
+	switch expr := expr.(type) {
+	case nil:
+		return nil
+
+	case *sqlparser.ColName:
+		qualifier := expr.Qualifier.Name.String()
+		column := expr.Name.String()
+		// a column that isn't in the schema, or of a table that isn't, is
+		// unknown, and no index is known to serve it either. A reference to
+		// the enclosing query isn't a filter of this one: the correlated
+		// subquery rules look at it.
+		tableName, err := s.resolveLocalColumn(qualifier, column, tables)
+		if err != nil {
+			return nil
+		}
+		if tableName != "" && !sliceContains(where[tableName], column) {
+			where[tableName] = append(where[tableName], column)
+		}
+
+	case *sqlparser.ComparisonExpr:
+		if err := walkPredicate(expr.Left, s, tables, where); err != nil {
+			return fmt.Errorf("comparison (left): %w", err)
+		}
+		if err := walkPredicate(expr.Right, s, tables, where); err != nil {
+			return fmt.Errorf("comparison (right): %w", err)
+		}
+
+	case *sqlparser.AndExpr:
+		if err := walkPredicate(expr.Left, s, tables, where); err != nil {
+			return fmt.Errorf("and (left): %w", err)
+		}
+		if err := walkPredicate(expr.Right, s, tables, where); err != nil {
+			return fmt.Errorf("and (right): %w", err)
+		}
+
+	case *sqlparser.OrExpr:
+		if err := walkPredicate(expr.Left, s, tables, where); err != nil {
+			return fmt.Errorf("or (left): %w", err)
+		}
+		if err := walkPredicate(expr.Right, s, tables, where); err != nil {
+			return fmt.Errorf("or (right): %w", err)
+		}
+
+	case *sqlparser.NotExpr:
+		return walkPredicate(expr.Expr, s, tables, where)
+
+	case *sqlparser.ParenExpr:
+		return walkPredicate(expr.Expr, s, tables, where)
+
+	case *sqlparser.RangeCond:
+		for _, operand := range []sqlparser.Expr{expr.Left, expr.From, expr.To} {
+			if err := walkPredicate(operand, s, tables, where); err != nil {
+				return fmt.Errorf("range: %w", err)
+			}
+		}
+
+	case *sqlparser.IsExpr:
+		return walkPredicate(expr.Expr, s, tables, where)
+
+	case sqlparser.ValTuple:
+		for _, value := range expr {
+			if err := walkPredicate(value, s, tables, where); err != nil {
+				return fmt.Errorf("tuple: %w", err)
+			}
+		}
+
+	case *sqlparser.BinaryExpr:
+		if err := walkPredicate(expr.Left, s, tables, where); err != nil {
+			return err
+		}
+		return walkPredicate(expr.Right, s, tables, where)
+
+	case *sqlparser.UnaryExpr:
+		return walkPredicate(expr.Expr, s, tables, where)
+
+	case *sqlparser.CollateExpr:
+		return walkPredicate(expr.Expr, s, tables, where)
+
+	case *sqlparser.ConvertExpr:
+		return walkPredicate(expr.Expr, s, tables, where)
+
+	case *sqlparser.CaseExpr:
+		if err := walkPredicate(expr.Expr, s, tables, where); err != nil {
+			return err
+		}
+		for _, when := range expr.Whens {
+			if err := walkPredicate(when.Cond, s, tables, where); err != nil {
+				return err
+			}
+			if err := walkPredicate(when.Val, s, tables, where); err != nil {
+				return err
+			}
+		}
+		return walkPredicate(expr.Else, s, tables, where)
+	}
+
+	return nil
+}
+
+// functionColumns returns the columns a predicate passes to a function, such
+// as lower(email), keyed by table. An index on the column can't serve them.
+func functionColumns(expr sqlparser.Expr, s *scope, tables []dbtypes.Table) map[string][]string {
+	columns := map[string][]string{}
+	if expr == nil {
+		return columns
+	}
+
+	record := func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, nil
+		case *sqlparser.ColName:
+			tableName, err := s.resolveLocalColumn(node.Qualifier.Name.String(), node.Name.String(), tables)
+			if err == nil && tableName != "" {
+				columns[tableName] = appendIfMissing(columns[tableName], node.Name.String())
+			}
+		}
+		return true, nil
+	}
+
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, nil
+		case *sqlparser.FuncExpr:
+			_ = sqlparser.Walk(record, node.Exprs)
+			return false, nil
+		}
+		return true, nil
+	}, expr)
+
+	return columns
+}
+
+// hasUnresolvedColumn returns true if a predicate names a column, outside of
+// its subqueries, that isn't in the schema.
+func hasUnresolvedColumn(expr sqlparser.Expr, s *scope, tables []dbtypes.Table) bool {
+	unresolved := false
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, nil
+		case *sqlparser.ColName:
+			if _, err := s.resolveColumn(node.Qualifier.Name.String(), node.Name.String(), tables); err != nil {
+				unresolved = true
+			}
+		}
+		return true, nil
+	}, expr)
+	return unresolved
+}
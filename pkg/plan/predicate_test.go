@@ -0,0 +1,132 @@
+package plan
+
+import (
+	"testing"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_processWhereClause(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		want      map[string][]string
+		functions map[string][]string
+	}{
+		{
+			name:  "and / or",
+			query: "select * from users where email = 'a' or (name = 'b' and id > 1)",
+			want:  map[string][]string{"users": {"email", "name", "id"}},
+		},
+		{
+			name:  "between, is null, not",
+			query: "select * from orders where not (user_id between 1 and 10) and status is null",
+			want:  map[string][]string{"orders": {"user_id", "status"}},
+		},
+		{
+			name:  "in tuple",
+			query: "select * from orders o where o.status in ('new', 'paid')",
+			want:  map[string][]string{"orders": {"status"}},
+		},
+		{
+			name:  "in subquery",
+			query: "select * from users where id in (select user_id from orders where status = 'new')",
+			want:  map[string][]string{"users": {"id"}},
+		},
+		{
+			name:  "correlated exists",
+			query: "select * from users u where exists (select 1 from orders o where o.user_id = u.id)",
+			want:  map[string][]string{},
+		},
+		{
+			name:      "function argument",
+			query:     "select * from users where lower(email) = 'a'",
+			want:      map[string][]string{},
+			functions: map[string][]string{"users": {"email"}},
+		},
+		{
+			name:      "nested function and a plain comparison",
+			query:     "select * from users where lower(trim(name)) = 'a' and id = 1",
+			want:      map[string][]string{"users": {"id"}},
+			functions: map[string][]string{"users": {"name"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selectStatement, err := parseSelectStatement(tt.query, testSchema())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, selectStatement.Where)
+			if tt.functions != nil {
+				assert.Equal(t, tt.functions, selectStatement.Functions)
+			}
+		})
+	}
+}
+
+func TestScanSelectStatementForIssues_disjunctions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "every branch indexed",
+			query: "select * from users where id = 1 or email = 'a'",
+			want:  []string{},
+		},
+		{
+			name:  "one branch unindexed",
+			query: "select * from users where id = 1 or name = 'a'",
+			want:  []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex, issuetypes.QueryIssueTypeOrPredicateMissingIndex},
+		},
+		{
+			name:  "same column",
+			query: "select * from users where name = 'a' or name = 'b'",
+			want:  []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScanSelectStatementForIssues(tt.query, testSchema())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, issueTypes(got))
+		})
+	}
+}
+
+func TestScanSelectStatementForIssues_functions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "indexed column in a function",
+			query: "select * from users where lower(email) = 'a'",
+			want:  []string{"where clause passes users.email to a function, so its index can't be used; compare the column itself, or index the expression"},
+		},
+		{
+			name:  "unindexed column in a function",
+			query: "select * from users where lower(name) = 'a'",
+			want:  []string{"where clause contains a column that is not indexed: users.name"},
+		},
+		{
+			name:  "column also compared itself",
+			query: "select * from users where email = 'a' and lower(email) = 'a'",
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScanSelectStatementForIssues(tt.query, testSchema())
+			require.NoError(t, err)
+			messages := []string{}
+			for _, issue := range got {
+				messages = append(messages, issue.Message)
+			}
+			assert.Equal(t, tt.want, messages)
+		})
+	}
+}
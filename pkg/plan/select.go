@@ -13,35 +13,130 @@ type SelectStatement struct {
 	Columns map[string][]string
 	Tables  []string
 	Where   map[string][]string
-	Join    map[string][]string
+	// Functions are the columns the where clause passes to a function
+	Functions map[string][]string
+	Join      map[string][]string
+
+	joinGraph *joinGraph
+	scope     *scope
 }
 
 type Index struct {
+	Name         string
 	Columns      []string
 	IsPrimaryKey bool
 	IsUnique     bool
 }
 
 func ScanSelectStatementForIssues(query string, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error) {
-	selectStatement, err := parseSelectStatement(query, tables)
+	ctes, body, err := SplitCommonTableExpressions(query)
+	if err != nil {
+		return nil, fmt.Errorf("split common table expressions: %w", err)
+	}
+
+	// indexes only come from the schema, derived tables and CTEs are never indexed
+	indexes := indexesByTable(tables)
+
+	queryIssues, tables, err := scanCommonTableExpressions(ctes, tables, indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	_, body = SplitLockingClause(body)
+
+	stmt, err := parseStatement(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse select statement: %w", err)
+	}
+
+	selectStmt, ok := stmt.(*sqlparser.Select)
+	if !ok {
+		return nil, fmt.Errorf("expected select statement, got %T", stmt)
+	}
+
+	issues, err := scanSelect(selectStmt, nil, tables, indexes)
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	return dedupeIssues(queryIssues), nil
+}
+
+// scanSelectStatement scans any select statement, including the sides of a
+// UNION.
+func scanSelectStatement(stmt sqlparser.SelectStatement, parent *scope, tables []dbtypes.Table, indexes map[string][]Index) ([]issuetypes.QueryIssue, error) {
+	switch stmt := stmt.(type) {
+	case *sqlparser.Select:
+		return scanSelect(stmt, parent, tables, indexes)
+	case *sqlparser.ParenSelect:
+		return scanSelectStatement(stmt.Select, parent, tables, indexes)
+	case *sqlparser.Union:
+		left, err := scanSelectStatement(stmt.Left, parent, tables, indexes)
+		if err != nil {
+			return nil, err
+		}
+		right, err := scanSelectStatement(stmt.Right, parent, tables, indexes)
+		if err != nil {
+			return nil, err
+		}
+		return append(left, right...), nil
+	}
+
+	return nil, nil
+}
+
+func scanSelect(selectStmt *sqlparser.Select, parent *scope, tables []dbtypes.Table, indexes map[string][]Index) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	// derived tables are planned on their own, and then behave like a table
+	// without indexes in this query
+	derivedIssues, tables, err := scanDerivedTables(selectStmt.From, tables, indexes)
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, derivedIssues...)
+
+	selectStatement, err := parseSelect(selectStmt, parent, tables)
 	if err != nil {
 		return nil, err
 	}
 
 	if selectStatement == nil {
-		return nil, nil
+		return queryIssues, nil
 	}
 
-	issues, err := scanSelectStatementForMissingIndexes(selectStatement, indexesByTable(tables))
+	issues, err := scanSelectStatementForMissingIndexes(selectStatement, indexes)
 	if err != nil {
 		return nil, err
 	}
+	queryIssues = append(queryIssues, issues...)
 
-	return issues, nil
+	issues, err = scanJoinGraphForIssues(selectStatement.joinGraph, indexes, tables)
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	if selectStmt.Where != nil {
+		issues, err = scanDisjunctionsForIssues(selectStmt.Where.Expr, selectStatement.scope, tables, indexes)
+		if err != nil {
+			return nil, err
+		}
+		queryIssues = append(queryIssues, issues...)
+	}
+
+	issues, err = scanSubqueriesForIssues(selectStmt, selectStatement.scope, tables, indexes)
+	if err != nil {
+		return nil, err
+	}
+	queryIssues = append(queryIssues, issues...)
+
+	return queryIssues, nil
 }
 
 func parseSelectStatement(query string, tables []dbtypes.Table) (*SelectStatement, error) {
-	stmt, err := sqlparser.Parse(query)
+	stmt, err := parseStatement(query)
 	if err != nil {
 		return nil, fmt.Errorf("parse select statement: %w", err)
 	}
@@ -51,6 +146,10 @@ func parseSelectStatement(query string, tables []dbtypes.Table) (*SelectStatemen
 		return nil, fmt.Errorf("expected select statement, got %T", stmt)
 	}
 
+	return parseSelect(selectStmt, nil, tables)
+}
+
+func parseSelect(selectStmt *sqlparser.Select, parent *scope, tables []dbtypes.Table) (*SelectStatement, error) {
 	// Check if the query is from information_schema
 	if selectStmt.From != nil {
 		for _, tableExpr := range selectStmt.From {
@@ -62,7 +161,7 @@ func parseSelectStatement(query string, tables []dbtypes.Table) (*SelectStatemen
 						return nil, nil
 					}
 				case *sqlparser.Subquery:
-					// parse this subquery
+					// derived tables are scanned by scanDerivedTables
 				default:
 					fmt.Printf("Unexpected type: %T\n", expr)
 				}
@@ -71,10 +170,11 @@ func parseSelectStatement(query string, tables []dbtypes.Table) (*SelectStatemen
 	}
 
 	result := SelectStatement{
-		Columns: map[string][]string{},
-		Tables:  []string{},
-		Where:   map[string][]string{},
-		Join:    map[string][]string{},
+		Columns:   map[string][]string{},
+		Tables:    []string{},
+		Where:     map[string][]string{},
+		Functions: map[string][]string{},
+		Join:      map[string][]string{},
 	}
 
 	tableAliasLookup, tableNames, err := extractTables(selectStmt)
@@ -83,6 +183,7 @@ func parseSelectStatement(query string, tables []dbtypes.Table) (*SelectStatemen
 	}
 
 	result.Tables = tableNames
+	result.scope = newScope(parent, tableAliasLookup, tableNames)
 
 	err = processSelectExpressions(selectStmt, tableAliasLookup, tables, &result)
 	if err != nil {
@@ -93,16 +194,27 @@ func parseSelectStatement(query string, tables []dbtypes.Table) (*SelectStatemen
 		return nil, fmt.Errorf("process join clauses: %w", err)
 	}
 
+	var whereExpr sqlparser.Expr
 	if selectStmt.Where != nil {
-		if err := processWhereClause(selectStmt.Where.Expr, tableAliasLookup, tables, &result); err != nil {
+		whereExpr = selectStmt.Where.Expr
+		if err := processWhereClause(selectStmt.Where.Expr, result.scope, tables, &result); err != nil {
 			return nil, fmt.Errorf("process where clause: %w", err)
 		}
 	}
 
+	result.joinGraph = buildJoinGraph(selectStmt.From, whereExpr, tables)
+
 	return &result, nil
 }
 
 func extractTables(selectStmt *sqlparser.Select) (map[string]string, []string, error) {
+	return extractTableExprs(selectStmt.From)
+}
+
+// extractTableExprs returns the alias lookup and the table names referenced by
+// a list of table expressions, such as a FROM clause or the tables of an
+// UPDATE.
+func extractTableExprs(tableExprs sqlparser.TableExprs) (map[string]string, []string, error) {
 	tableAliasLookup := make(map[string]string)
 	tables := make([]string, 0)
 
@@ -117,6 +229,9 @@ func extractTables(selectStmt *sqlparser.Select) (map[string]string, []string, e
 				if tbl.Qualifier.String() != "" {
 					fullTableName = tbl.Qualifier.String() + "." + fullTableName
 				}
+			} else if _, ok := node.Expr.(*sqlparser.Subquery); ok && !node.As.IsEmpty() {
+				// derived tables are known by their alias
+				fullTableName = node.As.String()
 			} else {
 				// Fallback for other expressions, if necessary
 				fullTableName = sqlparser.String(node.Expr)
@@ -132,6 +247,9 @@ func extractTables(selectStmt *sqlparser.Select) (map[string]string, []string, e
 				tables = append(tables, fullTableName)
 			}
 
+			// don't descend into derived tables, they have their own scope
+			return false, nil
+
 		case *sqlparser.JoinTableExpr:
 			// Recursively extract tables from the left and right expressions of the join
 			if _, err := extractFunc(node.LeftExpr); err != nil {
@@ -144,7 +262,7 @@ func extractTables(selectStmt *sqlparser.Select) (map[string]string, []string, e
 		return true, nil
 	}
 
-	if err := sqlparser.Walk(extractFunc, selectStmt.From); err != nil {
+	if err := sqlparser.Walk(extractFunc, tableExprs); err != nil {
 		return nil, nil, fmt.Errorf("walk: %w", err)
 	}
 
@@ -170,41 +288,36 @@ func scanSelectStatementForMissingIndexes(selectStatement *SelectStatement, inde
 			continue
 		}
 
-		for _, index := range indexesByTable[table] {
-			for _, column := range columns {
-				if !contains(index.Columns, column) {
-					columnNames := ""
-					columnNames += fmt.Sprintf(`From the %q" table: %s`, table, strings.Join(selectStatement.Columns[table], ", "))
-
-					queryIssues = append(queryIssues, issuetypes.QueryIssue{
-						IssueSeverity: issuetypes.IssueSeverityLow,
-						IssueType:     issuetypes.QueryIssueTypeWhereClauseMissingIndex,
-						Message:       "where clause contains a column that is not indexed",
-					})
-				}
-			}
+		for _, column := range unindexedColumns(indexesByTable[table], columns) {
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityLow,
+				IssueType:     issuetypes.QueryIssueTypeWhereClauseMissingIndex,
+				Message:       fmt.Sprintf("where clause contains a column that is not indexed: %s.%s", table, column),
+			})
 		}
 	}
 
-	// check if the join clause contains a column that is not indexed
-	for table, columns := range selectStatement.Join {
+	// a column passed to a function can't be looked up in an index on the
+	// column, unless the where clause also compares the column itself
+	for table, columns := range selectStatement.Functions {
 		if _, exists := indexesByTable[table]; !exists {
 			continue
 		}
 
-		for _, index := range indexesByTable[table] {
-			for _, column := range columns {
-				if !contains(index.Columns, column) {
-					columnNames := ""
-					columnNames += fmt.Sprintf(`From the %q" table: %s`, table, strings.Join(selectStatement.Columns[table], ", "))
-
-					queryIssues = append(queryIssues, issuetypes.QueryIssue{
-						IssueSeverity: issuetypes.IssueSeverityLow,
-						IssueType:     issuetypes.QueryIssueTypeClauseMissingIndex,
-						Message:       "join clause contains a column that is not indexed",
-					})
-				}
+		for _, column := range columns {
+			if contains(selectStatement.Where[table], column) {
+				continue
+			}
+
+			message := fmt.Sprintf("where clause contains a column that is not indexed: %s.%s", table, column)
+			if isColumnIndexed(indexesByTable[table], column) {
+				message = fmt.Sprintf("where clause passes %s.%s to a function, so its index can't be used; compare the column itself, or index the expression", table, column)
 			}
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityLow,
+				IssueType:     issuetypes.QueryIssueTypeWhereClauseMissingIndex,
+				Message:       message,
+			})
 		}
 	}
 
@@ -222,17 +335,49 @@ func indexesByTable(tables []dbtypes.Table) map[string][]Index {
 		})
 
 		// other indexes
-		// for _, index := range table.Indexes {
-		// 	indexesByTable[table.TableName] = append(indexesByTable[table.TableName], Index{
-		// 		Columns:      index.Columns,
-		// 		IsPrimaryKey: false,
-		// 		IsUnique:     index.IsUnique,
-		// 	})
+		for _, index := range table.GetIndexes() {
+			indexesByTable[table.GetName()] = append(indexesByTable[table.GetName()], Index{
+				Name:         index.Name,
+				Columns:      index.Columns,
+				IsPrimaryKey: false,
+				IsUnique:     index.IsUnique,
+			})
+		}
 	}
 
 	return indexesByTable
 }
 
+// unindexedColumns returns the columns that can't be served by any index. A
+// column is only usable in an index when every column ahead of it in that
+// index is also part of the predicate (the leftmost prefix rule).
+func unindexedColumns(indexes []Index, columns []string) []string {
+	covered := map[string]bool{}
+	for _, index := range indexes {
+		for _, indexColumn := range index.Columns {
+			if !contains(columns, indexColumn) {
+				break
+			}
+			covered[indexColumn] = true
+		}
+	}
+
+	unindexed := []string{}
+	for _, column := range columns {
+		if !covered[column] {
+			unindexed = append(unindexed, column)
+		}
+	}
+
+	return unindexed
+}
+
+// isColumnIndexed returns true when the column is the leading column of at
+// least one index.
+func isColumnIndexed(indexes []Index, column string) bool {
+	return len(unindexedColumns(indexes, []string{column})) == 0
+}
+
 func processSelectExpressions(selectStmt *sqlparser.Select, tableAliasLookup map[string]string, tables []dbtypes.Table, result *SelectStatement) error {
 	for _, selectExpr := range selectStmt.SelectExprs {
 		switch expr := selectExpr.(type) {
@@ -263,11 +408,6 @@ func processSelectExpressions(selectStmt *sqlparser.Select, tableAliasLookup map
 		}
 	}
 
-	if selectStmt.Where != nil {
-		if err := processWhereClause(selectStmt.Where.Expr, tableAliasLookup, tables, result); err != nil {
-			return fmt.Errorf("process where clause: %w", err)
-		}
-	}
 	return nil
 }
 
@@ -330,54 +470,9 @@ func columnNamesForTable(tableName string, tables []dbtypes.Table) []string {
 	return nil
 }
 
-func processWhereClause(whereExpr sqlparser.Expr, tableAliasLookup map[string]string, tables []dbtypes.Table, result *SelectStatement) error {
-	// This function will need to recursively process the WHERE clause expression tree
-	// and extract column names used in the expressions.
-	switch expr := whereExpr.(type) {
-	case *sqlparser.ColName:
-		qualifier := expr.Qualifier.Name.String()
-		column := expr.Name.String()
-		tableName, err := resolveColumnTable(result.Tables, qualifier, column, tableAliasLookup, tables)
-		if err != nil {
-			return fmt.Errorf("resolve column table: %w", err)
-		}
-		if tableName != "" {
-			// Check if column already exists for the table
-			if !sliceContains(result.Where[tableName], column) {
-				result.Where[tableName] = append(result.Where[tableName], column)
-			}
-		}
-	case *sqlparser.ComparisonExpr:
-		// Handle comparison expressions
-		if err := processWhereClause(expr.Left, tableAliasLookup, tables, result); err != nil {
-			return fmt.Errorf("process where clause (left): %w", err)
-		}
-		if err := processWhereClause(expr.Right, tableAliasLookup, tables, result); err != nil {
-			return fmt.Errorf("process where clause (right): %w", err)
-		}
-
-	case *sqlparser.AndExpr, *sqlparser.OrExpr:
-		// Handle AND, OR expressions (they have the same structure)
-		if binaryExpr, ok := expr.(*sqlparser.BinaryExpr); ok {
-			if err := processWhereClause(binaryExpr.Left, tableAliasLookup, tables, result); err != nil {
-				return fmt.Errorf("process where clause (and/or left): %w", err)
-			}
-			if err := processWhereClause(binaryExpr.Right, tableAliasLookup, tables, result); err != nil {
-				return fmt.Errorf("process where clause (and/or right): %w", err)
-			}
-		}
-
-	case *sqlparser.ParenExpr:
-		// Handle parenthesized expressions
-		if err := processWhereClause(expr.Expr, tableAliasLookup, tables, result); err != nil {
-			return fmt.Errorf("process where clause (paren): %w", err)
-		}
-
-	default:
-		// Handle other types of expressions (subqueries, functions, etc.)
-	}
-
-	return nil
+func processWhereClause(whereExpr sqlparser.Expr, s *scope, tables []dbtypes.Table, result *SelectStatement) error {
+	result.Functions = functionColumns(whereExpr, s, tables)
+	return walkPredicate(whereExpr, s, tables, result.Where)
 }
 
 func processJoinClauses(tableExprs sqlparser.TableExprs, tableAliasLookup map[string]string, result *SelectStatement) error {
@@ -411,7 +506,22 @@ func extractJoinColumns(onExpr sqlparser.Expr, tableAliasLookup map[string]strin
 		if err := extractColumnFromExpr(expr.Right, tableAliasLookup, result); err != nil {
 			return err
 		}
-		// Handle other cases as needed
+	case *sqlparser.AndExpr:
+		if err := extractJoinColumns(expr.Left, tableAliasLookup, result); err != nil {
+			return err
+		}
+		if err := extractJoinColumns(expr.Right, tableAliasLookup, result); err != nil {
+			return err
+		}
+	case *sqlparser.OrExpr:
+		if err := extractJoinColumns(expr.Left, tableAliasLookup, result); err != nil {
+			return err
+		}
+		if err := extractJoinColumns(expr.Right, tableAliasLookup, result); err != nil {
+			return err
+		}
+	case *sqlparser.ParenExpr:
+		return extractJoinColumns(expr.Expr, tableAliasLookup, result)
 	}
 	return nil
 }
@@ -420,6 +530,10 @@ func extractColumnFromExpr(expr sqlparser.Expr, tableAliasLookup map[string]stri
 	if colExpr, ok := expr.(*sqlparser.ColName); ok {
 		// Use the Qualifier directly as it is already a sqlparser.TableName
 		tableName := colExpr.Qualifier
+		if tableName.IsEmpty() && len(result.Tables) > 1 {
+			// unqualified columns are attributed by the join graph instead
+			return nil
+		}
 
 		resolvedTableName, err := resolveTableName(tableName, tableAliasLookup, len(result.Tables))
 		if err != nil {
@@ -0,0 +1,370 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+)
+
+var _ dbtypes.Table = derivedTable{}
+
+// derivedTable is the result of a subquery in FROM or a CTE. It has columns
+// but never has indexes.
+type derivedTable struct {
+	name    string
+	columns []string
+}
+
+func (t derivedTable) GetName() string {
+	return t.name
+}
+
+func (t derivedTable) GetColumns() []dbtypes.Column {
+	var cols []dbtypes.Column
+	for _, c := range t.columns {
+		cols = append(cols, derivedColumn{name: c})
+	}
+	return cols
+}
+
+func (t derivedTable) GetPrimaryKeys() []string {
+	return nil
+}
+
+func (t derivedTable) GetIndexes() []dbtypes.Index {
+	return nil
+}
+
+func (t derivedTable) GetForeignKeys() []dbtypes.ForeignKey {
+	return nil
+}
+
+func (t derivedTable) GetEstimatedRowCount() int64 {
+	return 0
+}
+
+var _ dbtypes.Column = derivedColumn{}
+
+type derivedColumn struct {
+	name string
+}
+
+func (c derivedColumn) GetName() string           { return c.name }
+func (c derivedColumn) GetDataType() string       { return "" }
+func (c derivedColumn) GetColumnType() string     { return "" }
+func (c derivedColumn) GetIsNullable() bool       { return true }
+func (c derivedColumn) GetColumnKey() string      { return "" }
+func (c derivedColumn) GetColumnDefault() *string { return nil }
+func (c derivedColumn) GetExtra() string          { return "" }
+
+// withTable returns a copy of tables with table added, leaving the caller's
+// slice alone.
+func withTable(tables []dbtypes.Table, table dbtypes.Table) []dbtypes.Table {
+	result := make([]dbtypes.Table, 0, len(tables)+1)
+	result = append(result, tables...)
+	return append(result, table)
+}
+
+// scanDerivedTables scans every subquery in a FROM clause and returns the
+// tables extended with the derived tables they produce.
+func scanDerivedTables(from sqlparser.TableExprs, tables []dbtypes.Table, indexes map[string][]Index) ([]issuetypes.QueryIssue, []dbtypes.Table, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	var scanTableExpr func(tableExpr sqlparser.TableExpr) error
+	scanTableExpr = func(tableExpr sqlparser.TableExpr) error {
+		switch expr := tableExpr.(type) {
+		case *sqlparser.AliasedTableExpr:
+			subquery, ok := expr.Expr.(*sqlparser.Subquery)
+			if !ok {
+				return nil
+			}
+
+			issues, err := scanSelectStatement(subquery.Select, nil, tables, indexes)
+			if err != nil {
+				return fmt.Errorf("scan derived table %q: %w", expr.As.String(), err)
+			}
+			queryIssues = append(queryIssues, issues...)
+
+			if !expr.As.IsEmpty() {
+				tables = withTable(tables, derivedTable{
+					name:    expr.As.String(),
+					columns: selectOutputColumns(subquery.Select, tables),
+				})
+			}
+
+		case *sqlparser.ParenTableExpr:
+			for _, inner := range expr.Exprs {
+				if err := scanTableExpr(inner); err != nil {
+					return err
+				}
+			}
+
+		case *sqlparser.JoinTableExpr:
+			if err := scanTableExpr(expr.LeftExpr); err != nil {
+				return err
+			}
+			return scanTableExpr(expr.RightExpr)
+		}
+
+		return nil
+	}
+
+	for _, tableExpr := range from {
+		if err := scanTableExpr(tableExpr); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return queryIssues, tables, nil
+}
+
+// selectOutputColumns returns the names of the columns a select statement
+// produces.
+func selectOutputColumns(stmt sqlparser.SelectStatement, tables []dbtypes.Table) []string {
+	switch stmt := stmt.(type) {
+	case *sqlparser.ParenSelect:
+		return selectOutputColumns(stmt.Select, tables)
+	case *sqlparser.Union:
+		return selectOutputColumns(stmt.Left, tables)
+	case *sqlparser.Select:
+		tableAliasLookup, tableNames, err := extractTables(stmt)
+		if err != nil {
+			return nil
+		}
+
+		columns := []string{}
+		for _, selectExpr := range stmt.SelectExprs {
+			switch expr := selectExpr.(type) {
+			case *sqlparser.StarExpr:
+				if expr.TableName.IsEmpty() {
+					for _, tableName := range tableNames {
+						columns = append(columns, columnNamesForTable(tableName, tables)...)
+					}
+				} else if tableName, err := resolveTableName(expr.TableName, tableAliasLookup, len(tableNames)); err == nil {
+					columns = append(columns, columnNamesForTable(tableName, tables)...)
+				}
+			case *sqlparser.AliasedExpr:
+				if !expr.As.IsEmpty() {
+					columns = append(columns, expr.As.String())
+				} else if col, ok := expr.Expr.(*sqlparser.ColName); ok {
+					columns = append(columns, col.Name.String())
+				} else {
+					columns = append(columns, sqlparser.String(expr.Expr))
+				}
+			}
+		}
+		return columns
+	}
+
+	return nil
+}
+
+type subqueryOccurrence struct {
+	subquery *sqlparser.Subquery
+	// kind is "scalar", "exists", sqlparser.InStr or sqlparser.NotInStr
+	kind string
+}
+
+// scanSubqueriesForIssues scans the subqueries in the select list, WHERE and
+// HAVING clauses of a select. Each subquery is planned in its own scope, and
+// checked for references back to the outer query.
+func scanSubqueriesForIssues(selectStmt *sqlparser.Select, outer *scope, tables []dbtypes.Table, indexes map[string][]Index) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	kinds := map[*sqlparser.Subquery]string{}
+	occurrences := []subqueryOccurrence{}
+	visit := func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.ComparisonExpr:
+			if subquery, ok := node.Right.(*sqlparser.Subquery); ok && (node.Operator == sqlparser.InStr || node.Operator == sqlparser.NotInStr) {
+				kinds[subquery] = node.Operator
+			}
+		case *sqlparser.ExistsExpr:
+			kinds[node.Subquery] = "exists"
+		case *sqlparser.Subquery:
+			kind, ok := kinds[node]
+			if !ok {
+				kind = "scalar"
+			}
+			occurrences = append(occurrences, subqueryOccurrence{subquery: node, kind: kind})
+			return false, nil
+		}
+		return true, nil
+	}
+
+	nodes := []sqlparser.SQLNode{selectStmt.SelectExprs}
+	if selectStmt.Where != nil {
+		nodes = append(nodes, selectStmt.Where)
+	}
+	if selectStmt.Having != nil {
+		nodes = append(nodes, selectStmt.Having)
+	}
+	if err := sqlparser.Walk(visit, nodes...); err != nil {
+		return nil, fmt.Errorf("walk subqueries: %w", err)
+	}
+
+	for _, occurrence := range occurrences {
+		issues, err := scanSelectStatement(occurrence.subquery.Select, outer, tables, indexes)
+		if err != nil {
+			return nil, fmt.Errorf("scan subquery: %w", err)
+		}
+		queryIssues = append(queryIssues, issues...)
+
+		innerSelect, ok := occurrence.subquery.Select.(*sqlparser.Select)
+		if !ok {
+			continue
+		}
+
+		issues, err = scanSubqueryForIssues(innerSelect, occurrence.kind, outer, tables, indexes)
+		if err != nil {
+			return nil, err
+		}
+		queryIssues = append(queryIssues, issues...)
+	}
+
+	return queryIssues, nil
+}
+
+func scanSubqueryForIssues(innerSelect *sqlparser.Select, kind string, outer *scope, tables []dbtypes.Table, indexes map[string][]Index) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	tableAliasLookup, tableNames, err := extractTables(innerSelect)
+	if err != nil {
+		return nil, fmt.Errorf("extract subquery tables: %w", err)
+	}
+
+	resolveLocal := func(col *sqlparser.ColName) (string, bool) {
+		tableName, err := resolveColumnTable(tableNames, col.Qualifier.Name.String(), col.Name.String(), tableAliasLookup, tables)
+		return tableName, err == nil
+	}
+	resolveOuter := func(col *sqlparser.ColName) (string, bool) {
+		if outer == nil {
+			return "", false
+		}
+		tableName, err := outer.resolveColumn(col.Qualifier.Name.String(), col.Name.String(), tables)
+		return tableName, err == nil
+	}
+
+	// find the references to the outer query, and the inner columns they are
+	// compared to
+	outerReferences := []string{}
+	innerColumns := []string{}
+	if innerSelect.Where != nil {
+		for _, conjunct := range splitAnd(innerSelect.Where.Expr) {
+			comparison, ok := conjunct.(*sqlparser.ComparisonExpr)
+			if !ok {
+				continue
+			}
+
+			leftCol, leftIsCol := comparison.Left.(*sqlparser.ColName)
+			rightCol, rightIsCol := comparison.Right.(*sqlparser.ColName)
+			if !leftIsCol || !rightIsCol {
+				continue
+			}
+
+			for _, pair := range [][2]*sqlparser.ColName{{leftCol, rightCol}, {rightCol, leftCol}} {
+				innerTable, innerIsLocal := resolveLocal(pair[0])
+				if !innerIsLocal {
+					continue
+				}
+				if _, otherIsLocal := resolveLocal(pair[1]); otherIsLocal {
+					continue
+				}
+				if outerTable, isOuter := resolveOuter(pair[1]); isOuter && comparison.Operator == sqlparser.EqualStr {
+					innerColumns = appendIfMissing(innerColumns, innerTable+"."+pair[0].Name.String())
+					outerReferences = appendIfMissing(outerReferences, outerTable+"."+pair[1].Name.String())
+				}
+			}
+		}
+	}
+
+	// any other reference to the outer query still makes the subquery correlated
+	if err := sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, nil
+		case *sqlparser.AliasedTableExpr:
+			return false, nil
+		case *sqlparser.ColName:
+			if _, isLocal := resolveLocal(node); isLocal {
+				return true, nil
+			}
+			if outerTable, isOuter := resolveOuter(node); isOuter {
+				outerReferences = appendIfMissing(outerReferences, outerTable+"."+node.Name.String())
+			}
+		}
+		return true, nil
+	}, innerSelect); err != nil {
+		return nil, fmt.Errorf("walk subquery: %w", err)
+	}
+
+	if len(outerReferences) > 0 {
+		unindexed := []string{}
+		for _, innerColumn := range innerColumns {
+			parts := strings.SplitN(innerColumn, ".", 2)
+			if _, known := indexes[parts[0]]; known && !isColumnIndexed(indexes[parts[0]], parts[1]) {
+				unindexed = append(unindexed, innerColumn)
+			}
+		}
+
+		// EXISTS and IN subqueries are rewritten into semi joins, so they are
+		// only a problem when the correlated column can't be looked up
+		if kind == "scalar" || len(unindexed) > 0 {
+			severity := issuetypes.IssueSeverityMedium
+			if kind == "scalar" && len(unindexed) > 0 {
+				severity = issuetypes.IssueSeverityHigh
+			}
+
+			message := fmt.Sprintf("correlated subquery on %s is executed once per row of the outer query (it references %s)", strings.Join(tableNames, ", "), strings.Join(outerReferences, ", "))
+			if len(unindexed) > 0 {
+				message += fmt.Sprintf("; %s is not indexed, so every execution scans the table", strings.Join(unindexed, ", "))
+			}
+
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeCorrelatedSubquery,
+				Message:       message,
+			})
+		}
+	}
+
+	// NOT IN (SELECT nullable_column ...) is never true once the subquery
+	// returns a NULL, and it prevents the anti join optimization
+	if kind == sqlparser.NotInStr && len(innerSelect.SelectExprs) == 1 {
+		if aliasedExpr, ok := innerSelect.SelectExprs[0].(*sqlparser.AliasedExpr); ok {
+			if col, ok := aliasedExpr.Expr.(*sqlparser.ColName); ok {
+				if tableName, isLocal := resolveLocal(col); isLocal {
+					column := FindColumn(tables, tableName, col.Name.String())
+					if column != nil && column.GetIsNullable() {
+						queryIssues = append(queryIssues, issuetypes.QueryIssue{
+							IssueSeverity: issuetypes.IssueSeverityMedium,
+							IssueType:     issuetypes.QueryIssueTypeNotInNullable,
+							Message:       fmt.Sprintf("NOT IN compares against the nullable column %s.%s; a single NULL makes the predicate return no rows, use NOT EXISTS instead", tableName, col.Name.String()),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return queryIssues, nil
+}
+
+// dedupeIssues removes issues that were reported more than once, which happens
+// when the same columns are seen from a subquery and from its outer query.
+func dedupeIssues(issues []issuetypes.QueryIssue) []issuetypes.QueryIssue {
+	seen := map[string]bool{}
+	deduped := []issuetypes.QueryIssue{}
+	for _, issue := range issues {
+		key := issue.IssueType + "\x00" + issue.Message
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, issue)
+	}
+	return deduped
+}
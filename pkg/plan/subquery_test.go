@@ -0,0 +1,125 @@
+package plan
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommonTableExpressions(t *testing.T) {
+	ctes, body, err := SplitCommonTableExpressions(`WITH recent (id) AS (SELECT id FROM orders WHERE status = 'new'),
+	big AS MATERIALIZED (SELECT user_id FROM orders)
+	SELECT * FROM recent`)
+	require.NoError(t, err)
+
+	require.Len(t, ctes, 2)
+	assert.Equal(t, "recent", ctes[0].Name)
+	assert.Equal(t, []string{"id"}, ctes[0].Columns)
+	assert.Equal(t, "SELECT id FROM orders WHERE status = 'new'", ctes[0].Query)
+	assert.Equal(t, "big", ctes[1].Name)
+	assert.Equal(t, "materialized", ctes[1].Materialized)
+	assert.Equal(t, "SELECT * FROM recent", body)
+}
+
+func TestScanSelectStatementForIssues_subqueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "scalar correlated subquery",
+			query: "select u.id, (select count(*) from orders o where o.user_id = u.id) from users u",
+			want:  []string{issuetypes.QueryIssueTypeCorrelatedSubquery},
+		},
+		{
+			name:  "exists on an indexed column is a semi join",
+			query: "select * from users u where exists (select 1 from orders o where o.user_id = u.id)",
+			want:  []string{},
+		},
+		{
+			name:  "exists probing an index from an unindexed outer column",
+			query: "select * from orders o where exists (select 1 from users u where u.id = o.status)",
+			want:  []string{},
+		},
+		{
+			name:  "exists on an unindexed column",
+			query: "select * from users u where exists (select 1 from orders o where o.status = u.email)",
+			want:  []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex, issuetypes.QueryIssueTypeCorrelatedSubquery},
+		},
+		{
+			name:  "not in against a nullable column",
+			query: "select * from orders where status not in (select name from users)",
+			want:  []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex, issuetypes.QueryIssueTypeNotInNullable},
+		},
+		{
+			name:  "derived table",
+			query: "select d.total from (select user_id, count(*) as total from orders where status = 'new' group by user_id) as d where d.total > 1",
+			want:  []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+		},
+		{
+			name:  "cte",
+			query: "with recent as (select user_id from orders where status = 'new') select * from users u join recent r on r.user_id = u.id",
+			want:  []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+		},
+		{
+			name:  "recursive cte with declared columns",
+			query: "with recursive t(n) as (select 1 union all select n+1 from t where n < 10) select * from t",
+			want:  []string{},
+		},
+		{
+			name:  "recursive cte without declared columns",
+			query: "with recursive chain as (select id, user_id from orders where id = 1 union all select o.id, o.user_id from orders o join chain c on c.user_id = o.id) select * from chain",
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScanSelectStatementForIssues(tt.query, testSchema())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, issueTypes(got))
+		})
+	}
+}
+
+func TestScanWriteStatementsForIssues_ctes(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		scan  func(query string, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error)
+		want  []string
+	}{
+		{
+			name:  "update",
+			query: "with banned as (select id from users where email = 'a') update orders set status = 'x' where user_id in (select id from banned)",
+			scan: func(query string, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error) {
+				return ScanUpdateStatementForIssues(query, tables, EnginePostgres)
+			},
+			want: []string{},
+		},
+		{
+			name:  "delete",
+			query: "with stale as (select id from orders where status = 'old') delete from orders where id in (select id from stale)",
+			scan: func(query string, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error) {
+				return ScanDeleteStatementForIssues(query, tables, EnginePostgres)
+			},
+			want: []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+		},
+		{
+			name:  "insert",
+			query: "with recent as (select id, user_id from orders where user_id = 1) insert into orders (user_id, user_ref, status) select user_id, 'r', 'copy' from recent",
+			scan:  ScanInsertStatementForIssues,
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.scan(tt.query, testSchema())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, issueTypes(got))
+		})
+	}
+}
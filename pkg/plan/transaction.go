@@ -0,0 +1,289 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+const (
+	TransactionControlBegin    = "begin"
+	TransactionControlCommit   = "commit"
+	TransactionControlRollback = "rollback"
+)
+
+// TransactionControl returns which transaction control statement the query
+// is, or an empty string for any other statement.
+func TransactionControl(query string) string {
+	tokens := significantTokens(lexer.Tokenize(query))
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	switch {
+	case tokens[0].Is("begin"), tokens[0].Is("start") && len(tokens) > 1 && tokens[1].Is("transaction"):
+		return TransactionControlBegin
+	case tokens[0].Is("commit"), tokens[0].Is("end"):
+		return TransactionControlCommit
+	case tokens[0].Is("rollback") && !(len(tokens) > 1 && tokens[1].Is("to")), tokens[0].Is("abort"):
+		return TransactionControlRollback
+	}
+
+	return ""
+}
+
+// GroupTransactions groups a script's statements into transactions. The
+// statements between BEGIN and COMMIT (or ROLLBACK, which holds the same locks
+// until it runs) form one transaction, and every statement outside of a block
+// is a transaction of its own.
+func GroupTransactions(statements []string) ([][]string, error) {
+	transactions := [][]string{}
+
+	var current []string
+	for _, statement := range statements {
+		switch TransactionControl(statement) {
+		case TransactionControlBegin:
+			if current != nil {
+				return nil, fmt.Errorf("BEGIN inside a transaction")
+			}
+			current = []string{}
+		case TransactionControlCommit, TransactionControlRollback:
+			if current == nil {
+				return nil, fmt.Errorf("%s outside of a transaction", strings.ToUpper(TransactionControl(statement)))
+			}
+			if len(current) > 0 {
+				transactions = append(transactions, current)
+			}
+			current = nil
+		default:
+			if current == nil {
+				transactions = append(transactions, []string{statement})
+			} else {
+				current = append(current, statement)
+			}
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("transaction is not committed")
+	}
+
+	return transactions, nil
+}
+
+// LargeTables returns the tables with enough rows that holding locks on them
+// blocks a lot of other work.
+func LargeTables(tables []dbtypes.Table) map[string]bool {
+	large := map[string]bool{}
+	for _, table := range tables {
+		if table.GetEstimatedRowCount() >= largeTableRowCount {
+			large[table.GetName()] = true
+		}
+	}
+	return large
+}
+
+// TransactionPlan is the locks each statement of a transaction takes, in
+// order.
+type TransactionPlan struct {
+	Statements []string
+	Locks      [][]Lock
+}
+
+// AnalyzeTransactions estimates the locks every statement takes, and reports
+// the lock patterns that block other transactions or deadlock, both within a
+// transaction and between the transactions of the batch.
+func AnalyzeTransactions(transactions [][]string, estimateLocks LockEstimator, hotTables map[string]bool) ([]TransactionPlan, []issuetypes.QueryIssue, error) {
+	plans := []TransactionPlan{}
+	for _, statements := range transactions {
+		plan := TransactionPlan{
+			Statements: statements,
+			Locks:      [][]Lock{},
+		}
+		for _, statement := range statements {
+			locks, err := estimateLocks(statement)
+			if err != nil {
+				return nil, nil, fmt.Errorf("estimate locks for %q: %w", statement, err)
+			}
+			plan.Locks = append(plan.Locks, locks)
+		}
+		plans = append(plans, plan)
+	}
+
+	queryIssues := []issuetypes.QueryIssue{}
+	for i, plan := range plans {
+		queryIssues = append(queryIssues, scanTransactionForLongHeldLocks(i, plan, hotTables)...)
+		queryIssues = append(queryIssues, scanTransactionForDeadlocks(i, plan)...)
+	}
+	queryIssues = append(queryIssues, scanTransactionsForLockOrder(plans)...)
+
+	return plans, queryIssues, nil
+}
+
+// scanTransactionForLongHeldLocks reports exclusive locks on many rows, or on
+// hot tables, that are taken before other statements run. Locks are held
+// until COMMIT, so everything that runs after them makes other transactions
+// wait longer.
+func scanTransactionForLongHeldLocks(transaction int, plan TransactionPlan, hotTables map[string]bool) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	remaining := len(plan.Statements) - 1
+	for i, locks := range plan.Locks {
+		if remaining-i <= 0 {
+			break
+		}
+
+		for _, lock := range locks {
+			if lock.Mode != LockModeExclusive {
+				continue
+			}
+			wide := lock.Granularity != LockGranularityRow
+			if !wide && !hotTables[lock.Table] {
+				continue
+			}
+
+			severity := issuetypes.IssueSeverityMedium
+			if lock.Granularity == LockGranularityTable && hotTables[lock.Table] {
+				severity = issuetypes.IssueSeverityHigh
+			}
+
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeLongHeldLock,
+				Message: fmt.Sprintf("transaction %d, statement %d takes an %s and holds it through %d more statements until COMMIT; run it as late in the transaction as possible",
+					transaction+1, i+1, lock, remaining-i),
+			})
+		}
+	}
+
+	return queryIssues
+}
+
+// scanTransactionForDeadlocks reports lock sequences that deadlock when two
+// copies of the same transaction run at the same time.
+func scanTransactionForDeadlocks(transaction int, plan TransactionPlan) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	shared := map[string]int{}
+	ranges := map[string]int{}
+	reported := map[string]bool{}
+
+	for i, locks := range plan.Locks {
+		for _, lock := range locks {
+			if lock.Mode == LockModeExclusive {
+				// both copies hold the shared lock, and each waits for the
+				// other to release it before it can take the exclusive one
+				if first, ok := shared[lock.Table]; ok && !reported["upgrade:"+lock.Table] {
+					reported["upgrade:"+lock.Table] = true
+					queryIssues = append(queryIssues, issuetypes.QueryIssue{
+						IssueSeverity: issuetypes.IssueSeverityHigh,
+						IssueType:     issuetypes.QueryIssueTypeDeadlockProne,
+						Message: fmt.Sprintf("transaction %d reads %s with a shared lock in statement %d and writes it in statement %d; two concurrent runs deadlock upgrading the lock, so take an exclusive lock (SELECT ... FOR UPDATE) up front",
+							transaction+1, lock.Table, first+1, i+1),
+					})
+				}
+
+				// gap locks don't conflict with each other, but they block the
+				// inserts of the other transaction into the same gap
+				if first, ok := ranges[lock.Table]; ok && first < i && isInsertLock(plan.Statements[i], lock) && !reported["gap:"+lock.Table] {
+					reported["gap:"+lock.Table] = true
+					queryIssues = append(queryIssues, issuetypes.QueryIssue{
+						IssueSeverity: issuetypes.IssueSeverityHigh,
+						IssueType:     issuetypes.QueryIssueTypeDeadlockProne,
+						Message: fmt.Sprintf("transaction %d locks a range of %s in statement %d and inserts into it in statement %d; two concurrent runs both hold the gap lock and deadlock on the insert",
+							transaction+1, lock.Table, first+1, i+1),
+					})
+				}
+			}
+
+			if lock.Mode == LockModeShared {
+				if _, ok := shared[lock.Table]; !ok {
+					shared[lock.Table] = i
+				}
+			}
+			if lock.Granularity != LockGranularityRow {
+				if _, ok := ranges[lock.Table]; !ok {
+					ranges[lock.Table] = i
+				}
+			}
+		}
+	}
+
+	return queryIssues
+}
+
+func isInsertLock(statement string, lock Lock) bool {
+	tokens := significantTokens(lexer.Tokenize(statement))
+	return len(tokens) > 0 && (tokens[0].Is("insert") || tokens[0].Is("replace")) && lock.Granularity == LockGranularityRow
+}
+
+// scanTransactionsForLockOrder reports pairs of transactions that lock the
+// same two tables in opposite orders, which deadlocks when they run at the
+// same time.
+func scanTransactionsForLockOrder(plans []TransactionPlan) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	orders := []map[string]Lock{}
+	positions := []map[string]int{}
+	for _, plan := range plans {
+		first := map[string]Lock{}
+		position := map[string]int{}
+		n := 0
+		for _, locks := range plan.Locks {
+			for _, lock := range locks {
+				if existing, ok := first[lock.Table]; ok {
+					if existing.Mode == LockModeShared && lock.Mode == LockModeExclusive {
+						first[lock.Table] = lock
+					}
+					continue
+				}
+				first[lock.Table] = lock
+				position[lock.Table] = n
+				n++
+			}
+		}
+		orders = append(orders, first)
+		positions = append(positions, position)
+	}
+
+	for a := 0; a < len(plans); a++ {
+		for b := a + 1; b < len(plans); b++ {
+			tables := []string{}
+			for table := range positions[a] {
+				if _, ok := positions[b][table]; ok && conflicts(orders[a][table], orders[b][table]) {
+					tables = append(tables, table)
+				}
+			}
+			position := positions[a]
+			sort.Slice(tables, func(i, j int) bool {
+				return position[tables[i]] < position[tables[j]]
+			})
+
+			for i := 0; i < len(tables); i++ {
+				for j := i + 1; j < len(tables); j++ {
+					first, second := tables[i], tables[j]
+					if positions[b][first] < positions[b][second] {
+						continue
+					}
+					queryIssues = append(queryIssues, issuetypes.QueryIssue{
+						IssueSeverity: issuetypes.IssueSeverityHigh,
+						IssueType:     issuetypes.QueryIssueTypeLockOrderInconsistency,
+						Message: fmt.Sprintf("transaction %d locks %s before %s, but transaction %d locks %s before %s; when they run at the same time they deadlock, so lock tables in the same order everywhere",
+							a+1, first, second, b+1, second, first),
+					})
+				}
+			}
+		}
+	}
+
+	return queryIssues
+}
+
+// conflicts returns true if two locks on the same table can block each other.
+func conflicts(a Lock, b Lock) bool {
+	return a.Mode == LockModeExclusive || b.Mode == LockModeExclusive
+}
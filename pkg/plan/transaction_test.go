@@ -0,0 +1,135 @@
+package plan
+
+import (
+	"testing"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupTransactions(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []string
+		want       [][]string
+		wantErr    bool
+	}{
+		{
+			name:       "block and autocommit statements",
+			statements: []string{"update a set x = 1", "BEGIN", "update b set x = 1", "update c set x = 1", "COMMIT", "start transaction", "delete from d", "rollback"},
+			want:       [][]string{{"update a set x = 1"}, {"update b set x = 1", "update c set x = 1"}, {"delete from d"}},
+		},
+		{
+			name:       "not committed",
+			statements: []string{"begin", "update a set x = 1"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GroupTransactions(tt.statements)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEstimateLocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []Lock
+	}{
+		{
+			name:  "plain read",
+			query: "select * from orders where status = 'open'",
+			want:  []Lock{},
+		},
+		{
+			name:  "locking read on a unique key",
+			query: "select * from users where id = 1 for update",
+			want:  []Lock{{Table: "users", Mode: LockModeExclusive, Granularity: LockGranularityRow, EstimatedRows: 1}},
+		},
+		{
+			name:  "update on a secondary index",
+			query: "update orders set status = 'open' where user_id = 1",
+			want:  []Lock{{Table: "orders", Mode: LockModeExclusive, Granularity: LockGranularityRange, EstimatedRows: 100000}},
+		},
+		{
+			name:  "delete without an index",
+			query: "delete from orders where status = 'open'",
+			want:  []Lock{{Table: "orders", Mode: LockModeExclusive, Granularity: LockGranularityTable, EstimatedRows: 1000000}},
+		},
+		{
+			name:  "insert select",
+			query: "insert into users (email) select user_ref from orders where id = 1",
+			want: []Lock{
+				{Table: "users", Mode: LockModeExclusive, Granularity: LockGranularityRow},
+				{Table: "orders", Mode: LockModeShared, Granularity: LockGranularityRow, EstimatedRows: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EstimateLocks(tt.query, testSchema())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAnalyzeTransactions(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions [][]string
+		want         []string
+	}{
+		{
+			name: "short row locks",
+			transactions: [][]string{
+				{"update users set name = 'a' where id = 1", "update orders set status = 'b' where id = 1"},
+			},
+			want: []string{},
+		},
+		{
+			name: "range lock held through the transaction",
+			transactions: [][]string{
+				{"update orders set status = 'b' where user_id = 1", "update users set name = 'a' where id = 1"},
+			},
+			want: []string{issuetypes.QueryIssueTypeLongHeldLock},
+		},
+		{
+			name: "lock upgrade",
+			transactions: [][]string{
+				{"select * from users where id = 1 lock in share mode", "update users set name = 'a' where id = 1"},
+			},
+			want: []string{issuetypes.QueryIssueTypeDeadlockProne},
+		},
+		{
+			name: "inconsistent lock order",
+			transactions: [][]string{
+				{"update users set name = 'a' where id = 1", "update orders set status = 'b' where id = 1"},
+				{"update orders set status = 'b' where id = 2", "update users set name = 'a' where id = 2"},
+			},
+			want: []string{issuetypes.QueryIssueTypeLockOrderInconsistency},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := testSchema()
+			estimateLocks := func(query string) ([]Lock, error) {
+				return EstimateLocks(query, tables)
+			}
+
+			plans, issues, err := AnalyzeTransactions(tt.transactions, estimateLocks, map[string]bool{})
+			require.NoError(t, err)
+			assert.Len(t, plans, len(tt.transactions))
+			assert.Equal(t, tt.want, issueTypes(issues))
+		})
+	}
+}
@@ -2,7 +2,6 @@ package plan
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/blastrain/vitess-sqlparser/sqlparser"
 	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
@@ -11,10 +10,24 @@ import (
 
 type UpdateStatement struct {
 	Columns map[string][]string
-	Tables  []string
+	// Tables are the tables with columns in the SET clause
+	Tables []string
+	// Sources are all the tables in the statement, including the ones that are
+	// only joined to pick the rows
+	Sources []string
+	Filter  *writeFilter
+
+	joinGraph *joinGraph
 }
 
-func ScanUpdateStatementForIssues(query string, tables []dbtypes.Table) ([]issuetypes.QueryIssue, error) {
+// ScanUpdateStatementForIssues returns the issues with an UPDATE, described for
+// the engine.
+func ScanUpdateStatementForIssues(query string, tables []dbtypes.Table, engine string) ([]issuetypes.QueryIssue, error) {
+	query, tables, queryIssues, err := withCommonTableExpressions(query, tables)
+	if err != nil {
+		return nil, err
+	}
+
 	updateStatement, err := parseUpdateStatement(query, tables)
 	if err != nil {
 		return nil, fmt.Errorf("parse update statement: %w", err)
@@ -24,53 +37,28 @@ func ScanUpdateStatementForIssues(query string, tables []dbtypes.Table) ([]issue
 		return nil, nil
 	}
 
-	queryIssues := []issuetypes.QueryIssue{}
-
-	issues, err := scanUpdateStatementForMissingIndexes(updateStatement, indexesByTable(tables))
+	issues, err := scanUpdateStatementForMissingIndexes(updateStatement, tables, indexesByTable(tables), engine)
 	if err != nil {
 		return nil, err
 	}
 	queryIssues = append(queryIssues, issues...)
 
-	issues, err = scanUpdateStatementForIndexUpdates(query, tables, updateStatement, indexesByTable(tables))
+	issues, err = scanJoinGraphForIssues(updateStatement.joinGraph, indexesByTable(tables), tables)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("scan join graph for issues: %w", err)
 	}
 	queryIssues = append(queryIssues, issues...)
 
 	return queryIssues, nil
 }
 
-func scanUpdateStatementForIndexUpdates(query string, tables []dbtypes.Table, updateStatement *UpdateStatement, indexesByTable map[string][]Index) ([]issuetypes.QueryIssue, error) {
-	queryIssues := []issuetypes.QueryIssue{}
-
-	// check if the column that's updated is part of an index
-	for table, columns := range updateStatement.Columns {
-		if _, exists := indexesByTable[table]; !exists {
-			continue
-		}
-
-		for _, index := range indexesByTable[table] {
-			for _, column := range columns {
-				if contains(index.Columns, column) {
-					columnNames := ""
-					columnNames += fmt.Sprintf(`From the %q" table: %s`, table, strings.Join(updateStatement.Columns[table], ", "))
-
-					queryIssues = append(queryIssues, issuetypes.QueryIssue{
-						IssueSeverity: issuetypes.IssueSeverityLow,
-						IssueType:     issuetypes.QueryIssueTypeColumnUpdatedInIndex,
-						Message:       "column updated is part of an index",
-					})
-				}
-			}
-		}
+func parseUpdateStatement(query string, tables []dbtypes.Table) (*UpdateStatement, error) {
+	query, tables, _, err := withCommonTableExpressions(query, tables)
+	if err != nil {
+		return nil, err
 	}
 
-	return queryIssues, nil
-}
-
-func parseUpdateStatement(query string, tables []dbtypes.Table) (*UpdateStatement, error) {
-	stmt, err := sqlparser.Parse(query)
+	stmt, err := parseStatement(query)
 	if err != nil {
 		return nil, fmt.Errorf("parse query: %w", err)
 	}
@@ -86,57 +74,53 @@ func parseUpdateStatement(query string, tables []dbtypes.Table) (*UpdateStatemen
 	}
 
 	// Extract table names
-	result.Tables = extractUpdateTableName(updateStmt)
+	tableAliasLookup, tableNames, err := extractTableExprs(updateStmt.TableExprs)
+	if err != nil {
+		return nil, fmt.Errorf("extract tables: %w", err)
+	}
+	result.Sources = tableNames
+	s := newScope(nil, tableAliasLookup, tableNames)
 
 	// Extract columns and their new values
-	err = processUpdateExpressions(updateStmt, tables, result.Tables, &result)
+	err = processUpdateExpressions(updateStmt, s, tables, &result)
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
-}
+	result.Filter, err = parseWriteFilter(updateStmt.Where, updateStmt.OrderBy, updateStmt.Limit, s, tables)
+	if err != nil {
+		return nil, err
+	}
 
-func extractUpdateTableName(updateStmt *sqlparser.Update) []string {
-	var tables []string
-	for _, tableExpr := range updateStmt.TableExprs {
-		switch expr := tableExpr.(type) {
-		case *sqlparser.AliasedTableExpr:
-			tableName := sqlparser.String(expr.Expr)
-			tables = append(tables, tableName)
-		case *sqlparser.JoinTableExpr:
-			// Handle JOINs
-			leftTable := sqlparser.String(expr.LeftExpr)
-			rightTable := sqlparser.String(expr.RightExpr)
-			tables = append(tables, leftTable, rightTable)
-			// Note: This is a simplified handling. For more complex JOINs, further parsing may be required.
-		}
+	if len(tableNames) > 1 {
+		result.Filter.Joined = true
+		result.joinGraph = buildJoinGraph(updateStmt.TableExprs, whereExpr(updateStmt.Where), tables)
 	}
-	return tables
+
+	return &result, nil
 }
 
-func processUpdateExpressions(updateStmt *sqlparser.Update, tables []dbtypes.Table, tableNames []string, result *UpdateStatement) error {
+// processUpdateExpressions assigns every column in the SET clause to the table
+// it belongs to. A column that can't be resolved belongs to the first table,
+// which is the target of a Postgres UPDATE ... FROM.
+func processUpdateExpressions(updateStmt *sqlparser.Update, s *scope, tables []dbtypes.Table, result *UpdateStatement) error {
 	for _, updateExpr := range updateStmt.Exprs {
-		columnName := sqlparser.String(updateExpr.Name.Name)
-
-		// Check if the column belongs to any of the tables being updated
-		for _, tableName := range tableNames {
-			if contains(columnNamesForTable(tableName, tables), columnName) {
-				// Add the column to the respective table's slice in the result
-				result.Columns[tableName] = append(result.Columns[tableName], columnName)
-				break
-			} else {
-				// If the table is not in columnsByTable, assume it's valid and add it
-				result.Columns[tableName] = append(result.Columns[tableName], columnName)
-				break
+		columnName := updateExpr.Name.Name.String()
+
+		tableName, err := s.resolveColumn(updateExpr.Name.Qualifier.Name.String(), columnName, tables)
+		if err != nil {
+			if len(s.tableNames) == 0 {
+				return fmt.Errorf("resolve column %q: %w", columnName, err)
 			}
+			tableName = s.tableNames[0]
 		}
+
+		result.Columns[tableName] = appendIfMissing(result.Columns[tableName], columnName)
+		result.Tables = appendIfMissing(result.Tables, tableName)
 	}
 	return nil
 }
 
-func scanUpdateStatementForMissingIndexes(updateStatement *UpdateStatement, indexesByTable map[string][]Index) ([]issuetypes.QueryIssue, error) {
-	queryIssues := []issuetypes.QueryIssue{}
-
-	return queryIssues, nil
+func scanUpdateStatementForMissingIndexes(updateStatement *UpdateStatement, tables []dbtypes.Table, indexesByTable map[string][]Index, engine string) ([]issuetypes.QueryIssue, error) {
+	return scanWriteFilterForIssues("UPDATE", updateStatement.Tables, updateStatement.Filter, tables, indexesByTable, engine)
 }
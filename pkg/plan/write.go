@@ -0,0 +1,272 @@
+package plan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+)
+
+const (
+	// largeTableRowCount is the estimated size above which a full scan of a
+	// table is reported as high severity
+	largeTableRowCount = 100000
+)
+
+// writeFilter is the part of an UPDATE or DELETE that decides which rows are
+// changed.
+type writeFilter struct {
+	Where    map[string][]string
+	Equality map[string][]string
+	// Functions are the columns the WHERE clause passes to a function, which
+	// an index on the column can't serve
+	Functions map[string][]string
+	// Unresolved is set when the WHERE clause names a column that isn't in
+	// the schema, so which rows it picks is unknown
+	Unresolved bool
+	HasWhere   bool
+	HasOrderBy bool
+	// Joined is set when the statement joins other tables, which pick the
+	// rows that are written even without a WHERE clause
+	Joined bool
+	Limit  *int64
+}
+
+func parseWriteFilter(where *sqlparser.Where, orderBy sqlparser.OrderBy, limit *sqlparser.Limit, s *scope, tables []dbtypes.Table) (*writeFilter, error) {
+	filter := writeFilter{
+		Where:      map[string][]string{},
+		Equality:   map[string][]string{},
+		Functions:  map[string][]string{},
+		HasOrderBy: len(orderBy) > 0,
+	}
+
+	if where != nil && where.Expr != nil {
+		filter.HasWhere = true
+
+		if err := walkPredicate(where.Expr, s, tables, filter.Where); err != nil {
+			return nil, fmt.Errorf("process where clause: %w", err)
+		}
+
+		filter.Equality = equalityColumns(where.Expr, s, tables)
+		filter.Functions = functionColumns(where.Expr, s, tables)
+		filter.Unresolved = hasUnresolvedColumn(where.Expr, s, tables)
+	}
+
+	if limit != nil {
+		if rowCount, ok := limit.Rowcount.(*sqlparser.SQLVal); ok && rowCount.Type == sqlparser.IntVal {
+			if value, err := strconv.ParseInt(string(rowCount.Val), 10, 64); err == nil {
+				filter.Limit = &value
+			}
+		}
+	}
+
+	return &filter, nil
+}
+
+// equalityColumns returns the columns that are compared to a single value in
+// the top level of a predicate.
+func equalityColumns(whereExpr sqlparser.Expr, s *scope, tables []dbtypes.Table) map[string][]string {
+	equality := map[string][]string{}
+
+	for _, conjunct := range splitAnd(whereExpr) {
+		comparison, ok := conjunct.(*sqlparser.ComparisonExpr)
+		if !ok {
+			continue
+		}
+
+		col, isCol := comparison.Left.(*sqlparser.ColName)
+		other := comparison.Right
+		if !isCol {
+			col, isCol = comparison.Right.(*sqlparser.ColName)
+			other = comparison.Left
+		}
+		if !isCol {
+			continue
+		}
+		if _, otherIsCol := other.(*sqlparser.ColName); otherIsCol {
+			continue
+		}
+
+		switch comparison.Operator {
+		case sqlparser.EqualStr, sqlparser.NullSafeEqualStr:
+		case sqlparser.InStr:
+			if tuple, ok := other.(sqlparser.ValTuple); !ok || len(tuple) != 1 {
+				continue
+			}
+		default:
+			continue
+		}
+
+		tableName, err := s.resolveColumn(col.Qualifier.Name.String(), col.Name.String(), tables)
+		if err != nil || tableName == "" {
+			continue
+		}
+		equality[tableName] = appendIfMissing(equality[tableName], col.Name.String())
+	}
+
+	return equality
+}
+
+// estimateAffectedRows estimates how many rows of a table a write changes,
+// using the table statistics and the indexes that serve the predicate. The
+// second return value is true when the rows can only be found by a full scan.
+func estimateAffectedRows(table dbtypes.Table, filter *writeFilter, indexes []Index) (int64, bool) {
+	rowCount := table.GetEstimatedRowCount()
+
+	estimate := rowCount
+	fullScan := true
+
+	if filter.HasWhere {
+		columns := filter.Where[table.GetName()]
+		equality := filter.Equality[table.GetName()]
+
+		if len(columns) > 0 && len(unindexedColumns(indexes, columns)) < len(columns) {
+			fullScan = false
+
+			// without histograms, assume an indexed predicate matches a
+			// tenth of the table
+			estimate = rowCount / 10
+		}
+
+		if matchesUniqueKey(indexes, equality) {
+			fullScan = false
+			estimate = 1
+		}
+	}
+
+	if filter.Limit != nil && *filter.Limit < estimate {
+		estimate = *filter.Limit
+	}
+
+	if estimate < 1 && rowCount > 0 {
+		estimate = 1
+	}
+
+	return estimate, fullScan
+}
+
+// matchesUniqueKey returns true if the equality columns cover every column of
+// the primary key or of a unique index, so at most one row matches.
+func matchesUniqueKey(indexes []Index, equality []string) bool {
+	for _, index := range indexes {
+		if index.IsUnique && len(index.Columns) > 0 && containsAll(equality, index.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(slice []string, elements []string) bool {
+	for _, element := range elements {
+		if !contains(slice, element) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanWriteFilterForIssues reports UPDATE and DELETE statements that change
+// every row, or that have to scan (and in InnoDB, lock) every row to find the
+// ones they change. The engine decides how the cost of the scan is described.
+func scanWriteFilterForIssues(verb string, tableNames []string, filter *writeFilter, tables []dbtypes.Table, indexesByTable map[string][]Index, engine string) ([]issuetypes.QueryIssue, error) {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	for _, tableName := range tableNames {
+		table := findTable(tables, tableName)
+
+		if !filter.HasWhere && !filter.Joined {
+			message := fmt.Sprintf("%s without a WHERE clause changes every row in %s", verb, tableName)
+			if table != nil {
+				message += fmt.Sprintf(" (~%d rows)", table.GetEstimatedRowCount())
+			}
+			if filter.Limit != nil {
+				message += fmt.Sprintf("; the LIMIT %d picks arbitrary rows", *filter.Limit)
+			}
+
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityHigh,
+				IssueType:     issuetypes.QueryIssueTypeMissingWhereClause,
+				Message:       message,
+			})
+			continue
+		}
+
+		if table == nil {
+			continue
+		}
+
+		_, fullScan := estimateAffectedRows(table, filter, indexesByTable[tableName])
+		columns := append([]string{}, filter.Where[tableName]...)
+		for _, column := range filter.Functions[tableName] {
+			columns = appendIfMissing(columns, column)
+		}
+		// a WHERE clause that doesn't name a column of the table, such as
+		// WHERE true or an EXISTS, still has every row of it scanned and
+		// written, unless a join picks the rows
+		if fullScan && len(columns) == 0 && filter.HasWhere && !filter.Joined && !filter.Unresolved {
+			message := fmt.Sprintf("%s has a WHERE clause that doesn't filter on any column of %s, so it changes or scans every row (~%d rows)",
+				verb, tableName, table.GetEstimatedRowCount())
+			if filter.Limit != nil {
+				message += fmt.Sprintf("; the LIMIT %d picks arbitrary rows", *filter.Limit)
+			}
+
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: issuetypes.IssueSeverityHigh,
+				IssueType:     issuetypes.QueryIssueTypeMissingWhereClause,
+				Message:       message,
+			})
+			continue
+		}
+
+		if fullScan && len(columns) > 0 {
+			severity := issuetypes.IssueSeverityMedium
+			if table.GetEstimatedRowCount() >= largeTableRowCount {
+				severity = issuetypes.IssueSeverityHigh
+			}
+
+			message := fmt.Sprintf("%s scans every row of %s (~%d rows) because no index serves its WHERE clause on %s",
+				verb, tableName, table.GetEstimatedRowCount(), strings.Join(columns, ", "))
+			switch engine {
+			case EngineMySQL:
+				message += "; InnoDB locks every row it scans, so the whole table is locked until the transaction ends"
+			case EnginePostgres:
+				message += "; Postgres only locks the rows that change, but reads the whole table to find them"
+			}
+
+			queryIssues = append(queryIssues, issuetypes.QueryIssue{
+				IssueSeverity: severity,
+				IssueType:     issuetypes.QueryIssueTypeWhereClauseMissingIndex,
+				Message:       message,
+			})
+		}
+	}
+
+	if filter.Limit != nil && !filter.HasOrderBy {
+		queryIssues = append(queryIssues, issuetypes.QueryIssue{
+			IssueSeverity: issuetypes.IssueSeverityLow,
+			IssueType:     issuetypes.QueryIssueTypeLimitWithoutOrderBy,
+			Message:       fmt.Sprintf("%s with LIMIT but no ORDER BY changes an unpredictable set of rows and is unsafe for statement based replication", verb),
+		})
+	}
+
+	return queryIssues, nil
+}
+
+func whereExpr(where *sqlparser.Where) sqlparser.Expr {
+	if where == nil {
+		return nil
+	}
+	return where.Expr
+}
+
+func findTable(tables []dbtypes.Table, tableName string) dbtypes.Table {
+	for _, table := range tables {
+		if table.GetName() == tableName {
+			return table
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package plan
+
+import (
+	"testing"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanDeleteStatementForIssues(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "primary key",
+			query: "delete from orders where id = 1",
+			want:  []string{},
+		},
+		{
+			name:  "no where clause",
+			query: "delete from orders",
+			want:  []string{issuetypes.QueryIssueTypeMissingWhereClause},
+		},
+		{
+			name:  "unindexed where clause",
+			query: "delete from orders where status = 'cancelled'",
+			want:  []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+		},
+		{
+			name:  "indexed column in a function",
+			query: "delete from orders where abs(user_id) = 1",
+			want:  []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+		},
+		{
+			name:  "limit without order by",
+			query: "delete from orders where user_id = 1 limit 10",
+			want:  []string{issuetypes.QueryIssueTypeLimitWithoutOrderBy},
+		},
+		{
+			name:  "table that isn't in the schema",
+			query: "delete from foo where bar = 1",
+			want:  []string{},
+		},
+		{
+			name:  "column that isn't in the schema",
+			query: "delete from orders where archived_at < now()",
+			want:  []string{},
+		},
+		{
+			name:  "where true",
+			query: "delete from orders where true",
+			want:  []string{issuetypes.QueryIssueTypeMissingWhereClause},
+		},
+		{
+			name:  "where 1 = 1",
+			query: "delete from orders where 1 = 1",
+			want:  []string{issuetypes.QueryIssueTypeMissingWhereClause},
+		},
+		{
+			name:  "where exists on another table",
+			query: "delete from orders where exists (select 1 from users u where u.id = orders.user_id)",
+			want:  []string{issuetypes.QueryIssueTypeMissingWhereClause},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScanDeleteStatementForIssues(tt.query, testSchema(), EngineMySQL)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, issueTypes(got))
+		})
+	}
+}
+
+func TestScanUpdateStatementForIssues_filter(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		want         []string
+		wantSeverity string
+	}{
+		{
+			name:  "unique index",
+			query: "update users set name = 'a' where email = 'a@example.com'",
+			want:  []string{},
+		},
+		{
+			name:         "no where clause",
+			query:        "update users set name = 'a'",
+			want:         []string{issuetypes.QueryIssueTypeMissingWhereClause},
+			wantSeverity: issuetypes.IssueSeverityHigh,
+		},
+		{
+			name:         "where 1 = 1",
+			query:        "update orders set status = 'x' where 1 = 1",
+			want:         []string{issuetypes.QueryIssueTypeMissingWhereClause},
+			wantSeverity: issuetypes.IssueSeverityHigh,
+		},
+		{
+			name:         "unindexed where clause on a small table",
+			query:        "update users set name = 'b' where name = 'a'",
+			want:         []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+			wantSeverity: issuetypes.IssueSeverityMedium,
+		},
+		{
+			name:         "unindexed where clause on a large table",
+			query:        "update orders set user_ref = 'a' where status = 'open'",
+			want:         []string{issuetypes.QueryIssueTypeWhereClauseMissingIndex},
+			wantSeverity: issuetypes.IssueSeverityHigh,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScanUpdateStatementForIssues(tt.query, testSchema(), EngineMySQL)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, issueTypes(got))
+			if len(got) > 0 {
+				assert.Equal(t, tt.wantSeverity, got[0].IssueSeverity)
+			}
+		})
+	}
+}
+
+func Test_estimateAffectedRows(t *testing.T) {
+	tables := testSchema()
+	indexes := indexesByTable(tables)
+
+	tests := []struct {
+		name         string
+		query        string
+		wantRows     int64
+		wantFullScan bool
+	}{
+		{
+			name:         "no where clause",
+			query:        "delete from orders",
+			wantRows:     1000000,
+			wantFullScan: true,
+		},
+		{
+			name:     "primary key",
+			query:    "delete from orders where id = 5",
+			wantRows: 1,
+		},
+		{
+			name:     "secondary index",
+			query:    "delete from orders where user_id = 5",
+			wantRows: 100000,
+		},
+		{
+			name:         "limit",
+			query:        "delete from orders where status = 'open' limit 100",
+			wantRows:     100,
+			wantFullScan: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleteStatement, err := parseDeleteStatement(tt.query, tables)
+			require.NoError(t, err)
+
+			rows, fullScan := estimateAffectedRows(findTable(tables, "orders"), deleteStatement.Filter, indexes["orders"])
+			assert.Equal(t, tt.wantRows, rows)
+			assert.Equal(t, tt.wantFullScan, fullScan)
+		})
+	}
+}
+
+func TestScanUpdateStatementForIssues_withoutSchema(t *testing.T) {
+	got, err := ScanUpdateStatementForIssues("update foo f set a = 1 from bar b where b.id = f.bar_id and b.name = 'x'", nil, EnginePostgres)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, issueTypes(got))
+}
+
+func TestScanDeleteStatementForIssues_engineWording(t *testing.T) {
+	query := "delete from orders where status = 'cancelled'"
+
+	got, err := ScanDeleteStatementForIssues(query, testSchema(), EngineMySQL)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "DELETE scans every row of orders (~1000000 rows) because no index serves its WHERE clause on status; InnoDB locks every row it scans, so the whole table is locked until the transaction ends", got[0].Message)
+
+	got, err = ScanDeleteStatementForIssues(query, testSchema(), EnginePostgres)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.NotContains(t, got[0].Message, "InnoDB")
+	assert.Contains(t, got[0].Message, "Postgres only locks the rows that change")
+}
@@ -0,0 +1,230 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+)
+
+const (
+	// maxIndexWritesPerRow is the number of index entries written for each
+	// changed row above which a write is reported as over-indexed
+	maxIndexWritesPerRow = 5
+
+	primaryKeyIndexName = "primary key"
+)
+
+// WriteCost is the index maintenance a write does in one table. The model
+// follows InnoDB, where the table is stored in the primary key and every
+// secondary index points at the primary key; the engine packages adjust it
+// where their storage differs.
+type WriteCost struct {
+	Table     string
+	Statement string
+	// EstimatedRows is the number of rows the statement writes
+	EstimatedRows int64
+	// Indexes is every index on the table, including the primary key
+	Indexes []string
+	// IndexesWritten are the indexes with an entry that changes for every
+	// written row
+	IndexesWritten []string
+	// MovesRow is set when an UPDATE changes the primary key, which deletes
+	// and reinserts the row and rewrites every secondary index entry
+	MovesRow bool
+	// HeapOnlyTuple is set by the Postgres package when an UPDATE can be done
+	// without touching any index
+	HeapOnlyTuple bool
+	// Notes are engine specific remarks added to the summary
+	Notes []string
+}
+
+// Cost is the number of row and index entry writes the statement does.
+func (c WriteCost) Cost() int64 {
+	rows := c.EstimatedRows
+	if rows < 1 {
+		rows = 1
+	}
+	return rows * int64(1+len(c.IndexesWritten))
+}
+
+// EstimateWriteCosts returns the write cost of an INSERT, UPDATE or DELETE
+// for each table it writes. Other statements have no write cost.
+func EstimateWriteCosts(query string, tables []dbtypes.Table) ([]WriteCost, error) {
+	stmt, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := indexesByTable(tables)
+
+	switch stmt.(type) {
+	case *sqlparser.Insert:
+		insertStatement, err := parseInsertStatement(query)
+		if err != nil {
+			return nil, fmt.Errorf("parse insert statement: %w", err)
+		}
+
+		rows := int64(len(insertStatement.Values))
+		if insertStatement.Select != nil {
+			rows = 0
+		}
+		return []WriteCost{
+			newWriteCost("INSERT", insertStatement.Table, rows, indexes[insertStatement.Table], true),
+		}, nil
+
+	case *sqlparser.Update:
+		updateStatement, err := parseUpdateStatement(query, tables)
+		if err != nil {
+			return nil, fmt.Errorf("parse update statement: %w", err)
+		}
+
+		costs := []WriteCost{}
+		for _, tableName := range updateStatement.Tables {
+			columns, ok := updateStatement.Columns[tableName]
+			if !ok {
+				continue
+			}
+
+			rows := int64(0)
+			if table := findTable(tables, tableName); table != nil {
+				rows, _ = estimateAffectedRows(table, updateStatement.Filter, indexes[tableName])
+			}
+
+			cost := newWriteCost("UPDATE", tableName, rows, indexes[tableName], false)
+			for _, index := range indexes[tableName] {
+				if !containsAny(index.Columns, columns) {
+					continue
+				}
+				if index.IsPrimaryKey {
+					cost.MovesRow = true
+					continue
+				}
+				cost.IndexesWritten = append(cost.IndexesWritten, index.Name)
+			}
+			if cost.MovesRow {
+				cost.IndexesWritten = cost.Indexes
+			}
+
+			costs = append(costs, cost)
+		}
+		return costs, nil
+
+	case *sqlparser.Delete:
+		deleteStatement, err := parseDeleteStatement(query, tables)
+		if err != nil {
+			return nil, fmt.Errorf("parse delete statement: %w", err)
+		}
+
+		costs := []WriteCost{}
+		for _, tableName := range deleteStatement.Tables {
+			rows := int64(0)
+			if table := findTable(tables, tableName); table != nil {
+				rows, _ = estimateAffectedRows(table, deleteStatement.Filter, indexes[tableName])
+			}
+			costs = append(costs, newWriteCost("DELETE", tableName, rows, indexes[tableName], true))
+		}
+		return costs, nil
+	}
+
+	return nil, nil
+}
+
+// newWriteCost returns the cost of a write to the table, which writes every
+// index when allIndexes is set, and none otherwise.
+func newWriteCost(statement string, tableName string, rows int64, indexes []Index, allIndexes bool) WriteCost {
+	cost := WriteCost{
+		Table:          tableName,
+		Statement:      statement,
+		EstimatedRows:  rows,
+		Indexes:        []string{},
+		IndexesWritten: []string{},
+	}
+
+	for _, index := range indexes {
+		if len(index.Columns) == 0 {
+			continue
+		}
+		name := index.Name
+		if index.IsPrimaryKey {
+			name = primaryKeyIndexName
+		}
+		cost.Indexes = append(cost.Indexes, name)
+	}
+
+	if allIndexes {
+		cost.IndexesWritten = cost.Indexes
+	}
+
+	return cost
+}
+
+func containsAny(slice []string, elements []string) bool {
+	for _, element := range elements {
+		if contains(slice, element) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanWriteCostsForIssues reports writes that maintain more indexes than they
+// need to, with a summary of every table written ranked by cost.
+func ScanWriteCostsForIssues(costs []WriteCost) []issuetypes.QueryIssue {
+	queryIssues := []issuetypes.QueryIssue{}
+
+	severity := ""
+	for _, cost := range costs {
+		switch {
+		case cost.MovesRow && cost.EstimatedRows >= largeTableRowCount:
+			severity = issuetypes.IssueSeverityHigh
+		case cost.MovesRow || len(cost.IndexesWritten) > maxIndexWritesPerRow:
+			if severity != issuetypes.IssueSeverityHigh {
+				severity = issuetypes.IssueSeverityMedium
+			}
+		case cost.Statement == "UPDATE" && len(cost.IndexesWritten) > 0:
+			if severity == "" {
+				severity = issuetypes.IssueSeverityLow
+			}
+		}
+	}
+
+	if severity == "" {
+		return queryIssues
+	}
+
+	ranked := make([]WriteCost, len(costs))
+	copy(ranked, costs)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Cost() > ranked[j].Cost()
+	})
+
+	lines := []string{"write cost, highest first:"}
+	for i, cost := range ranked {
+		line := fmt.Sprintf("  %d. %s %s: ~%d rows, %d of %d indexes written per row", i+1, cost.Statement, cost.Table, cost.EstimatedRows, len(cost.IndexesWritten), len(cost.Indexes))
+		if len(cost.IndexesWritten) > 0 {
+			line += fmt.Sprintf(" (%s)", strings.Join(cost.IndexesWritten, ", "))
+		}
+		if cost.MovesRow {
+			line += "; the primary key changes, so the row moves and every secondary index is rewritten"
+		}
+		if cost.HeapOnlyTuple {
+			line += "; eligible for a HOT update"
+		}
+		for _, note := range cost.Notes {
+			line += "; " + note
+		}
+		lines = append(lines, line)
+	}
+
+	queryIssues = append(queryIssues, issuetypes.QueryIssue{
+		IssueSeverity: severity,
+		IssueType:     issuetypes.QueryIssueTypeWriteAmplification,
+		Message:       strings.Join(lines, "\n"),
+	})
+
+	return queryIssues
+}
@@ -0,0 +1,87 @@
+package plan
+
+import (
+	"testing"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateWriteCosts(t *testing.T) {
+	tests := []struct {
+		name              string
+		query             string
+		wantRows          int64
+		wantIndexes       []string
+		wantMovesRow      bool
+		wantIssueSeverity string
+	}{
+		{
+			name:        "insert writes every index",
+			query:       "insert into users (email, name) values ('a', 'b'), ('c', 'd')",
+			wantRows:    2,
+			wantIndexes: []string{primaryKeyIndexName, "users_email"},
+		},
+		{
+			name:        "update of an unindexed column",
+			query:       "update orders set status = 'shipped' where id = 1",
+			wantRows:    1,
+			wantIndexes: []string{},
+		},
+		{
+			name:              "update of an indexed column",
+			query:             "update orders set user_id = 2 where user_id = 1",
+			wantRows:          100000,
+			wantIndexes:       []string{"orders_user_id"},
+			wantIssueSeverity: issuetypes.IssueSeverityLow,
+		},
+		{
+			name:              "update of the primary key",
+			query:             "update users set id = 2 where id = 1",
+			wantRows:          1,
+			wantIndexes:       []string{primaryKeyIndexName, "users_email"},
+			wantMovesRow:      true,
+			wantIssueSeverity: issuetypes.IssueSeverityMedium,
+		},
+		{
+			name:        "delete",
+			query:       "delete from orders where id = 1",
+			wantRows:    1,
+			wantIndexes: []string{primaryKeyIndexName, "orders_user_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			costs, err := EstimateWriteCosts(tt.query, testSchema())
+			require.NoError(t, err)
+			require.Len(t, costs, 1)
+
+			assert.Equal(t, tt.wantRows, costs[0].EstimatedRows)
+			assert.Equal(t, tt.wantIndexes, costs[0].IndexesWritten)
+			assert.Equal(t, tt.wantMovesRow, costs[0].MovesRow)
+
+			issues := ScanWriteCostsForIssues(costs)
+			if tt.wantIssueSeverity == "" {
+				assert.Empty(t, issues)
+				return
+			}
+			require.Len(t, issues, 1)
+			assert.Equal(t, issuetypes.QueryIssueTypeWriteAmplification, issues[0].IssueType)
+			assert.Equal(t, tt.wantIssueSeverity, issues[0].IssueSeverity)
+		})
+	}
+}
+
+func TestScanWriteCostsForIssues_ranking(t *testing.T) {
+	costs := []WriteCost{
+		{Table: "small", Statement: "UPDATE", EstimatedRows: 10, Indexes: []string{"a"}, IndexesWritten: []string{"a"}},
+		{Table: "large", Statement: "UPDATE", EstimatedRows: 1000, Indexes: []string{"b", "c"}, IndexesWritten: []string{"b", "c"}},
+	}
+
+	issues := ScanWriteCostsForIssues(costs)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "write cost, highest first:\n"+
+		"  1. UPDATE large: ~1000 rows, 2 of 2 indexes written per row (b, c)\n"+
+		"  2. UPDATE small: ~10 rows, 1 of 1 indexes written per row (a)", issues[0].Message)
+}
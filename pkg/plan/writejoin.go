@@ -0,0 +1,116 @@
+package plan
+
+import (
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// rewriteWriteJoins rewrites the Postgres forms of a multi-table write,
+// UPDATE ... FROM and DELETE ... USING, into the MySQL forms the parser
+// understands. The target table stays first, so unqualified columns still
+// resolve to it.
+//
+//	UPDATE t SET ... FROM a, b WHERE ...  =>  UPDATE t, a, b SET ... WHERE ...
+//	DELETE FROM t USING a, b WHERE ...    =>  DELETE t FROM t, a, b WHERE ...
+func rewriteWriteJoins(query string) string {
+	tokens := significantTokens(lexer.Tokenize(query))
+	if len(tokens) == 0 {
+		return query
+	}
+
+	switch {
+	case tokens[0].Is("update"):
+		set := topLevelKeyword(tokens, 1, "set")
+		if set < 0 {
+			return query
+		}
+		from := topLevelKeyword(tokens, set+1, "from")
+		if from < 0 {
+			return query
+		}
+		end := clauseEnd(query, tokens, from+1)
+
+		return strings.TrimSpace(query[:tokens[set].Pos]) + ", " +
+			strings.TrimSpace(query[tokens[from].End():end]) + " " +
+			strings.TrimSpace(query[tokens[set].Pos:tokens[from].Pos]) + " " +
+			strings.TrimSpace(query[end:])
+
+	case tokens[0].Is("delete") && len(tokens) > 1 && tokens[1].Is("from"):
+		using := topLevelKeyword(tokens, 2, "using")
+		if using < 0 {
+			return query
+		}
+		end := clauseEnd(query, tokens, using+1)
+
+		targets := strings.TrimSpace(query[tokens[1].End():tokens[using].Pos])
+		sources := strings.TrimSpace(query[tokens[using].End():end])
+		rest := strings.TrimSpace(query[end:])
+
+		// the MySQL form lists the targets in FROM and all the tables in
+		// USING, which only needs the keywords moved. Only the USING table
+		// list is searched for the target, since the WHERE clause names it
+		// in the Postgres form too
+		sourceTokens := []lexer.Token{}
+		for _, token := range tokens[using+1:] {
+			if token.Pos >= end {
+				break
+			}
+			sourceTokens = append(sourceTokens, token)
+		}
+		targetTokens := tokens[2:using]
+		if strings.Contains(targets, ",") || len(targetTokens) == 1 && containsWord(sourceTokens, targetTokens[0].Value) {
+			return strings.TrimSpace("delete " + targets + " from " + sources + " " + rest)
+		}
+
+		// Postgres names a single target with an optional alias
+		target := targetTokens[len(targetTokens)-1].Value
+		return strings.TrimSpace("delete " + target + " from " + targets + ", " + sources + " " + rest)
+	}
+
+	return query
+}
+
+// topLevelKeyword returns the index of the first token from start that is the
+// keyword outside of any parentheses, or -1.
+func topLevelKeyword(tokens []lexer.Token, start int, keyword string) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch {
+		case tokens[i].IsPunctuation("("):
+			depth++
+		case tokens[i].IsPunctuation(")"):
+			depth--
+		case depth == 0 && tokens[i].Is(keyword):
+			return i
+		}
+	}
+	return -1
+}
+
+// clauseEnd returns the byte offset where a table list that starts at
+// tokens[start] ends: at the WHERE, RETURNING, ORDER BY or LIMIT that follows
+// it, or at the end of the statement.
+func clauseEnd(query string, tokens []lexer.Token, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch {
+		case tokens[i].IsPunctuation("("):
+			depth++
+		case tokens[i].IsPunctuation(")"):
+			depth--
+		case depth == 0 && (tokens[i].Is("where") || tokens[i].Is("returning") || tokens[i].Is("order") || tokens[i].Is("limit") || tokens[i].IsPunctuation(";")):
+			return tokens[i].Pos
+		}
+	}
+	return len(query)
+}
+
+func containsWord(tokens []lexer.Token, word string) bool {
+	for _, token := range tokens {
+		if token.Is(word) || token.Type == lexer.QuotedIdentifier && unquoteIdentifier(token.Value) == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package plan
+
+import (
+	"testing"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rewriteWriteJoins(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "mysql update",
+			query: "update orders o join users u on u.id = o.user_id set o.status = u.name",
+			want:  "update orders o join users u on u.id = o.user_id set o.status = u.name",
+		},
+		{
+			name:  "update from",
+			query: "update orders o set status = u.name from users u where u.id = o.user_id returning o.id",
+			want:  "update orders o, users u set status = u.name where u.id = o.user_id returning o.id",
+		},
+		{
+			name:  "update with a subquery",
+			query: "update orders set status = (select name from users where users.id = orders.user_id)",
+			want:  "update orders set status = (select name from users where users.id = orders.user_id)",
+		},
+		{
+			name:  "delete using",
+			query: "delete from orders o using users u where u.id = o.user_id",
+			want:  "delete o from orders o, users u where u.id = o.user_id",
+		},
+		{
+			name:  "delete using without an alias",
+			query: "delete from orders using users u where u.id = orders.user_id and u.email = 'a'",
+			want:  "delete orders from orders, users u where u.id = orders.user_id and u.email = 'a'",
+		},
+		{
+			name:  "mysql delete using",
+			query: "delete from orders using orders join users on users.id = orders.user_id",
+			want:  "delete orders from orders join users on users.id = orders.user_id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rewriteWriteJoins(tt.query))
+		})
+	}
+}
+
+func TestParseUpdateStatement_joins(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantTables  []string
+		wantColumns map[string][]string
+	}{
+		{
+			name:        "join with aliases",
+			query:       "update orders o join users u on u.id = o.user_id set o.status = u.name where u.email = 'a'",
+			wantTables:  []string{"orders"},
+			wantColumns: map[string][]string{"orders": {"status"}},
+		},
+		{
+			name:        "both tables",
+			query:       "update orders o join users u on u.id = o.user_id set o.status = 'a', u.name = 'b'",
+			wantTables:  []string{"orders", "users"},
+			wantColumns: map[string][]string{"orders": {"status"}, "users": {"name"}},
+		},
+		{
+			name:        "update from",
+			query:       "update orders set status = u.name from users u where u.id = orders.user_id",
+			wantTables:  []string{"orders"},
+			wantColumns: map[string][]string{"orders": {"status"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUpdateStatement(tt.query, testSchema())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTables, got.Tables)
+			assert.Equal(t, tt.wantColumns, got.Columns)
+			assert.Equal(t, []string{"orders", "users"}, got.Sources)
+		})
+	}
+}
+
+func TestScanDeleteStatementForIssues_joins(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantTables []string
+		want       []string
+	}{
+		{
+			name:       "delete join",
+			query:      "delete o from orders o join users u on u.id = o.user_id where u.email = 'a'",
+			wantTables: []string{"orders"},
+			want:       []string{},
+		},
+		{
+			name:       "delete using",
+			query:      "delete from orders o using users u where u.id = o.user_id and u.email = 'a'",
+			wantTables: []string{"orders"},
+			want:       []string{},
+		},
+		{
+			name:       "delete using without an alias",
+			query:      "delete from orders using users u where u.id = orders.user_id and u.email = 'a'",
+			wantTables: []string{"orders"},
+			want:       []string{},
+		},
+		{
+			name:       "delete from both tables without a join predicate",
+			query:      "delete o, u from orders o, users u",
+			wantTables: []string{"orders", "users"},
+			want:       []string{issuetypes.QueryIssueTypeCartesianJoin},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleteStatement, err := parseDeleteStatement(tt.query, testSchema())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTables, deleteStatement.Tables)
+
+			got, err := ScanDeleteStatementForIssues(tt.query, testSchema(), EngineMySQL)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, issueTypes(got))
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package querylog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLineLength is the longest line the parsers read. Statements with large
+// literals can be several megabytes long.
+const maxLineLength = 64 * 1024 * 1024
+
+// ParseMySQLSlowLog reads a MySQL slow query log. Each entry starts with
+// comment lines such as
+//
+//	# Time: 2024-01-02T15:04:05.123456Z
+//	# User@Host: app[app] @ localhost []  Id:    12
+//	# Query_time: 2.000123  Lock_time: 0.000010 Rows_sent: 1  Rows_examined: 1000000
+//
+// followed by an optional use statement, SET timestamp and the statement,
+// which can span several lines.
+func ParseMySQLSlowLog(r io.Reader, handle func(Entry)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+
+	database := ""
+	entry := Entry{}
+	query := []string{}
+	inHeader := false
+
+	flush := func() {
+		statement := strings.TrimSpace(strings.Join(query, "\n"))
+		statement = strings.TrimSpace(strings.TrimSuffix(statement, ";"))
+		query = []string{}
+		if statement == "" {
+			return
+		}
+		entry.Query = statement
+		if entry.Database == "" {
+			entry.Database = database
+		}
+		handle(entry)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "#") {
+			if !inHeader {
+				flush()
+				entry = Entry{}
+				inHeader = true
+			}
+			if err := parseMySQLSlowLogComment(strings.TrimSpace(strings.TrimPrefix(line, "#")), &entry); err != nil {
+				return err
+			}
+			continue
+		}
+		inHeader = false
+
+		trimmed := strings.TrimSpace(line)
+		lowered := strings.ToLower(trimmed)
+		switch {
+		case len(query) == 0 && strings.HasPrefix(lowered, "use "):
+			database = strings.Trim(strings.TrimSuffix(trimmed[len("use "):], ";"), "` ")
+		case len(query) == 0 && strings.HasPrefix(lowered, "set timestamp="):
+			if seconds, err := strconv.ParseInt(strings.TrimSuffix(trimmed[len("set timestamp="):], ";"), 10, 64); err == nil {
+				entry.Time = time.Unix(seconds, 0).UTC()
+			}
+		case isMySQLServerHeader(trimmed):
+			// the server writes a header each time it opens the log
+		default:
+			query = append(query, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read slow log: %w", err)
+	}
+
+	flush()
+
+	return nil
+}
+
+// parseMySQLSlowLogComment reads the fields of a comment line into the entry.
+func parseMySQLSlowLogComment(comment string, entry *Entry) error {
+	switch {
+	case strings.HasPrefix(comment, "Time:"):
+		value := strings.TrimSpace(strings.TrimPrefix(comment, "Time:"))
+		for _, layout := range []string{time.RFC3339Nano, "060102 15:04:05", "060102  15:04:05"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				entry.Time = t
+				break
+			}
+		}
+		return nil
+	case strings.HasPrefix(comment, "User@Host:"):
+		value := strings.TrimSpace(strings.TrimPrefix(comment, "User@Host:"))
+		if end := strings.Index(value, "["); end > 0 {
+			entry.User = value[:end]
+		}
+		return nil
+	}
+
+	// the rest are "Name: value" pairs
+	fields := strings.Fields(comment)
+	for i := 0; i+1 < len(fields); i += 2 {
+		name, value := strings.TrimSuffix(fields[i], ":"), fields[i+1]
+
+		var err error
+		switch name {
+		case "Query_time":
+			entry.Duration, err = parseSeconds(value)
+		case "Lock_time":
+			entry.LockTime, err = parseSeconds(value)
+		case "Rows_sent":
+			entry.RowsSent, err = strconv.ParseInt(value, 10, 64)
+		case "Rows_examined":
+			entry.RowsExamined, err = strconv.ParseInt(value, 10, 64)
+		case "Schema":
+			// Percona Server and MariaDB write the database of the statement
+			entry.Database = value
+		}
+		if err != nil {
+			return fmt.Errorf("parse %s %q: %w", name, value, err)
+		}
+	}
+
+	return nil
+}
+
+func parseSeconds(value string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func isMySQLServerHeader(line string) bool {
+	return strings.Contains(line, "started with:") ||
+		strings.HasPrefix(line, "Tcp port:") ||
+		strings.HasPrefix(line, "Time ") && strings.Contains(line, "Command") && strings.Contains(line, "Argument")
+}
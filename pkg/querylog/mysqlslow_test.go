@@ -0,0 +1,70 @@
+package querylog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mysqlSlowLog = `/usr/sbin/mysqld, Version: 8.0.36 (MySQL Community Server - GPL). started with:
+Tcp port: 3306  Unix socket: /var/run/mysqld/mysqld.sock
+Time                 Id Command    Argument
+# Time: 2024-01-02T15:04:05.123456Z
+# User@Host: app[app] @ localhost []  Id:    12
+# Query_time: 2.500000  Lock_time: 0.000010 Rows_sent: 1  Rows_examined: 1000000
+use shop;
+SET timestamp=1704207845;
+SELECT *
+FROM orders
+WHERE status = 'open';
+# Time: 2024-01-02T15:04:06.000000Z
+# User@Host: app[app] @ localhost []  Id:    12
+# Query_time: 0.500000  Lock_time: 0.000000 Rows_sent: 3  Rows_examined: 3
+SET timestamp=1704207846;
+select * from users where id in (1, 2, 3);
+`
+
+func TestParseMySQLSlowLog(t *testing.T) {
+	entries := []Entry{}
+	err := ParseMySQLSlowLog(strings.NewReader(mysqlSlowLog), func(entry Entry) {
+		entries = append(entries, entry)
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, Entry{
+		Time:         time.Unix(1704207845, 0).UTC(),
+		Database:     "shop",
+		User:         "app",
+		Query:        "SELECT *\nFROM orders\nWHERE status = 'open'",
+		Duration:     2500 * time.Millisecond,
+		LockTime:     10 * time.Microsecond,
+		RowsSent:     1,
+		RowsExamined: 1000000,
+	}, entries[0])
+
+	assert.Equal(t, "select * from users where id in (1, 2, 3)", entries[1].Query)
+	assert.Equal(t, "shop", entries[1].Database)
+	assert.Equal(t, int64(3), entries[1].RowsExamined)
+}
+
+func TestAggregator(t *testing.T) {
+	aggregator := NewAggregator()
+	aggregator.Add(Entry{Query: "select * from users where id = 1", Duration: time.Second, RowsSent: 1, RowsExamined: 1})
+	aggregator.Add(Entry{Query: "SELECT * FROM users WHERE id = 2", Duration: 3 * time.Second, RowsSent: 1, RowsExamined: 1})
+	aggregator.Add(Entry{Query: "select * from orders", Duration: time.Second, RowsSent: 10, RowsExamined: 10})
+
+	statements := aggregator.Statements()
+	require.Len(t, statements, 2)
+
+	assert.Equal(t, "select * from users where id = 1", statements[0].Query)
+	assert.Equal(t, int64(2), statements[0].Calls)
+	assert.Equal(t, 4*time.Second, statements[0].TotalTime)
+	assert.Equal(t, 2*time.Second, statements[0].MeanTime)
+	assert.Equal(t, int64(2), statements[0].RowsExamined)
+
+	assert.Equal(t, "select * from orders", statements[1].Query)
+}
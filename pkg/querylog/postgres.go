@@ -0,0 +1,297 @@
+package querylog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/queryplan-ai/qp/pkg/lexer"
+)
+
+// postgresMessage is a message the server logged, with the detail line that
+// belongs to it.
+type postgresMessage struct {
+	time     time.Time
+	user     string
+	database string
+	severity string
+	message  string
+	detail   string
+}
+
+var (
+	postgresLineRegexp     = regexp.MustCompile(`^(.*?)\b(LOG|DETAIL|STATEMENT|HINT|CONTEXT|ERROR|WARNING|NOTICE|INFO|FATAL|PANIC|DEBUG[1-5]?):  (.*)$`)
+	postgresDurationRegexp = regexp.MustCompile(`(?s)^duration: ([\d.]+) ms\s+(statement|execute [^:]*|plan):\s*(.*)$`)
+	postgresTimeLayouts    = []string{"2006-01-02 15:04:05.000 MST", "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05.000 -07", "2006-01-02T15:04:05.000-07:00"}
+)
+
+// ParsePostgresLog reads a Postgres log written to stderr. The statements are
+// the messages that log_min_duration_statement and auto_explain write:
+//
+//	2024-01-02 15:04:05.123 UTC [42] LOG:  duration: 1520.123 ms  statement: select ...
+//	2024-01-02 15:04:05.123 UTC [42] LOG:  duration: 3.100 ms  execute <unnamed>: select ... where id = $1
+//	2024-01-02 15:04:05.123 UTC [42] DETAIL:  parameters: $1 = '42'
+//	2024-01-02 15:04:05.123 UTC [42] LOG:  duration: 1520.123 ms  plan:
+//		Query Text: select ...
+//		Seq Scan on orders  (cost=0.00..18334.00 rows=5 width=8) (actual ...)
+//
+// Messages that span several lines continue on lines that start with a tab.
+// The log_line_prefix can be anything that ends before the severity.
+func ParsePostgresLog(r io.Reader, handle func(Entry)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+
+	var pending *postgresMessage
+	var continued *string
+	flush := func() {
+		if pending != nil {
+			handlePostgresMessage(*pending, handle)
+		}
+		pending, continued = nil, nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "\t") {
+			if continued != nil {
+				*continued += "\n" + line[1:]
+			}
+			continue
+		}
+
+		matches := postgresLineRegexp.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		prefix, severity, text := matches[1], matches[2], matches[3]
+
+		if severity == "DETAIL" && pending != nil && pending.detail == "" {
+			pending.detail = text
+			continued = &pending.detail
+			continue
+		}
+		if severity == "DETAIL" || severity == "STATEMENT" || severity == "HINT" || severity == "CONTEXT" {
+			// these belong to a message that isn't a statement
+			continued = nil
+			continue
+		}
+
+		flush()
+		pending = &postgresMessage{
+			time:     parsePostgresPrefixTime(prefix),
+			severity: severity,
+			message:  text,
+		}
+		continued = &pending.message
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read postgres log: %w", err)
+	}
+
+	flush()
+
+	return nil
+}
+
+// ParsePostgresCSVLog reads a Postgres csvlog, where a message and its detail
+// are in the same record.
+func ParsePostgresCSVLog(r io.Reader, handle func(Entry)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read postgres csvlog: %w", err)
+		}
+
+		// log_time, user_name, database_name, ... error_severity is the 12th
+		// column, message the 14th and detail the 15th
+		if len(record) < 15 {
+			continue
+		}
+		t, _ := time.Parse("2006-01-02 15:04:05.000 MST", record[0])
+		handlePostgresMessage(postgresMessage{
+			time:     t,
+			user:     record[1],
+			database: record[2],
+			severity: record[11],
+			message:  record[13],
+			detail:   record[14],
+		}, handle)
+	}
+}
+
+// ParsePostgresJSONLog reads a Postgres jsonlog, which Postgres 15 and later
+// write with one JSON object per line.
+func ParsePostgresJSONLog(r io.Reader, handle func(Entry)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		record := struct {
+			Timestamp string `json:"timestamp"`
+			User      string `json:"user"`
+			DBName    string `json:"dbname"`
+			Severity  string `json:"error_severity"`
+			Message   string `json:"message"`
+			Detail    string `json:"detail"`
+		}{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return fmt.Errorf("unmarshal postgres jsonlog line: %w", err)
+		}
+
+		t, _ := time.Parse("2006-01-02 15:04:05.000 MST", record.Timestamp)
+		handlePostgresMessage(postgresMessage{
+			time:     t,
+			user:     record.User,
+			database: record.DBName,
+			severity: record.Severity,
+			message:  record.Message,
+			detail:   record.Detail,
+		}, handle)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read postgres jsonlog: %w", err)
+	}
+
+	return nil
+}
+
+// handlePostgresMessage calls handle with the entry of a message, if the
+// message is the duration of a statement or a plan.
+func handlePostgresMessage(message postgresMessage, handle func(Entry)) {
+	if message.severity != "LOG" {
+		return
+	}
+
+	matches := postgresDurationRegexp.FindStringSubmatch(message.message)
+	if matches == nil {
+		return
+	}
+	milliseconds, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return
+	}
+
+	entry := Entry{
+		Time:     message.time,
+		Database: message.database,
+		User:     message.user,
+		Duration: time.Duration(milliseconds * float64(time.Millisecond)),
+	}
+
+	kind, text := matches[2], strings.TrimSpace(matches[3])
+	if kind == "plan" {
+		entry.Plan = text
+		entry.Query = postgresPlanQuery(text)
+	} else {
+		entry.Query = text
+		entry.Parameters = parsePostgresParameters(message.detail)
+	}
+
+	entry.Query = strings.TrimSpace(strings.TrimSuffix(entry.Query, ";"))
+	if entry.Query == "" {
+		return
+	}
+
+	handle(entry)
+}
+
+// postgresPlanQuery returns the query of an auto_explain plan, which is its
+// first line in the text format, and the "Query Text" key in JSON.
+func postgresPlanQuery(plan string) string {
+	if strings.HasPrefix(plan, "{") {
+		explain := struct {
+			QueryText string `json:"Query Text"`
+		}{}
+		if err := json.Unmarshal([]byte(plan), &explain); err != nil {
+			return ""
+		}
+		return explain.QueryText
+	}
+
+	query := []string{}
+	for _, line := range strings.Split(plan, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if len(query) == 0 {
+			if !strings.HasPrefix(trimmed, "Query Text:") {
+				return ""
+			}
+			query = append(query, strings.TrimSpace(strings.TrimPrefix(trimmed, "Query Text:")))
+			continue
+		}
+		if strings.Contains(trimmed, "(cost=") || strings.Contains(trimmed, "(actual") {
+			break
+		}
+		query = append(query, line)
+	}
+
+	return strings.Join(query, "\n")
+}
+
+// parsePostgresParameters returns the values of the bind parameters in a
+// "parameters: $1 = '42', $2 = NULL" detail, in order. The values are SQL
+// literals, as they were logged. A value is all the text up to the next
+// parameter, so a signed number such as -5 is kept whole.
+func parsePostgresParameters(detail string) []string {
+	if !strings.HasPrefix(detail, "parameters:") {
+		return nil
+	}
+
+	text := strings.TrimPrefix(detail, "parameters:")
+	value := func(start int, end int) string {
+		return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text[start:end]), ","))
+	}
+
+	parameters := []string{}
+	tokens := lexer.Tokenize(text)
+	start := -1
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Type != lexer.Placeholder || !tokens[i+1].IsPunctuation("=") {
+			continue
+		}
+		if start >= 0 {
+			parameters = append(parameters, value(start, tokens[i].Pos))
+		}
+		start = tokens[i+1].End()
+		i++
+	}
+	if start >= 0 {
+		parameters = append(parameters, value(start, len(text)))
+	}
+
+	return parameters
+}
+
+// parsePostgresPrefixTime returns the time at the start of the log_line_prefix,
+// or the zero time if the prefix doesn't start with one.
+func parsePostgresPrefixTime(prefix string) time.Time {
+	fields := strings.Fields(prefix)
+	for n := min(len(fields), 3); n > 0; n-- {
+		value := strings.Join(fields[:n], " ")
+		for _, layout := range postgresTimeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,147 @@
+package querylog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const postgresLog = "2024-01-02 15:04:05.123 UTC [42] LOG:  database system is ready to accept connections\n" +
+	"2024-01-02 15:04:06.000 UTC [43] LOG:  duration: 1520.500 ms  statement: select *\n" +
+	"\tfrom orders\n" +
+	"\twhere status = 'open';\n" +
+	"2024-01-02 15:04:07.000 UTC [43] LOG:  duration: 3.000 ms  execute <unnamed>: select * from users where id = $1 and name = $2\n" +
+	"2024-01-02 15:04:07.000 UTC [43] DETAIL:  parameters: $1 = '42', $2 = 'it''s'\n" +
+	"2024-01-02 15:04:08.000 UTC [43] ERROR:  relation \"missing\" does not exist\n" +
+	"2024-01-02 15:04:08.000 UTC [43] STATEMENT:  select * from missing\n" +
+	"2024-01-02 15:04:09.000 UTC [43] LOG:  duration: 1500.000 ms  plan:\n" +
+	"\tQuery Text: select * from orders where status = 'open'\n" +
+	"\tSeq Scan on orders  (cost=0.00..18334.00 rows=5 width=8) (actual time=0.010..1499.000 rows=5 loops=1)\n" +
+	"\t  Filter: (status = 'open'::text)\n" +
+	"\t  Rows Removed by Filter: 999995\n"
+
+func TestParsePostgresLog(t *testing.T) {
+	entries := []Entry{}
+	err := ParsePostgresLog(strings.NewReader(postgresLog), func(entry Entry) {
+		entries = append(entries, entry)
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, Entry{
+		Time:     time.Date(2024, 1, 2, 15, 4, 6, 0, time.UTC),
+		Query:    "select *\nfrom orders\nwhere status = 'open'",
+		Duration: 1520500 * time.Microsecond,
+	}, entries[0])
+
+	assert.Equal(t, "select * from users where id = $1 and name = $2", entries[1].Query)
+	assert.Equal(t, []string{"'42'", "'it''s'"}, entries[1].Parameters)
+	assert.Equal(t, 3*time.Millisecond, entries[1].Duration)
+
+	assert.Equal(t, "select * from orders where status = 'open'", entries[2].Query)
+	assert.Contains(t, entries[2].Plan, "Rows Removed by Filter: 999995")
+	assert.Equal(t, 1500*time.Millisecond, entries[2].Duration)
+}
+
+func TestParsePostgresCSVLog(t *testing.T) {
+	log := `2024-01-02 15:04:06.000 UTC,"app","shop",43,"[local]",65943b7e.2b,1,"SELECT",2024-01-02 15:04:00 UTC,3/2,0,LOG,00000,"duration: 3.000 ms  execute <unnamed>: select * from users where id = $1","parameters: $1 = '42'",,,,,,,,"psql","client backend",,0
+2024-01-02 15:04:07.000 UTC,"app","shop",43,"[local]",65943b7e.2b,2,"idle",2024-01-02 15:04:00 UTC,3/3,0,ERROR,42P01,"relation ""missing"" does not exist",,,,,,"select * from missing",15,,"psql","client backend",,0
+`
+	entries := []Entry{}
+	err := ParsePostgresCSVLog(strings.NewReader(log), func(entry Entry) {
+		entries = append(entries, entry)
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	assert.Equal(t, Entry{
+		Time:       time.Date(2024, 1, 2, 15, 4, 6, 0, time.UTC),
+		Database:   "shop",
+		User:       "app",
+		Query:      "select * from users where id = $1",
+		Duration:   3 * time.Millisecond,
+		Parameters: []string{"'42'"},
+	}, entries[0])
+}
+
+func TestParsePostgresJSONLog(t *testing.T) {
+	log := `{"timestamp":"2024-01-02 15:04:06.000 UTC","user":"app","dbname":"shop","pid":43,"error_severity":"LOG","message":"duration: 12.500 ms  statement: select count(*) from orders"}
+{"timestamp":"2024-01-02 15:04:07.000 UTC","user":"app","dbname":"shop","pid":43,"error_severity":"LOG","message":"duration: 1500.000 ms  plan:\n{\n  \"Query Text\": \"select * from orders where status = 'open'\",\n  \"Plan\": {\"Node Type\": \"Seq Scan\"}\n}"}
+`
+	entries := []Entry{}
+	err := ParsePostgresJSONLog(strings.NewReader(log), func(entry Entry) {
+		entries = append(entries, entry)
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "select count(*) from orders", entries[0].Query)
+	assert.Equal(t, "shop", entries[0].Database)
+	assert.Equal(t, 12500*time.Microsecond, entries[0].Duration)
+
+	assert.Equal(t, "select * from orders where status = 'open'", entries[1].Query)
+	assert.True(t, strings.HasPrefix(entries[1].Plan, "{"))
+}
+
+func TestAggregatorPlans(t *testing.T) {
+	aggregator := NewAggregator()
+	aggregator.Add(Entry{Query: "select * from orders where id = 1", Duration: time.Second})
+	aggregator.Add(Entry{Query: "select * from orders where id = 2", Duration: 2 * time.Second, Plan: "slow plan"})
+	aggregator.Add(Entry{Query: "select * from orders where id = 3", Duration: time.Second, Plan: "fast plan"})
+	aggregator.Add(Entry{Query: "select * from users where id = 1", Duration: 500 * time.Millisecond, Plan: "users plan"})
+
+	statements := aggregator.Statements()
+	require.Len(t, statements, 2)
+
+	// the plans aren't counted when the statement was logged on its own
+	assert.Equal(t, "select * from orders where id = 1", statements[0].Query)
+	assert.Equal(t, int64(1), statements[0].Calls)
+	assert.Equal(t, "slow plan", statements[0].Plan)
+
+	assert.Equal(t, "select * from users where id = 1", statements[1].Query)
+	assert.Equal(t, int64(1), statements[1].Calls)
+	assert.Equal(t, "users plan", statements[1].Plan)
+}
+
+func Test_parsePostgresParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail string
+		want   []string
+	}{
+		{
+			name:   "quoted values",
+			detail: "parameters: $1 = '42', $2 = 'it''s, quoted'",
+			want:   []string{"'42'", "'it''s, quoted'"},
+		},
+		{
+			name:   "signed number and null",
+			detail: "parameters: $1 = -5, $2 = NULL, $3 = +1.5",
+			want:   []string{"-5", "NULL", "+1.5"},
+		},
+		{
+			name:   "not parameters",
+			detail: "Key (id)=(1) already exists.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parsePostgresParameters(tt.detail))
+		})
+	}
+}
+
+func TestAggregatorParameters(t *testing.T) {
+	aggregator := NewAggregator()
+	aggregator.Add(Entry{Query: "select * from users where id = $1", Duration: time.Second, Parameters: []string{"'42'"}})
+	aggregator.Add(Entry{Query: "select * from users where id = $1", Duration: time.Second, Parameters: []string{"'7'"}})
+
+	statements := aggregator.Statements()
+	require.Len(t, statements, 1)
+
+	// the parameters are those of the example query
+	assert.Equal(t, []string{"'42'"}, statements[0].Parameters)
+}
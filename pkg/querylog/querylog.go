@@ -0,0 +1,152 @@
+package querylog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/queryplan-ai/qp/pkg/fingerprint"
+	"github.com/queryplan-ai/qp/pkg/workload"
+)
+
+const (
+	FormatMySQLSlow = "mysql-slow"
+	// FormatPostgres is the stderr log, with any log_line_prefix
+	FormatPostgres     = "postgres"
+	FormatPostgresCSV  = "postgres-csv"
+	FormatPostgresJSON = "postgres-json"
+)
+
+// Formats are the log formats that can be parsed.
+var Formats = []string{
+	FormatMySQLSlow,
+	FormatPostgres,
+	FormatPostgresCSV,
+	FormatPostgresJSON,
+}
+
+// Entry is one execution of a statement read from a log.
+type Entry struct {
+	Time     time.Time
+	Database string
+	User     string
+	Query    string
+	Duration time.Duration
+	LockTime time.Duration
+	// RowsSent and RowsExamined are only in MySQL logs
+	RowsSent     int64
+	RowsExamined int64
+	// Parameters are the values of the bind parameters of a Postgres
+	// prepared statement, as SQL literals
+	Parameters []string
+	// Plan is the plan auto_explain logged for the statement, in the text or
+	// JSON format
+	Plan string
+}
+
+// Engine returns the database engine that writes logs in a format.
+func Engine(format string) string {
+	switch format {
+	case FormatMySQLSlow:
+		return "mysql"
+	case FormatPostgres, FormatPostgresCSV, FormatPostgresJSON:
+		return "postgres"
+	}
+	return ""
+}
+
+// Parse reads a log in a format, calling handle for each entry as it's read,
+// so logs larger than memory can be parsed.
+func Parse(format string, r io.Reader, handle func(Entry)) error {
+	switch format {
+	case FormatMySQLSlow:
+		return ParseMySQLSlowLog(r, handle)
+	case FormatPostgres:
+		return ParsePostgresLog(r, handle)
+	case FormatPostgresCSV:
+		return ParsePostgresCSVLog(r, handle)
+	case FormatPostgresJSON:
+		return ParsePostgresJSONLog(r, handle)
+	}
+
+	return fmt.Errorf("unsupported log format %q, use one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Aggregator groups the entries of a log by the fingerprint of their query,
+// the way the database's statement statistics do.
+type Aggregator struct {
+	statements map[string]*aggregate
+}
+
+// aggregate is a statement of the log. Postgres logs the duration of a
+// statement and its auto_explain plan as separate entries, so the entries with
+// a plan are only counted when the statement has no others.
+type aggregate struct {
+	statement   workload.Statement
+	explained   workload.Statement
+	slowestPlan time.Duration
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		statements: map[string]*aggregate{},
+	}
+}
+
+// Add counts an entry towards the statement with the same fingerprint. The
+// first query of each fingerprint is kept as its example, with its bind
+// parameters, and the plan of its slowest execution.
+func (a *Aggregator) Add(entry Entry) {
+	queryID := fingerprint.Fingerprint(entry.Query)
+
+	aggregated, ok := a.statements[queryID]
+	if !ok {
+		aggregated = &aggregate{}
+		a.statements[queryID] = aggregated
+	}
+
+	statement := &aggregated.statement
+	if entry.Plan != "" {
+		statement = &aggregated.explained
+		if aggregated.slowestPlan == 0 || entry.Duration > aggregated.slowestPlan {
+			aggregated.slowestPlan = entry.Duration
+			aggregated.statement.Plan = entry.Plan
+		}
+	}
+	if statement.Query == "" {
+		statement.QueryID = queryID
+		statement.Query = entry.Query
+		statement.Parameters = entry.Parameters
+	}
+
+	statement.Calls++
+	statement.TotalTime += entry.Duration
+	statement.MeanTime = statement.TotalTime / time.Duration(statement.Calls)
+	statement.Rows += entry.RowsSent
+	statement.RowsExamined += entry.RowsExamined
+}
+
+// Statements returns the aggregated statements, ordered by total time.
+func (a *Aggregator) Statements() []workload.Statement {
+	statements := []workload.Statement{}
+	for _, aggregated := range a.statements {
+		statement := aggregated.statement
+		if statement.Calls == 0 {
+			plan := statement.Plan
+			statement = aggregated.explained
+			statement.Plan = plan
+		}
+		statements = append(statements, statement)
+	}
+
+	sort.Slice(statements, func(i, j int) bool {
+		if statements[i].TotalTime != statements[j].TotalTime {
+			return statements[i].TotalTime > statements[j].TotalTime
+		}
+		return statements[i].QueryID < statements[j].QueryID
+	})
+
+	return statements
+}
@@ -0,0 +1,111 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chzyer/readline"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+)
+
+// command is a shell command. The help is generated from the commands, so a
+// new command only has to be added to the registry.
+type command struct {
+	name    string
+	aliases []string
+	// usage describes the arguments, if the command takes any
+	usage       string
+	description string
+	handler     func(sh *types.Shell, args string) *types.ShellCommandResult
+}
+
+// commands is the registry of shell commands, in the order /help lists them.
+// It's set in init because /help refers to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "/help", aliases: []string{"/?"}, description: "Show this help", handler: handleHelp},
+		{name: "/connect", usage: "[name] <uri | @profile>", description: "Connect to a database and load its schema, replacing the active connection unless a name is given", handler: handleConnect},
+		{name: "/use", usage: "[name]", description: "Switch to a named connection, or list the connections", handler: handleUse},
+		{name: "/disconnect", usage: "[name]", description: "Close the active connection, or the one named", handler: handleDisconnect},
+		{name: "/status", description: "Show the connection, the schema and the session state", handler: handleStatus},
+		{name: "/reload-schema", description: "Load the schema of the database again", handler: handleReloadSchema},
+		{name: "/tables", usage: "[filter]", description: "List the tables with their estimated row counts", handler: handleTables},
+		{name: "/describe", usage: "<table>", description: "Show the columns and keys of a table", handler: handleDescribe},
+		{name: "/indexes", usage: "<table>", description: "Show the indexes and foreign keys of a table", handler: handleIndexes},
+		{name: "/batch", usage: "<file>", description: "Plan the transactions in a SQL file", handler: handleBatch},
+		{name: "/top", usage: "[n] [order]", description: "Plan the statements the database spends the most time on", handler: handleTop},
+		{name: "/params", usage: "[value...]", description: "Set the parameter values /explain uses, or clear them", handler: handleParams},
+		{name: "/explain", usage: "<query>", description: "Show the plan the database picks for a query", handler: handleExplain},
+		{name: "/compare", usage: "<name> <name> <query>", description: "Plan a query on two connections side by side", handler: handleCompare},
+		{name: "/run", usage: "<query>", description: "Run a query and show its timing and first rows, rolling back any changes", handler: handleRun},
+		{name: "/timing", usage: "on [runs] | off", description: "Run queries several times with /run and show their latency", handler: handleTiming},
+		{name: "/clear", description: "Clear the screen", handler: handleClear},
+		{name: "/exit", aliases: []string{"/quit"}, description: "Exit the shell", handler: handleExit},
+	}
+}
+
+// findCommand returns the command with a name or an alias.
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+		for _, alias := range c.aliases {
+			if alias == name {
+				return c, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+func handleHelp(sh *types.Shell, args string) *types.ShellCommandResult {
+	var b strings.Builder
+	b.WriteString("Type a query ending with ; to plan it, or BEGIN; to plan the statements up to COMMIT as a transaction.\n\n")
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		usage := c.name
+		if c.usage != "" {
+			usage += " " + c.usage
+		}
+		description := c.description
+		if len(c.aliases) > 0 {
+			description += fmt.Sprintf(" (also %s)", strings.Join(c.aliases, ", "))
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", usage, description)
+	}
+	w.Flush()
+
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+		Message:   strings.TrimSuffix(b.String(), "\n"),
+	}
+}
+
+func showUnknownCommand(name string) *types.ShellCommandResult {
+	return &types.ShellCommandResult{
+		IsSuccess: false,
+		IsFatal:   false,
+		Message:   fmt.Sprintf("unknown command %s, use /help to list the commands", name),
+	}
+}
+
+func handleExit(sh *types.Shell, args string) *types.ShellCommandResult {
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+		IsFatal:   true,
+	}
+}
+
+func handleClear(sh *types.Shell, args string) *types.ShellCommandResult {
+	readline.ClearScreen(os.Stdout)
+
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+	}
+}
@@ -0,0 +1,36 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessShellCommand(t *testing.T) {
+	sh := &types.Shell{}
+
+	result := processShellCommand(sh, "/help")
+	require.True(t, result.IsSuccess)
+	for _, c := range commands {
+		assert.Contains(t, result.Message, c.name)
+	}
+
+	result = processShellCommand(sh, "/?")
+	assert.True(t, result.IsSuccess)
+
+	result = processShellCommand(sh, "/nope")
+	assert.False(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "unknown command /nope")
+
+	result = processShellCommand(sh, "/quit")
+	assert.True(t, result.IsFatal)
+
+	result = processShellCommand(sh, "/status")
+	assert.True(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "Not connected")
+
+	result = processShellCommand(sh, "/disconnect")
+	assert.False(t, result.IsSuccess)
+}
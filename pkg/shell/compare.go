@@ -0,0 +1,209 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+)
+
+// compareColumnWidth is the width of each side of /compare.
+const compareColumnWidth = 60
+
+// handleCompare plans a query on two connections and shows the plans side by
+// side, followed by the differences between the schemas of the tables the
+// query uses, which usually explain why the plans differ: /compare prod
+// staging <query>.
+func handleCompare(sh *types.Shell, args string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) < 3 {
+		result.Message = "usage: /compare <connection> <connection> <query>"
+		return result
+	}
+	query := strings.TrimSpace(args)
+	for _, name := range fields[:2] {
+		query = strings.TrimSpace(strings.TrimPrefix(query, name))
+	}
+	query = strings.TrimSuffix(query, ";")
+
+	connections := []*types.Connection{}
+	for _, name := range fields[:2] {
+		connection, ok := sh.Connections[name]
+		if !ok {
+			result.Message = fmt.Sprintf("no connection named %s, use /use to list the connections", name)
+			return result
+		}
+		if message := schemaNotReady(connection.DB); message != "" {
+			result.Message = fmt.Sprintf("%s: %s", name, message)
+			return result
+		}
+		connections = append(connections, connection)
+	}
+	if !isQuery(query) {
+		result.Message = "not a valid query"
+		return result
+	}
+
+	plans := []string{}
+	for _, connection := range connections {
+		message, err := db.PlanQuery(connection.DB, query)
+		if err != nil {
+			message = fmt.Sprintf("Error planning query: %s", err)
+		}
+		plans = append(plans, strings.TrimSuffix(message, "\n"))
+	}
+
+	left, right := connections[0], connections[1]
+
+	var b strings.Builder
+	if plans[0] == plans[1] {
+		fmt.Fprintf(&b, "Same plan on %s and %s:\n%s\n", left.Name, right.Name, plans[0])
+	} else {
+		b.WriteString(sideBySide(left.Name, plans[0], right.Name, plans[1], compareColumnWidth))
+	}
+
+	differences := schemaDifferences(query, left, right)
+	if len(differences) > 0 {
+		b.WriteString("\nSchema differences:\n")
+		for _, difference := range differences {
+			fmt.Fprintf(&b, "  %s\n", difference)
+		}
+	}
+
+	result.IsSuccess = true
+	result.Message = strings.TrimSuffix(b.String(), "\n")
+	return result
+}
+
+// schemaDifferences describes how the tables a query uses differ between two
+// connections: tables and columns that only one has, row estimates, primary
+// keys, and indexes, which are told apart by their columns since their names
+// often differ between environments.
+func schemaDifferences(query string, left *types.Connection, right *types.Connection) []string {
+	differences := []string{}
+
+	seen := map[string]bool{}
+	for _, name := range referencedNames(lexer.Tokenize(query)) {
+		if seen[strings.ToLower(name.table)] {
+			continue
+		}
+		seen[strings.ToLower(name.table)] = true
+
+		leftTable, leftOK := findTable(left.DB.Tables, name.table)
+		rightTable, rightOK := findTable(right.DB.Tables, name.table)
+		switch {
+		case !leftOK && !rightOK:
+			continue
+		case !leftOK:
+			differences = append(differences, fmt.Sprintf("%s: only on %s", name.table, right.Name))
+			continue
+		case !rightOK:
+			differences = append(differences, fmt.Sprintf("%s: only on %s", name.table, left.Name))
+			continue
+		}
+
+		tableName := leftTable.GetName()
+		if leftTable.GetEstimatedRowCount() != rightTable.GetEstimatedRowCount() {
+			differences = append(differences, fmt.Sprintf("%s: about %d rows on %s, %d on %s",
+				tableName, leftTable.GetEstimatedRowCount(), left.Name, rightTable.GetEstimatedRowCount(), right.Name))
+		}
+
+		leftKey := strings.Join(leftTable.GetPrimaryKeys(), ", ")
+		rightKey := strings.Join(rightTable.GetPrimaryKeys(), ", ")
+		if leftKey != rightKey {
+			differences = append(differences, fmt.Sprintf("%s: primary key (%s) on %s, (%s) on %s",
+				tableName, leftKey, left.Name, rightKey, right.Name))
+		}
+
+		for _, column := range onlyIn(columnNames(leftTable), columnNames(rightTable)) {
+			differences = append(differences, fmt.Sprintf("%s.%s: only on %s", tableName, column, left.Name))
+		}
+		for _, column := range onlyIn(columnNames(rightTable), columnNames(leftTable)) {
+			differences = append(differences, fmt.Sprintf("%s.%s: only on %s", tableName, column, right.Name))
+		}
+
+		for _, index := range onlyIn(indexSignatures(leftTable), indexSignatures(rightTable)) {
+			differences = append(differences, fmt.Sprintf("%s: %s only on %s", tableName, index, left.Name))
+		}
+		for _, index := range onlyIn(indexSignatures(rightTable), indexSignatures(leftTable)) {
+			differences = append(differences, fmt.Sprintf("%s: %s only on %s", tableName, index, right.Name))
+		}
+	}
+
+	return differences
+}
+
+// indexSignatures describes the indexes of a table by their columns.
+func indexSignatures(table dbtypes.Table) []string {
+	signatures := []string{}
+	for _, index := range table.GetIndexes() {
+		kind := "index"
+		if index.IsUnique {
+			kind = "unique index"
+		}
+		signatures = append(signatures, fmt.Sprintf("%s on (%s)", kind, strings.Join(index.Columns, ", ")))
+	}
+	return signatures
+}
+
+// onlyIn returns the sorted values of a that aren't in b.
+func onlyIn(a []string, b []string) []string {
+	values := []string{}
+	for _, value := range a {
+		if !contains(b, value) && !contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// sideBySide lays out two texts in columns, wrapping their lines to the width.
+func sideBySide(leftTitle string, left string, rightTitle string, right string, width int) string {
+	leftLines := append([]string{leftTitle, strings.Repeat("-", len(leftTitle))}, wrap(left, width)...)
+	rightLines := append([]string{rightTitle, strings.Repeat("-", len(rightTitle))}, wrap(right, width)...)
+
+	var b strings.Builder
+	for i := 0; i < max(len(leftLines), len(rightLines)); i++ {
+		leftLine, rightLine := "", ""
+		if i < len(leftLines) {
+			leftLine = leftLines[i]
+		}
+		if i < len(rightLines) {
+			rightLine = rightLines[i]
+		}
+		line := fmt.Sprintf("%-*s  %s", width, leftLine, rightLine)
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return b.String()
+}
+
+// wrap breaks the lines of a text at spaces so that they fit in the width.
+// Words longer than the width are kept whole.
+func wrap(text string, width int) []string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		current := ""
+		for _, word := range strings.Fields(line) {
+			if current != "" && len(current)+1+len(word) > width {
+				lines = append(lines, current)
+				current = "  "
+			}
+			if strings.TrimSpace(current) != "" {
+				current += " "
+			}
+			current += word
+		}
+		lines = append(lines, current)
+	}
+	return lines
+}
@@ -0,0 +1,140 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/queryplan-ai/qp/pkg/config"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/pg"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConnectionsShell() *types.Shell {
+	orders := func(rowCount int64, indexes []dbtypes.Index) pg.PostgresTable {
+		return pg.PostgresTable{
+			TableName:         "orders",
+			EstimatedRowCount: rowCount,
+			Columns: []pg.PostgresColumn{
+				{ColumnName: "id", DataType: "integer"},
+				{ColumnName: "user_id", DataType: "integer"},
+			},
+			PrimaryKeys: []string{"id"},
+			Indexes:     indexes,
+		}
+	}
+
+	prod := &types.Connection{
+		Name:           "prod",
+		DatabaseName:   "app",
+		DatabaseEngine: "postgres",
+		DB: &dbtypes.DB{
+			Engine:       "postgres",
+			SchemaLoaded: true,
+			Tables: []dbtypes.Table{
+				orders(1000000, []dbtypes.Index{{Name: "orders_user_id", Columns: []string{"user_id"}}}),
+			},
+		},
+	}
+	staging := &types.Connection{
+		Name:           "staging",
+		DatabaseName:   "app_staging",
+		DatabaseEngine: "postgres",
+		DB: &dbtypes.DB{
+			Engine:       "postgres",
+			SchemaLoaded: true,
+			Tables: []dbtypes.Table{
+				orders(1000000, nil),
+			},
+		},
+	}
+
+	sh := &types.Shell{
+		Connections: map[string]*types.Connection{
+			"prod":    prod,
+			"staging": staging,
+		},
+	}
+	useConnection(sh, prod)
+	return sh
+}
+
+func TestUseConnection(t *testing.T) {
+	sh := testConnectionsShell()
+	assert.Equal(t, "prod postgres/app >>> ", prompt(sh))
+
+	result := processShellCommand(sh, "/use")
+	require.True(t, result.IsSuccess)
+	assert.Equal(t, "* prod: postgres/app\n  staging: postgres/app_staging", result.Message)
+
+	result = processShellCommand(sh, "/use staging")
+	require.True(t, result.IsSuccess)
+	assert.Equal(t, "staging", sh.ConnectionName)
+	assert.Equal(t, "app_staging", sh.DatabaseName)
+	assert.Equal(t, "staging postgres/app_staging >>> ", prompt(sh))
+
+	result = processShellCommand(sh, "/use nope")
+	assert.False(t, result.IsSuccess)
+	assert.Equal(t, "staging", sh.ConnectionName)
+
+	result = processShellCommand(sh, "/disconnect staging")
+	require.True(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "using prod")
+	assert.Equal(t, "prod", sh.ConnectionName)
+
+	result = processShellCommand(sh, "/disconnect")
+	require.True(t, result.IsSuccess)
+	assert.Nil(t, sh.DB)
+	assert.Empty(t, sh.ConnectionName)
+}
+
+func TestUseConnectionInTransaction(t *testing.T) {
+	sh := testConnectionsShell()
+	sh.Transaction = []string{"update orders set user_id = 1 where id = 1"}
+
+	result := processShellCommand(sh, "/use staging")
+	assert.False(t, result.IsSuccess)
+	assert.Equal(t, "prod", sh.ConnectionName)
+}
+
+func TestConnectInTransaction(t *testing.T) {
+	sh := testConnectionsShell()
+	sh.Transaction = []string{"update orders set user_id = 1 where id = 1"}
+
+	result := processShellCommand(sh, "/connect other postgres://localhost/other")
+	assert.False(t, result.IsSuccess)
+	assert.Equal(t, inTransactionMessage, result.Message)
+	assert.Equal(t, "prod", sh.ConnectionName)
+
+	result = connectSnapshot(sh, "ci", config.Profile{Schema: "schema.json"})
+	assert.False(t, result.IsSuccess)
+	assert.Equal(t, inTransactionMessage, result.Message)
+	assert.Equal(t, "prod", sh.ConnectionName)
+	assert.Len(t, sh.Connections, 2)
+}
+
+func TestHandleCompare(t *testing.T) {
+	sh := testConnectionsShell()
+
+	result := processShellCommand(sh, "/compare prod staging select * from orders where user_id = 1")
+	require.True(t, result.IsSuccess, result.Message)
+	lines := strings.Split(result.Message, "\n")
+	assert.Regexp(t, `^prod\s+staging$`, lines[0])
+	assert.Regexp(t, `No issues found\s+where clause contains a column that is not indexed`, lines[2])
+	assert.Contains(t, result.Message, "Schema differences:\n  orders: index on (user_id) only on prod")
+
+	result = processShellCommand(sh, "/compare prod nope select 1")
+	assert.False(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "no connection named nope")
+
+	result = processShellCommand(sh, "/compare prod staging")
+	assert.False(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "usage")
+}
+
+func TestSideBySide(t *testing.T) {
+	assert.Equal(t, "a           b\n-           -\none two     four\n  three\n",
+		sideBySide("a", "one two three", "b", "four", 10))
+}
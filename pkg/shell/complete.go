@@ -0,0 +1,289 @@
+package shell
+
+import (
+	"sort"
+	"strings"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+)
+
+// sqlKeywords are the keywords offered when completing a statement.
+var sqlKeywords = []string{
+	"ALTER", "AND", "AS", "ASC", "BEGIN", "BETWEEN", "BY", "CASE", "COMMIT", "COUNT", "CREATE",
+	"DELETE", "DESC", "DISTINCT", "DROP", "ELSE", "END", "EXISTS", "FOR", "FROM", "GROUP",
+	"HAVING", "IN", "INDEX", "INNER", "INSERT", "INTO", "IS", "JOIN", "LEFT", "LIKE", "LIMIT",
+	"NOT", "NULL", "OFFSET", "ON", "OR", "ORDER", "OUTER", "RETURNING", "RIGHT", "ROLLBACK",
+	"SELECT", "SET", "TABLE", "THEN", "UNION", "UPDATE", "USING", "VALUES", "WHEN", "WHERE", "WITH",
+}
+
+// tableKeywords are the keywords that are followed by a table name.
+var tableKeywords = map[string]bool{
+	"from": true, "join": true, "update": true, "into": true, "table": true,
+}
+
+// tableCommands are the shell commands that take a table name.
+var tableCommands = map[string]bool{
+	"/describe": true, "/indexes": true,
+}
+
+// connectionCommands are the shell commands that take a connection name.
+var connectionCommands = map[string]bool{
+	"/use": true, "/disconnect": true,
+}
+
+// queryCommands are the shell commands that take a query.
+var queryCommands = map[string]bool{
+	"/explain": true, "/run": true,
+}
+
+// completer completes shell commands, SQL keywords, and the names of the
+// tables and columns of the schema. The schema is read when completing, so
+// the names follow the schema as it's loaded and reloaded.
+type completer struct {
+	sh *types.Shell
+}
+
+// Do implements readline.AutoCompleter. It returns the rest of each candidate
+// for the word before the cursor, and the length of that word.
+func (c *completer) Do(line []rune, pos int) ([][]rune, int) {
+	text := string(line[:pos])
+
+	word := text[wordStart(text):]
+	candidates := c.candidates(text[:len(text)-len(word)], word)
+
+	completions := [][]rune{}
+	for _, candidate := range candidates {
+		completions = append(completions, []rune(candidate[len(word):]+" "))
+	}
+	return completions, len([]rune(word))
+}
+
+// candidates returns the names that complete a word, given the text before it.
+func (c *completer) candidates(before string, word string) []string {
+	if strings.HasPrefix(strings.TrimLeft(before+word, " "), "/") {
+		fields := strings.Fields(before)
+		if len(fields) == 0 {
+			return matching(commandNames(), word, false)
+		}
+		if tableCommands[fields[0]] && len(fields) == 1 {
+			return matching(c.tableNames(), word, false)
+		}
+		if connectionCommands[fields[0]] && len(fields) == 1 || fields[0] == "/compare" && len(fields) < 3 {
+			return matching(connectionNames(c.sh), word, false)
+		}
+		if fields[0] == "/compare" {
+			// the query follows the two connection names
+			before = strings.Join(strings.SplitN(strings.TrimLeft(before, " "), " ", 3)[2:], "")
+		} else if queryCommands[fields[0]] {
+			before = strings.TrimPrefix(strings.TrimLeft(before, " "), fields[0])
+		} else {
+			return nil
+		}
+	}
+
+	// only the statement the cursor is in matters
+	statement := before
+	for _, token := range lexer.Tokenize(before) {
+		if token.IsPunctuation(";") {
+			statement = before[token.End():]
+		}
+	}
+	tokens := lexer.Tokenize(statement)
+
+	if qualifier, name, ok := strings.Cut(word, "."); ok {
+		table := c.resolveTable(tokens, qualifier)
+		if table == nil {
+			return nil
+		}
+		names := matching(columnNames(table), name, false)
+		for i := range names {
+			names[i] = qualifier + "." + names[i]
+		}
+		return names
+	}
+
+	if len(tokens) > 0 && expectsTable(tokens) {
+		return matching(c.tableNames(), word, false)
+	}
+
+	columns := []string{}
+	for _, table := range c.mentionedTables(tokens) {
+		columns = append(columns, columnNames(table)...)
+	}
+	return append(matching(columns, word, false), matching(sqlKeywords, word, true)...)
+}
+
+// expectsTable returns true if a statement ends where a table name goes: after
+// FROM, JOIN, UPDATE, INTO or TABLE, or after a comma in the FROM list.
+func expectsTable(tokens []lexer.Token) bool {
+	last := tokens[len(tokens)-1]
+	if last.Type == lexer.Word {
+		return tableKeywords[strings.ToLower(last.Value)]
+	}
+	if !last.IsPunctuation(",") {
+		return false
+	}
+
+	// a comma continues the FROM list if there's no other clause after FROM
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].Type != lexer.Word {
+			continue
+		}
+		switch strings.ToLower(tokens[i].Value) {
+		case "from":
+			return true
+		case "select", "where", "on", "set", "values", "by", "having":
+			return false
+		}
+	}
+	return false
+}
+
+// mentionedTables returns the tables of the schema a statement names, and the
+// tables of their aliases.
+func (c *completer) mentionedTables(tokens []lexer.Token) []dbtypes.Table {
+	tables := []dbtypes.Table{}
+	for _, name := range referencedNames(tokens) {
+		if table, ok := findTable(c.tables(), name.table); ok {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// resolveTable returns the table a qualifier is the name or the alias of.
+func (c *completer) resolveTable(tokens []lexer.Token, qualifier string) dbtypes.Table {
+	for _, name := range referencedNames(tokens) {
+		if strings.EqualFold(name.alias, qualifier) || strings.EqualFold(name.table, qualifier) {
+			if table, ok := findTable(c.tables(), name.table); ok {
+				return table
+			}
+		}
+	}
+
+	if table, ok := findTable(c.tables(), qualifier); ok {
+		return table
+	}
+	return nil
+}
+
+type referencedName struct {
+	table string
+	alias string
+}
+
+// referencedNames returns the names that follow the table keywords, with the
+// alias that follows each name, if any.
+func referencedNames(tokens []lexer.Token) []referencedName {
+	names := []referencedName{}
+	for i := 0; i < len(tokens)-1; i++ {
+		isTableKeyword := tokens[i].Type == lexer.Word && tableKeywords[strings.ToLower(tokens[i].Value)]
+		inFromList := tokens[i].IsPunctuation(",") && expectsTable(tokens[:i+1])
+		if !isTableKeyword && !inFromList {
+			continue
+		}
+
+		next := tokens[i+1]
+		if next.Type != lexer.Word && next.Type != lexer.QuotedIdentifier {
+			continue
+		}
+		name := referencedName{table: strings.Trim(next.Value, "`\"")}
+
+		j := i + 2
+		if j < len(tokens) && tokens[j].Is("as") {
+			j++
+		}
+		if j < len(tokens) && (tokens[j].Type == lexer.Word || tokens[j].Type == lexer.QuotedIdentifier) && !isKeyword(tokens[j].Value) {
+			name.alias = strings.Trim(tokens[j].Value, "`\"")
+		}
+
+		names = append(names, name)
+	}
+	return names
+}
+
+func isKeyword(word string) bool {
+	for _, keyword := range sqlKeywords {
+		if strings.EqualFold(keyword, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// tables returns the tables of the schema, or none while it's loading.
+func (c *completer) tables() []dbtypes.Table {
+	if c.sh.DB == nil || !c.sh.DB.SchemaLoaded || c.sh.DB.SchemaLoading {
+		return nil
+	}
+	return c.sh.DB.Tables
+}
+
+func (c *completer) tableNames() []string {
+	names := []string{}
+	for _, table := range c.tables() {
+		names = append(names, table.GetName())
+	}
+	return names
+}
+
+func columnNames(table dbtypes.Table) []string {
+	names := []string{}
+	for _, column := range table.GetColumns() {
+		names = append(names, column.GetName())
+	}
+	return names
+}
+
+func commandNames() []string {
+	names := []string{}
+	for _, c := range commands {
+		names = append(names, c.name)
+		names = append(names, c.aliases...)
+	}
+	return names
+}
+
+// matching returns the sorted, distinct names that start with a prefix,
+// ignoring case. Keywords are returned in the case of the prefix, so that
+// completing "sel" gives "select".
+func matching(names []string, prefix string, keywords bool) []string {
+	lowerPrefix := prefix != "" && prefix == strings.ToLower(prefix)
+
+	seen := map[string]bool{}
+	matches := []string{}
+	for _, name := range names {
+		if len(name) < len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) {
+			continue
+		}
+		if keywords && lowerPrefix {
+			name = strings.ToLower(name)
+		}
+		// the typed prefix is kept as it is
+		name = prefix + name[len(prefix):]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		matches = append(matches, name)
+	}
+
+	sort.Strings(matches)
+	return matches
+}
+
+// wordStart returns the offset of the word that ends the text.
+func wordStart(text string) int {
+	i := len(text)
+	for i > 0 {
+		c := text[i-1]
+		if c != '_' && c != '.' && c != '$' && c != '/' &&
+			!(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c < 0x80 {
+			break
+		}
+		i--
+	}
+	return i
+}
@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleter(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{
+			name: "commands",
+			line: "/de",
+			want: []string{"/describe"},
+		},
+		{
+			name: "table argument of a command",
+			line: "/indexes us",
+			want: []string{"users"},
+		},
+		{
+			name: "keywords in the case they are typed",
+			line: "sel",
+			want: []string{"select"},
+		},
+		{
+			name: "tables after from",
+			line: "select * from ",
+			want: []string{"orders", "users"},
+		},
+		{
+			name: "tables after join",
+			line: "select * from orders o join u",
+			want: []string{"users"},
+		},
+		{
+			name: "tables after a comma in the from list",
+			line: "select * from orders, u",
+			want: []string{"users"},
+		},
+		{
+			name: "columns of the mentioned tables",
+			line: "select * from orders where us",
+			want: []string{"user_id", "using"},
+		},
+		{
+			name: "columns of an alias",
+			line: "select * from orders o join users u on u.",
+			want: []string{"u.email", "u.id"},
+		},
+		{
+			name: "columns of the tables after the cursor aren't known",
+			line: "select em",
+			want: []string{},
+		},
+		{
+			name: "only the current statement counts",
+			line: "select * from users; select * from orders where em",
+			want: []string{},
+		},
+		{
+			name: "statements after /explain",
+			line: "/explain update ",
+			want: []string{"orders", "users"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &completer{sh: testSchemaShell()}
+			line := []rune(test.line)
+			completions, length := c.Do(line, len(line))
+
+			word := line[len(line)-length:]
+			got := []string{}
+			for _, completion := range completions {
+				got = append(got, string(word)+string(completion))
+			}
+
+			want := []string{}
+			for _, name := range test.want {
+				want = append(want, name+" ")
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestCompleterWithoutSchema(t *testing.T) {
+	c := &completer{sh: &types.Shell{}}
+	line := []rune("select * from ")
+	completions, _ := c.Do(line, len(line))
+	assert.Empty(t, completions)
+}
@@ -3,7 +3,11 @@ package shell
 import (
 	"fmt"
 	"net/url"
+	"os"
+	"sort"
+	"strings"
 
+	"github.com/queryplan-ai/qp/pkg/config"
 	"github.com/queryplan-ai/qp/pkg/db"
 	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
 	"github.com/queryplan-ai/qp/pkg/shell/types"
@@ -13,12 +17,72 @@ var (
 	ErrUnsupportedScheme = fmt.Errorf("unsupported connection scheme")
 )
 
+// defaultConnectionName is the name of a connection opened without one.
+const defaultConnectionName = "default"
+
+// inTransactionMessage is why the active connection can't change while the
+// statements of a transaction are collected: they're planned on COMMIT
+// against the connection they were entered on.
+const inTransactionMessage = "in a transaction, use COMMIT to plan it or ROLLBACK to discard it first"
+
+// handleConnect opens a connection and makes it the active one: /connect <uri>
+// replaces the active connection, and /connect <name> <uri> opens a connection
+// with a name, next to the others. /connect @profile connects with a profile of
+// the config file, and is named after it unless a name is given.
 func handleConnect(sh *types.Shell, cmd string) *types.ShellCommandResult {
 	result := &types.ShellCommandResult{
 		IsFatal:   false,
 		IsSuccess: false,
 	}
 
+	if sh.Transaction != nil {
+		result.Message = inTransactionMessage
+		return result
+	}
+
+	name := ""
+	fields := strings.Fields(cmd)
+	switch len(fields) {
+	case 1:
+		cmd = fields[0]
+	case 2:
+		name, cmd = fields[0], fields[1]
+	default:
+		result.Message = "usage: /connect [name] <uri | @profile>"
+		return result
+	}
+
+	issueFilter := sh.IssueFilter
+	if strings.HasPrefix(cmd, "@") {
+		profileName := strings.TrimPrefix(cmd, "@")
+		if name == "" {
+			name = profileName
+		}
+
+		profile, err := loadProfile(profileName)
+		if err != nil {
+			result.Message = err.Error()
+			return result
+		}
+		issueFilter = profile.IssueFilter()
+
+		cmd, err = profile.ConnectionURI()
+		if err != nil {
+			result.Message = fmt.Sprintf("Error in profile %s: %s", profileName, err)
+			return result
+		}
+		if cmd == "" {
+			return connectSnapshot(sh, name, profile)
+		}
+	}
+
+	if name == "" {
+		name = sh.ConnectionName
+	}
+	if name == "" {
+		name = defaultConnectionName
+	}
+
 	// parse the connection string
 	uri, err := url.Parse(cmd)
 	if err != nil {
@@ -26,6 +90,10 @@ func handleConnect(sh *types.Shell, cmd string) *types.ShellCommandResult {
 		return result
 	}
 
+	connection := &types.Connection{
+		Name: name,
+	}
+
 	switch uri.Scheme {
 	case "mysql":
 		dbName, err := db.VerifyMysqlConnection(cmd)
@@ -34,8 +102,8 @@ func handleConnect(sh *types.Shell, cmd string) *types.ShellCommandResult {
 			return result
 		}
 
-		sh.DatabaseName = dbName
-		sh.DatabaseEngine = "mysql"
+		connection.DatabaseName = dbName
+		connection.DatabaseEngine = "mysql"
 
 	case "postgres", "postgresql":
 		// test the connection
@@ -45,21 +113,272 @@ func handleConnect(sh *types.Shell, cmd string) *types.ShellCommandResult {
 			return result
 		}
 
-		sh.DatabaseName = dbName
-		sh.DatabaseEngine = "postgres"
+		connection.DatabaseName = dbName
+		connection.DatabaseEngine = "postgres"
 
 	default:
 		result.Message = ErrUnsupportedScheme.Error()
 		return result
 	}
 
-	sh.DB = &dbtypes.DB{
+	connection.DB = &dbtypes.DB{
 		ConnectionURI: cmd,
-		DatabaseName:  sh.DatabaseName,
+		DatabaseName:  connection.DatabaseName,
+		IssueFilter:   issueFilter,
+	}
+
+	go db.LoadSchema(connection.DB)
+
+	addConnection(sh, connection)
+
+	result.IsSuccess = true
+	return result
+}
+
+func loadProfile(name string) (config.Profile, error) {
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return config.Profile{}, err
+	}
+
+	return cfg.Profile(name)
+}
+
+// connectSnapshot opens a connection to the schema snapshot of a profile
+// without a URI. Queries are planned against the snapshot, but can't be run or
+// explained.
+func connectSnapshot(sh *types.Shell, name string, profile config.Profile) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	if sh.Transaction != nil {
+		result.Message = inTransactionMessage
+		return result
+	}
+	if profile.Schema == "" {
+		result.Message = fmt.Sprintf("profile %s has no uri or schema", name)
+		return result
+	}
+
+	f, err := os.Open(profile.SchemaPath())
+	if err != nil {
+		result.Message = fmt.Sprintf("Error opening schema: %s", err)
+		return result
+	}
+	defer f.Close()
+
+	snapshot, err := db.LoadSnapshot(f)
+	if err != nil {
+		result.Message = fmt.Sprintf("Error loading schema: %s", err)
+		return result
+	}
+	snapshot.IssueFilter = profile.IssueFilter()
+
+	addConnection(sh, &types.Connection{
+		Name:           name,
+		DB:             snapshot,
+		DatabaseName:   snapshot.DatabaseName,
+		DatabaseEngine: snapshot.Engine,
+	})
+
+	result.IsSuccess = true
+	result.Message = fmt.Sprintf("Loaded the schema of %s from %s, queries are planned against it but can't be run", snapshot.DatabaseName, profile.Schema)
+	return result
+}
+
+// addConnection adds a connection, replacing one with the same name, and
+// makes it the active one.
+func addConnection(sh *types.Shell, connection *types.Connection) {
+	if sh.Connections == nil {
+		sh.Connections = map[string]*types.Connection{}
+	}
+	sh.Connections[connection.Name] = connection
+	useConnection(sh, connection)
+}
+
+// useConnection makes a connection the active one, or disconnects the shell
+// when it's nil.
+func useConnection(sh *types.Shell, connection *types.Connection) {
+	if connection == nil {
+		sh.DB = nil
+		sh.DatabaseName = ""
+		sh.DatabaseEngine = ""
+		sh.ConnectionName = ""
+		return
+	}
+
+	sh.DB = connection.DB
+	sh.DatabaseName = connection.DatabaseName
+	sh.DatabaseEngine = connection.DatabaseEngine
+	sh.ConnectionName = connection.Name
+}
+
+// connectionNames returns the names of the open connections, sorted.
+func connectionNames(sh *types.Shell) []string {
+	names := []string{}
+	for name := range sh.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handleUse switches to a named connection, or lists the connections.
+func handleUse(sh *types.Shell, args string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	name := strings.TrimSpace(args)
+	if name == "" {
+		if len(sh.Connections) == 0 {
+			result.Message = "not connected, use /connect"
+			return result
+		}
+
+		lines := []string{}
+		for _, name := range connectionNames(sh) {
+			connection := sh.Connections[name]
+			marker := " "
+			if name == sh.ConnectionName {
+				marker = "*"
+			}
+			lines = append(lines, fmt.Sprintf("%s %s: %s/%s", marker, name, connection.DatabaseEngine, connection.DatabaseName))
+		}
+		result.IsSuccess = true
+		result.Message = strings.Join(lines, "\n")
+		return result
+	}
+
+	connection, ok := sh.Connections[name]
+	if !ok {
+		result.Message = fmt.Sprintf("no connection named %s, use /use to list the connections", name)
+		return result
+	}
+	if sh.Transaction != nil && name != sh.ConnectionName {
+		result.Message = inTransactionMessage
+		return result
 	}
 
-	go db.LoadSchema(sh.DB)
+	useConnection(sh, connection)
+
+	result.IsSuccess = true
+	result.Message = fmt.Sprintf("Using %s (%s/%s)", name, connection.DatabaseEngine, connection.DatabaseName)
+	return result
+}
+
+// handleDisconnect closes the active connection, or the one named. When the
+// active connection is closed, the first of the others becomes active.
+func handleDisconnect(sh *types.Shell, args string) *types.ShellCommandResult {
+	name := strings.TrimSpace(args)
+	if name == "" {
+		name = sh.ConnectionName
+	}
+
+	connection, ok := sh.Connections[name]
+	if sh.DB == nil || !ok {
+		message := "not connected"
+		if args != "" {
+			message = fmt.Sprintf("no connection named %s", name)
+		}
+		return &types.ShellCommandResult{
+			Message: message,
+		}
+	}
+
+	message := fmt.Sprintf("Disconnected from %s/%s", connection.DatabaseEngine, connection.DatabaseName)
+	delete(sh.Connections, name)
+
+	if name == sh.ConnectionName {
+		if sh.Transaction != nil {
+			message += ", the open transaction was discarded"
+		}
+		sh.Transaction = nil
+
+		var next *types.Connection
+		if names := connectionNames(sh); len(names) > 0 {
+			next = sh.Connections[names[0]]
+			message += fmt.Sprintf(", using %s", next.Name)
+		}
+		useConnection(sh, next)
+	}
+
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+		Message:   message,
+	}
+}
+
+// handleStatus shows the connection, with its password redacted, how much of
+// the schema is loaded, and the state of the session.
+func handleStatus(sh *types.Shell, args string) *types.ShellCommandResult {
+	lines := []string{}
+
+	if sh.DB == nil {
+		lines = append(lines, "Not connected, use /connect")
+	} else {
+		uri := sh.DB.ConnectionURI
+		if parsed, err := url.Parse(uri); err == nil {
+			uri = parsed.Redacted()
+		}
+		lines = append(lines, fmt.Sprintf("Connected to %s/%s (%s)", sh.DatabaseEngine, sh.DatabaseName, uri))
+		if len(sh.Connections) > 1 {
+			lines = append(lines, fmt.Sprintf("Connections: %s, using %s", strings.Join(connectionNames(sh), ", "), sh.ConnectionName))
+		}
+
+		if sh.DB.ServerVersion != "" {
+			lines = append(lines, fmt.Sprintf("Server version: %s", sh.DB.ServerVersion))
+		}
+
+		switch {
+		case sh.DB.SchemaLoading:
+			lines = append(lines, "Schema: loading")
+		case sh.DB.SchemaLoaded:
+			lines = append(lines, fmt.Sprintf("Schema: %d tables, %d views", len(sh.DB.Tables), len(sh.DB.Views)))
+		default:
+			lines = append(lines, "Schema: not loaded, use /reload-schema")
+		}
+	}
+
+	if sh.Transaction != nil {
+		lines = append(lines, fmt.Sprintf("Transaction: %d statements", len(sh.Transaction)))
+	}
+	if len(sh.Parameters) > 0 {
+		lines = append(lines, fmt.Sprintf("Parameters: %s", strings.Join(sh.Parameters, ", ")))
+	}
+
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+		Message:   strings.Join(lines, "\n"),
+	}
+}
+
+// handleReloadSchema loads the schema again, after it changed or if loading it
+// failed when connecting. Unlike on connect, it waits for the schema to load.
+func handleReloadSchema(sh *types.Shell, args string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	if sh.DB == nil {
+		result.Message = "not connected, use /connect"
+		return result
+	}
+	if sh.DB.SchemaLoading {
+		result.Message = "the schema is already loading"
+		return result
+	}
+
+	if err := db.LoadSchema(sh.DB); err != nil {
+		result.Message = fmt.Sprintf("Error loading schema: %s", err)
+		return result
+	}
 
 	result.IsSuccess = true
+	result.Message = fmt.Sprintf("Loaded %d tables and %d views", len(sh.DB.Tables), len(sh.DB.Views))
 	return result
 }
@@ -0,0 +1,82 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+	"github.com/queryplan-ai/qp/pkg/plan"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+)
+
+// handleExplain shows the plan the database picks for a query, with the
+// parameters set by /params.
+func handleExplain(sh *types.Shell, query string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	if sh.DB == nil {
+		result.Message = "not connected, use /connect"
+		return result
+	}
+	if !isDML(query) {
+		result.Message = "/explain only explains SELECT, INSERT, UPDATE and DELETE statements"
+		return result
+	}
+
+	message, err := db.Explain(sh.DB, query, sh.Parameters)
+	if err != nil {
+		result.Message = fmt.Sprintf("Error explaining query: %s", err)
+		return result
+	}
+
+	result.IsSuccess = true
+	result.Message = message
+	return result
+}
+
+// handleParams sets the values /explain binds the parameters of a query to,
+// in order: /params 42, 'open'. Without values the parameters are cleared.
+func handleParams(sh *types.Shell, args string) *types.ShellCommandResult {
+	sh.Parameters = parseParameterValues(args)
+
+	message := "Parameters cleared"
+	if len(sh.Parameters) > 0 {
+		message = "Parameters: " + strings.Join(sh.Parameters, ", ")
+	}
+
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+		Message:   message,
+	}
+}
+
+// parseParameterValues splits a list of values separated by commas or spaces
+// into SQL literals. Quoted strings can contain either.
+func parseParameterValues(args string) []string {
+	values := []string{}
+	negative := false
+	for _, token := range lexer.Tokenize(args) {
+		switch {
+		case token.IsPunctuation(","):
+		case token.IsPunctuation("-"):
+			negative = true
+		case token.Type == lexer.String:
+			values = append(values, token.Value)
+		default:
+			value := token.Value
+			if negative {
+				value = "-" + value
+				negative = false
+			}
+			values = append(values, plan.ParameterLiteral(value))
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,41 @@
+package shell
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDML(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "select * from users where id = 1", want: true},
+		{query: "with recent as (select * from orders) select * from recent", want: true},
+		{query: "insert into users (id) values (1)", want: true},
+		{query: "update users set name = 'x' where id = 1", want: true},
+		{query: "delete from users where id = 1", want: true},
+		{query: "analyze delete from users", want: false},
+		{query: "/* x */ ANALYZE select * from users", want: false},
+		{query: "analyze users", want: false},
+		{query: "create index users_email on users (email)", want: false},
+		{query: "drop table users", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			assert.Equal(t, test.want, isDML(test.query))
+		})
+	}
+}
+
+func TestHandleExplainRefusesAnalyze(t *testing.T) {
+	sh := &types.Shell{DB: &dbtypes.DB{ConnectionURI: "postgres://localhost/app"}}
+
+	result := processShellCommand(sh, "/explain analyze delete from users")
+	assert.False(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "only explains")
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/adrg/xdg"
+	"github.com/queryplan-ai/qp/pkg/lexer"
 	"github.com/queryplan-ai/qp/pkg/shell/types"
 )
 
@@ -53,3 +54,29 @@ func trimHistory(sh *types.Shell) error {
 
 	return nil
 }
+
+// historyEntry returns the lines of a statement as one history entry. The
+// history file has an entry per line, so the lines are joined with spaces, and
+// the line comments are dropped, since they'd comment out the rest of the
+// statement once it's on one line.
+func historyEntry(lines []string) string {
+	text := strings.Join(lines, "\n")
+
+	var b strings.Builder
+	last := 0
+	for _, token := range lexer.Tokenize(text) {
+		if token.Type == lexer.Comment && !strings.HasPrefix(token.Value, "/*") {
+			b.WriteString(text[last:token.Pos])
+			last = token.End()
+		}
+	}
+	b.WriteString(text[last:])
+
+	entry := []string{}
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entry = append(entry, line)
+		}
+	}
+	return strings.Join(entry, " ")
+}
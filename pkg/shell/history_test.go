@@ -0,0 +1,36 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_historyEntry(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{
+			name:  "one line",
+			lines: []string{"select * from users;"},
+			want:  "select * from users;",
+		},
+		{
+			name:  "line comments",
+			lines: []string{"select * -- every column", "  from users", "# only active ones", "  where active = '--';"},
+			want:  "select * from users where active = '--';",
+		},
+		{
+			name:  "block comment is kept",
+			lines: []string{"select /* all */ *", "from users;"},
+			want:  "select /* all */ * from users;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, historyEntry(tt.lines))
+		})
+	}
+}
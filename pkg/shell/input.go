@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/lexer"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+)
+
+// readInput adds a line to the statement being entered, and returns the
+// statements it completes. Statements end with a semicolon that isn't in a
+// string, a quoted identifier, a comment or a dollar quoted body, so a query
+// can span lines and a pasted script is planned statement by statement. The
+// text after the last semicolon is kept for the next line.
+//
+// Shell commands are a single line, and are returned as they are when no
+// statement is being entered.
+func readInput(sh *types.Shell, line string) []string {
+	if len(sh.Input) == 0 {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			return nil
+		}
+		if isShellCommand(trimmed) {
+			return []string{trimmed}
+		}
+	}
+
+	text := strings.Join(append(sh.Input, line), "\n")
+
+	end := -1
+	for _, token := range lexer.Tokenize(text) {
+		if token.IsPunctuation(";") {
+			end = token.End()
+		}
+	}
+	if end == -1 {
+		sh.Input = append(sh.Input, line)
+		return nil
+	}
+
+	sh.Input = nil
+	rest := text[end:]
+	for _, token := range lexer.Tokenize(rest) {
+		if token.Type != lexer.Comment {
+			sh.Input = []string{strings.TrimLeft(rest, " \t\n")}
+			break
+		}
+	}
+
+	return lexer.SplitStatements(text[:end])
+}
+
+// isShellCommand returns true if the input is a shell command rather than SQL.
+// A comment starts with a slash too.
+func isShellCommand(input string) bool {
+	return strings.HasPrefix(input, "/") && !strings.HasPrefix(input, "/*")
+}
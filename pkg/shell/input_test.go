@@ -0,0 +1,104 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadInput(t *testing.T) {
+	tests := []struct {
+		name string
+		// lines are entered one at a time, and the statements of all of them
+		// are collected
+		lines     []string
+		want      []string
+		wantInput []string
+	}{
+		{
+			name:  "single line",
+			lines: []string{"select * from users;"},
+			want:  []string{"select * from users"},
+		},
+		{
+			name:  "statement over several lines",
+			lines: []string{"select *", "from users", "where id = 1;"},
+			want:  []string{"select *\nfrom users\nwhere id = 1"},
+		},
+		{
+			name:      "unterminated statement is kept",
+			lines:     []string{"select *", "from users"},
+			want:      []string{},
+			wantInput: []string{"select *", "from users"},
+		},
+		{
+			name:  "semicolons in strings, comments and dollar quotes",
+			lines: []string{"select 'a;b' -- c;", "from users /* ; */", "where x = $$;$$;"},
+			want:  []string{"select 'a;b' -- c;\nfrom users /* ; */\nwhere x = $$;$$"},
+		},
+		{
+			name:  "string over several lines",
+			lines: []string{"select 'a", ";b' from users;"},
+			want:  []string{"select 'a\n;b' from users"},
+		},
+		{
+			name:  "pasted statements",
+			lines: []string{"begin; update users set name = 'x';", "commit;"},
+			want:  []string{"begin", "update users set name = 'x'", "commit"},
+		},
+		{
+			name:      "text after the last semicolon is kept",
+			lines:     []string{"select 1; select *"},
+			want:      []string{"select 1"},
+			wantInput: []string{"select *"},
+		},
+		{
+			name:  "a trailing comment isn't kept",
+			lines: []string{"select 1; -- done"},
+			want:  []string{"select 1"},
+		},
+		{
+			name:  "shell commands don't need a semicolon",
+			lines: []string{"  /tables", "/describe users"},
+			want:  []string{"/tables", "/describe users"},
+		},
+		{
+			name:  "a comment isn't a shell command",
+			lines: []string{"/* users */ select 1;"},
+			want:  []string{"/* users */ select 1"},
+		},
+		{
+			name:  "a slash inside a statement isn't a shell command",
+			lines: []string{"select 4", "/2;"},
+			want:  []string{"select 4\n/2"},
+		},
+		{
+			name:  "empty lines are ignored",
+			lines: []string{"", "   "},
+			want:  []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sh := &types.Shell{}
+			got := []string{}
+			for _, line := range test.lines {
+				got = append(got, readInput(sh, line)...)
+			}
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantInput, sh.Input)
+		})
+	}
+}
+
+func TestContinuationPrompt(t *testing.T) {
+	sh := &types.Shell{}
+	first := prompt(sh)
+
+	sh.Input = []string{"select *"}
+	continuation := prompt(sh)
+	assert.Len(t, continuation, len(first))
+	assert.Contains(t, continuation, "...")
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/blastrain/vitess-sqlparser/sqlparser"
 	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+	"github.com/queryplan-ai/qp/pkg/plan"
 	"github.com/queryplan-ai/qp/pkg/shell/types"
 )
 
@@ -36,8 +38,13 @@ func isQuery(query string) bool {
 		recover()
 	}()
 
-	// a database query is a string that starts with "select", "insert", "update", "delete"
-	stmt, err := sqlparser.Parse(query)
+	// a database query is a string that starts with "select", "insert", "update", "delete",
+	// or a schema change
+	if _, ok := plan.ParseDDL(query); ok {
+		return true
+	}
+
+	stmt, err := plan.Parse(query)
 	if err != nil {
 		fmt.Printf("Error parsing query: %s", err)
 		return false
@@ -50,3 +57,36 @@ func isQuery(query string) bool {
 		return false
 	}
 }
+
+// isDML returns true if a query is a SELECT, INSERT, UPDATE or DELETE, the
+// statements the database is asked to explain. A query that starts with
+// ANALYZE is refused, since EXPLAIN ANALYZE runs the statement.
+func isDML(query string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	for _, token := range lexer.Tokenize(query) {
+		if token.Type == lexer.Comment {
+			continue
+		}
+		if token.Is("analyze") || token.Is("analyse") {
+			return false
+		}
+		break
+	}
+
+	stmt, err := plan.Parse(query)
+	if err != nil {
+		return false
+	}
+
+	switch stmt.(type) {
+	case *sqlparser.Select, *sqlparser.Insert, *sqlparser.Update, *sqlparser.Delete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,243 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+	"github.com/queryplan-ai/qp/pkg/plan"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+)
+
+const (
+	// previewRows is the number of rows of a result /run shows
+	previewRows = 20
+	// previewWidth is the width a value in the preview is truncated to
+	previewWidth = 60
+	// defaultTimingRuns is the number of runs /timing on sets without a count
+	defaultTimingRuns = 10
+)
+
+// readStatementKeywords are the words a statement that only reads starts with.
+var readStatementKeywords = map[string]bool{
+	"select": true, "with": true, "show": true, "values": true, "table": true, "explain": true,
+}
+
+// writeKeywords are the words that make a statement that starts as a read
+// write, or lock rows: a CTE that modifies data, or SELECT ... FOR UPDATE.
+var writeKeywords = map[string]bool{
+	"insert": true, "update": true, "delete": true, "merge": true,
+}
+
+// handleRun executes a query and shows how long it took, its row count and its
+// first rows. Queries that only read run in a read only transaction. INSERT,
+// UPDATE and DELETE statements have to be confirmed, and run in a transaction
+// that's rolled back, so the data isn't changed. Nothing else is run. With
+// /timing on, the query runs several times and the latency of the runs is
+// summarized.
+func handleRun(sh *types.Shell, query string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	if sh.DB == nil {
+		result.Message = "not connected, use /connect"
+		return result
+	}
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if query == "" {
+		result.Message = "usage: /run <query>"
+		return result
+	}
+
+	// anything but a read or a DML statement can commit implicitly, or change
+	// what a transaction can't roll back, so it isn't run
+	readOnly := isReadOnly(query)
+	if !readOnly && !isDML(query) {
+		result.Message = "/run only runs queries, and INSERT, UPDATE and DELETE statements in a transaction that's rolled back"
+		return result
+	}
+
+	// the parameters set for /explain are only bound to a query that has
+	// parameters
+	bound := query
+	if plan.HasParameters(query) {
+		var err error
+		bound, err = plan.BindParameters(query, sh.Parameters)
+		if err != nil {
+			result.Message = fmt.Sprintf("Error binding parameters: %s, use /params to set them", err)
+			return result
+		}
+	}
+
+	if !readOnly {
+		if sh.Confirm == nil || !sh.Confirm("The statement can change data. Run it in a transaction that's rolled back?") {
+			result.IsSuccess = true
+			result.Message = "Not run"
+			return result
+		}
+	}
+
+	runResult, err := db.Run(sh.DB, bound, dbtypes.RunOptions{
+		ReadOnly: readOnly,
+		Runs:     max(sh.TimingRuns, 1),
+		MaxRows:  previewRows,
+	})
+	if err != nil {
+		result.Message = fmt.Sprintf("Error running query: %s", err)
+		return result
+	}
+
+	result.IsSuccess = true
+	result.Message = formatRunResult(runResult, readOnly)
+	return result
+}
+
+// handleTiming turns timing on, to run queries several times with /run, or off.
+func handleTiming(sh *types.Shell, args string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	fields := strings.Fields(strings.ToLower(args))
+	switch {
+	case len(fields) == 0:
+	case fields[0] == "off" && len(fields) == 1:
+		sh.TimingRuns = 0
+	case fields[0] == "on" && len(fields) <= 2:
+		runs := defaultTimingRuns
+		if len(fields) == 2 {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 1 {
+				result.Message = fmt.Sprintf("invalid number of runs: %s", fields[1])
+				return result
+			}
+			runs = n
+		}
+		sh.TimingRuns = runs
+	default:
+		result.Message = "usage: /timing on [runs] | off"
+		return result
+	}
+
+	result.IsSuccess = true
+	result.Message = "Timing is off"
+	if sh.TimingRuns > 0 {
+		result.Message = fmt.Sprintf("Timing is on, /run runs queries %d times", sh.TimingRuns)
+	}
+	return result
+}
+
+// isReadOnly returns true if a statement only reads.
+func isReadOnly(query string) bool {
+	first := true
+	for _, token := range lexer.Tokenize(query) {
+		if token.Type == lexer.Comment {
+			continue
+		}
+		if token.Type != lexer.Word {
+			if first {
+				return false
+			}
+			continue
+		}
+
+		word := strings.ToLower(token.Value)
+		if first && !readStatementKeywords[word] {
+			return false
+		}
+		first = false
+		if writeKeywords[word] {
+			return false
+		}
+	}
+	return !first
+}
+
+func formatRunResult(result *dbtypes.RunResult, readOnly bool) string {
+	var b strings.Builder
+
+	if len(result.Columns) > 0 {
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(result.Columns, "\t"))
+		for _, row := range result.Rows {
+			cells := []string{}
+			for _, value := range row {
+				cells = append(cells, previewValue(value))
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+		w.Flush()
+		b.WriteString("\n")
+
+		if int64(len(result.Rows)) < result.RowCount {
+			fmt.Fprintf(&b, "Showing the first %d of %d rows\n", len(result.Rows), result.RowCount)
+		}
+	}
+
+	rows := "rows"
+	if result.RowCount == 1 {
+		rows = "row"
+	}
+	if len(result.Columns) > 0 {
+		fmt.Fprintf(&b, "%d %s in %s", result.RowCount, rows, formatDuration(result.Durations[0]))
+	} else {
+		fmt.Fprintf(&b, "%d %s affected in %s", result.RowCount, rows, formatDuration(result.Durations[0]))
+	}
+	if !readOnly {
+		b.WriteString(", rolled back")
+	}
+
+	if len(result.Durations) > 1 {
+		minimum, median, p95 := durationStats(result.Durations)
+		fmt.Fprintf(&b, "\n%d runs: min %s, median %s, p95 %s", len(result.Durations),
+			formatDuration(minimum), formatDuration(median), formatDuration(p95))
+	}
+
+	return b.String()
+}
+
+// previewValue returns a value on one line, truncated so that a long value
+// doesn't stretch the whole table.
+func previewValue(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if runes := []rune(value); len(runes) > previewWidth {
+		value = string(runes[:previewWidth-3]) + "..."
+	}
+	return value
+}
+
+// durationStats returns the minimum, the median and the 95th percentile of
+// durations, using the nearest rank.
+func durationStats(durations []time.Duration) (time.Duration, time.Duration, time.Duration) {
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	rank := (len(sorted)*95 + 99) / 100
+	return sorted[0], median, sorted[rank-1]
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,150 @@
+package shell
+
+import (
+	"testing"
+	"time"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReadOnly(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "select * from users", want: true},
+		{query: "/* report */ SELECT count(*) from orders", want: true},
+		{query: "with recent as (select * from orders) select * from recent", want: true},
+		{query: "show tables", want: true},
+		{query: "select * from users for update", want: false},
+		{query: "with gone as (delete from orders returning *) select * from gone", want: false},
+		{query: "update users set name = 'x'", want: false},
+		{query: "insert into users (id) values (1)", want: false},
+		{query: "(select 1)", want: false},
+		{query: "", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			assert.Equal(t, test.want, isReadOnly(test.query))
+		})
+	}
+}
+
+func TestDurationStats(t *testing.T) {
+	durations := []time.Duration{}
+	for i := 20; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	minimum, median, p95 := durationStats(durations)
+	assert.Equal(t, time.Millisecond, minimum)
+	assert.Equal(t, 10500*time.Microsecond, median)
+	assert.Equal(t, 19*time.Millisecond, p95)
+
+	minimum, median, p95 = durationStats([]time.Duration{3 * time.Millisecond})
+	assert.Equal(t, 3*time.Millisecond, minimum)
+	assert.Equal(t, 3*time.Millisecond, median)
+	assert.Equal(t, 3*time.Millisecond, p95)
+}
+
+func TestFormatRunResult(t *testing.T) {
+	message := formatRunResult(&dbtypes.RunResult{
+		Columns:   []string{"id", "email"},
+		Rows:      [][]string{{"1", "a@example.com"}, {"2", "multi\nline"}},
+		RowCount:  120,
+		Durations: []time.Duration{2 * time.Millisecond, 1 * time.Millisecond, 3 * time.Millisecond},
+	}, true)
+
+	assert.Equal(t, `id  email
+1   a@example.com
+2   multi line
+
+Showing the first 2 of 120 rows
+120 rows in 2ms
+3 runs: min 1ms, median 2ms, p95 3ms`, message)
+
+	message = formatRunResult(&dbtypes.RunResult{
+		RowCount:  1,
+		Durations: []time.Duration{1500 * time.Microsecond},
+	}, false)
+	assert.Equal(t, "1 row affected in 1.5ms, rolled back", message)
+}
+
+func TestHandleTiming(t *testing.T) {
+	sh := &types.Shell{}
+
+	result := processShellCommand(sh, "/timing on")
+	require.True(t, result.IsSuccess)
+	assert.Equal(t, defaultTimingRuns, sh.TimingRuns)
+
+	result = processShellCommand(sh, "/timing on 25")
+	require.True(t, result.IsSuccess)
+	assert.Equal(t, 25, sh.TimingRuns)
+
+	result = processShellCommand(sh, "/timing on zero")
+	assert.False(t, result.IsSuccess)
+	assert.Equal(t, 25, sh.TimingRuns)
+
+	result = processShellCommand(sh, "/timing off")
+	require.True(t, result.IsSuccess)
+	assert.Equal(t, 0, sh.TimingRuns)
+}
+
+func TestHandleRunRequiresConfirmation(t *testing.T) {
+	confirmed := ""
+	sh := &types.Shell{
+		DB: &dbtypes.DB{},
+		Confirm: func(message string) bool {
+			confirmed = message
+			return false
+		},
+	}
+
+	result := processShellCommand(sh, "/run delete from orders")
+	require.True(t, result.IsSuccess)
+	assert.Equal(t, "Not run", result.Message)
+	assert.NotEmpty(t, confirmed)
+
+	result = processShellCommand(&types.Shell{DB: &dbtypes.DB{}}, "/run update orders set status = 'x'")
+	assert.Equal(t, "Not run", result.Message)
+
+	for _, statement := range []string{
+		"drop table orders",
+		"create view open_orders as select * from orders",
+		"drop view open_orders",
+		"create user reader",
+		"grant select on orders to reader",
+		"drop database app",
+		"call archive_orders()",
+		"analyze delete from orders",
+	} {
+		result = processShellCommand(sh, "/run "+statement)
+		assert.False(t, result.IsSuccess, statement)
+		assert.Contains(t, result.Message, "only runs", statement)
+	}
+}
+
+func TestHandleRunBindsParameters(t *testing.T) {
+	sh := &types.Shell{
+		DB:         &dbtypes.DB{},
+		Parameters: []string{"42"},
+	}
+
+	// a query without parameters isn't bound to the values set for /explain
+	result := processShellCommand(sh, "/run delete from orders where status = 'a  b'")
+	assert.Equal(t, "Not run", result.Message)
+
+	result = processShellCommand(sh, "/run delete from orders where id = $1 and user_id = $2")
+	assert.False(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "Error binding parameters")
+}
+
+func TestStripCommand(t *testing.T) {
+	assert.Equal(t, "select * from users where name = 'a  b'", stripCommand("/run  select * from users where name = 'a  b'"))
+	assert.Equal(t, "", stripCommand("/status"))
+	assert.Equal(t, "select 1", stripCommand("select 1"))
+}
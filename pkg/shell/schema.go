@@ -0,0 +1,297 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+)
+
+// loadedSchema returns the schema of the connection, or the result to show
+// when there's no schema to look at yet.
+func loadedSchema(sh *types.Shell) (*dbtypes.DB, *types.ShellCommandResult) {
+	if message := schemaNotReady(sh.DB); message != "" {
+		return nil, &types.ShellCommandResult{
+			IsFatal:   false,
+			IsSuccess: false,
+			Message:   message,
+		}
+	}
+
+	return sh.DB, nil
+}
+
+// schemaNotReady returns why the schema of a connection can't be used yet, or
+// an empty string when it's loaded.
+func schemaNotReady(db *dbtypes.DB) string {
+	switch {
+	case db == nil:
+		return "not connected, use /connect"
+	case db.SchemaLoading:
+		return "the schema is still loading, try again in a moment"
+	case !db.SchemaLoaded:
+		return "the schema isn't loaded, use /reload-schema"
+	}
+
+	return ""
+}
+
+// findTable returns the table with a name. Unquoted names are case
+// insensitive in both engines, so an exact match is preferred but not
+// required.
+func findTable(tables []dbtypes.Table, name string) (dbtypes.Table, bool) {
+	name = strings.Trim(name, "`\"")
+
+	var match dbtypes.Table
+	for _, table := range tables {
+		if table.GetName() == name {
+			return table, true
+		}
+		if match == nil && strings.EqualFold(table.GetName(), name) {
+			match = table
+		}
+	}
+
+	return match, match != nil
+}
+
+// tableArgument returns the table named by the argument of a command.
+func tableArgument(sh *types.Shell, args string, usage string) (dbtypes.Table, *types.ShellCommandResult) {
+	schema, result := loadedSchema(sh)
+	if result != nil {
+		return nil, result
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) != 1 {
+		return nil, &types.ShellCommandResult{
+			Message: fmt.Sprintf("usage: %s", usage),
+		}
+	}
+
+	table, ok := findTable(schema.Tables, fields[0])
+	if !ok {
+		return nil, &types.ShellCommandResult{
+			Message: fmt.Sprintf("table %s not found, use /tables to list the tables", fields[0]),
+		}
+	}
+
+	return table, nil
+}
+
+// handleTables lists the tables with the planner's estimate of their row
+// count. An argument filters the tables to the ones whose name contains it.
+func handleTables(sh *types.Shell, args string) *types.ShellCommandResult {
+	schema, result := loadedSchema(sh)
+	if result != nil {
+		return result
+	}
+
+	filter := strings.ToLower(strings.TrimSpace(args))
+	tables := []dbtypes.Table{}
+	for _, table := range schema.Tables {
+		if strings.Contains(strings.ToLower(table.GetName()), filter) {
+			tables = append(tables, table)
+		}
+	}
+	if len(tables) == 0 {
+		message := "no tables"
+		if filter != "" {
+			message = fmt.Sprintf("no tables match %s", filter)
+		}
+		return &types.ShellCommandResult{
+			IsSuccess: true,
+			Message:   message,
+		}
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].GetName() < tables[j].GetName()
+	})
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "table\testimated rows\tcolumns\tindexes")
+	for _, table := range tables {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", table.GetName(), table.GetEstimatedRowCount(), len(table.GetColumns()), len(table.GetIndexes()))
+	}
+	w.Flush()
+
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+		Message:   strings.TrimSuffix(b.String(), "\n"),
+	}
+}
+
+// handleDescribe shows the columns of a table and the keys they are part of.
+func handleDescribe(sh *types.Shell, args string) *types.ShellCommandResult {
+	table, result := tableArgument(sh, args, "/describe <table>")
+	if result != nil {
+		return result
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Table %s, about %d rows\n\n", table.GetName(), table.GetEstimatedRowCount())
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "column\ttype\tnullable\tdefault\textra\tkey")
+	for _, column := range table.GetColumns() {
+		columnType := column.GetColumnType()
+		if columnType == "" {
+			columnType = column.GetDataType()
+		}
+
+		nullable := "no"
+		if column.GetIsNullable() {
+			nullable = "yes"
+		}
+
+		columnDefault := ""
+		if column.GetColumnDefault() != nil {
+			columnDefault = *column.GetColumnDefault()
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", column.GetName(), columnType, nullable, columnDefault, column.GetExtra(), columnKeys(table, column.GetName()))
+	}
+	w.Flush()
+
+	if len(table.GetPrimaryKeys()) > 0 {
+		fmt.Fprintf(&b, "\nPrimary key: (%s)\n", strings.Join(table.GetPrimaryKeys(), ", "))
+	} else {
+		b.WriteString("\nNo primary key\n")
+	}
+	for _, foreignKey := range table.GetForeignKeys() {
+		fmt.Fprintf(&b, "Foreign key %s: %s\n", foreignKey.Name, formatForeignKey(foreignKey))
+	}
+
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+		Message:   strings.TrimSuffix(b.String(), "\n"),
+	}
+}
+
+// columnKeys describes the keys a column is part of: PRI for the primary key,
+// UNI for a unique index on the column alone, MUL for any other index, and FK
+// for a foreign key.
+func columnKeys(table dbtypes.Table, columnName string) string {
+	keys := []string{}
+
+	if contains(table.GetPrimaryKeys(), columnName) {
+		keys = append(keys, "PRI")
+	}
+
+	indexKey := ""
+	for _, index := range table.GetIndexes() {
+		for _, column := range index.Columns {
+			if column != columnName {
+				continue
+			}
+			if index.IsUnique && len(index.Columns) == 1 {
+				indexKey = "UNI"
+			} else if indexKey == "" {
+				indexKey = "MUL"
+			}
+		}
+	}
+	if indexKey != "" {
+		keys = append(keys, indexKey)
+	}
+
+	for _, foreignKey := range table.GetForeignKeys() {
+		if contains(foreignKey.Columns, columnName) {
+			keys = append(keys, "FK")
+			break
+		}
+	}
+
+	return strings.Join(keys, ",")
+}
+
+// handleIndexes shows the primary key, the indexes and the foreign keys of a
+// table. A foreign key without an index that starts with its columns is
+// flagged, because deleting or updating a referenced row then scans the table.
+func handleIndexes(sh *types.Shell, args string) *types.ShellCommandResult {
+	table, result := tableArgument(sh, args, "/indexes <table>")
+	if result != nil {
+		return result
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "index\tcolumns\tunique\tprimary")
+	if len(table.GetPrimaryKeys()) > 0 {
+		fmt.Fprintf(w, "%s\t%s\tyes\tyes\n", "(primary key)", strings.Join(table.GetPrimaryKeys(), ", "))
+	}
+	for _, index := range table.GetIndexes() {
+		unique := "no"
+		if index.IsUnique {
+			unique = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\tno\n", index.Name, strings.Join(index.Columns, ", "), unique)
+	}
+	w.Flush()
+
+	if len(table.GetForeignKeys()) > 0 {
+		b.WriteString("\n")
+		w = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "foreign key\tcolumns\treferences\tindexed")
+		for _, foreignKey := range table.GetForeignKeys() {
+			indexed := "no"
+			if isIndexed(table, foreignKey.Columns) {
+				indexed = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s(%s)\t%s\n", foreignKey.Name, strings.Join(foreignKey.Columns, ", "),
+				foreignKey.ReferencedTable, strings.Join(foreignKey.ReferencedColumns, ", "), indexed)
+		}
+		w.Flush()
+	}
+
+	return &types.ShellCommandResult{
+		IsSuccess: true,
+		Message:   strings.TrimSuffix(b.String(), "\n"),
+	}
+}
+
+// isIndexed returns true if the primary key or an index of a table starts with
+// the columns, in any order.
+func isIndexed(table dbtypes.Table, columns []string) bool {
+	keys := [][]string{table.GetPrimaryKeys()}
+	for _, index := range table.GetIndexes() {
+		keys = append(keys, index.Columns)
+	}
+
+	for _, key := range keys {
+		if len(key) < len(columns) {
+			continue
+		}
+		covered := true
+		for _, column := range columns {
+			if !contains(key[:len(columns)], column) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatForeignKey(foreignKey dbtypes.ForeignKey) string {
+	return fmt.Sprintf("(%s) references %s(%s)", strings.Join(foreignKey.Columns, ", "),
+		foreignKey.ReferencedTable, strings.Join(foreignKey.ReferencedColumns, ", "))
+}
+
+func contains(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
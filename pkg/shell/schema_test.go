@@ -0,0 +1,133 @@
+package shell
+
+import (
+	"testing"
+
+	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	"github.com/queryplan-ai/qp/pkg/pg"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchemaShell() *types.Shell {
+	now := "now()"
+	return &types.Shell{
+		DB: &dbtypes.DB{
+			SchemaLoaded: true,
+			Tables: []dbtypes.Table{
+				pg.PostgresTable{
+					TableName:         "users",
+					EstimatedRowCount: 1000,
+					Columns: []pg.PostgresColumn{
+						{ColumnName: "id", DataType: "integer", Extra: "identity"},
+						{ColumnName: "email", DataType: "character varying (255)"},
+					},
+					PrimaryKeys: []string{"id"},
+					Indexes: []dbtypes.Index{
+						{Name: "users_email", Columns: []string{"email"}, IsUnique: true},
+					},
+				},
+				pg.PostgresTable{
+					TableName:         "orders",
+					EstimatedRowCount: 1000000,
+					Columns: []pg.PostgresColumn{
+						{ColumnName: "id", DataType: "integer", Extra: "identity"},
+						{ColumnName: "user_id", DataType: "integer"},
+						{ColumnName: "coupon_id", DataType: "integer", IsNullable: true},
+						{ColumnName: "created_at", DataType: "timestamp with time zone", ColumnDefault: &now},
+					},
+					PrimaryKeys: []string{"id"},
+					Indexes: []dbtypes.Index{
+						{Name: "orders_user_id_created_at", Columns: []string{"user_id", "created_at"}},
+					},
+					ForeignKeys: []dbtypes.ForeignKey{
+						{Name: "orders_user_id_fkey", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+						{Name: "orders_coupon_id_fkey", Columns: []string{"coupon_id"}, ReferencedTable: "coupons", ReferencedColumns: []string{"id"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSchemaCommands(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		wantSuccess bool
+		want        []string
+	}{
+		{
+			name:        "tables are sorted with their row counts",
+			command:     "/tables",
+			wantSuccess: true,
+			want: []string{
+				"table   estimated rows  columns  indexes",
+				"orders  1000000         4        1",
+				"users   1000            2        1",
+			},
+		},
+		{
+			name:        "tables filtered",
+			command:     "/tables USE",
+			wantSuccess: true,
+			want:        []string{"users"},
+		},
+		{
+			name:        "describe",
+			command:     "/describe Orders",
+			wantSuccess: true,
+			want: []string{
+				"Table orders, about 1000000 rows",
+				"id          integer                   no                 identity  PRI",
+				"user_id     integer                   no                           MUL,FK",
+				"coupon_id   integer                   yes                          FK",
+				"created_at  timestamp with time zone  no        now()              MUL",
+				"Primary key: (id)",
+				"Foreign key orders_user_id_fkey: (user_id) references users(id)",
+			},
+		},
+		{
+			name:        "indexes and unindexed foreign keys",
+			command:     "/indexes orders",
+			wantSuccess: true,
+			want: []string{
+				"(primary key)              id                   yes     yes",
+				"orders_user_id_created_at  user_id, created_at  no      no",
+				"orders_user_id_fkey    user_id    users(id)    yes",
+				"orders_coupon_id_fkey  coupon_id  coupons(id)  no",
+			},
+		},
+		{
+			name:    "unknown table",
+			command: "/describe nope",
+			want:    []string{"table nope not found"},
+		},
+		{
+			name:    "missing table",
+			command: "/indexes",
+			want:    []string{"usage: /indexes <table>"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := processShellCommand(testSchemaShell(), test.command)
+			require.Equal(t, test.wantSuccess, result.IsSuccess, result.Message)
+			for _, want := range test.want {
+				assert.Contains(t, result.Message, want)
+			}
+		})
+	}
+}
+
+func TestSchemaCommandsWithoutSchema(t *testing.T) {
+	result := processShellCommand(&types.Shell{}, "/tables")
+	assert.False(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "not connected")
+
+	result = processShellCommand(&types.Shell{DB: &dbtypes.DB{SchemaLoading: true}}, "/describe users")
+	assert.False(t, result.IsSuccess)
+	assert.Contains(t, result.Message, "still loading")
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/chzyer/readline"
+	"github.com/queryplan-ai/qp/pkg/plan"
 	"github.com/queryplan-ai/qp/pkg/shell/types"
 )
 
@@ -23,13 +24,16 @@ func RunShell(opts types.ShellOpts) error {
 	sh := types.Shell{
 		HistoryFilePath: historyFile,
 		HistoryMaxSize:  maxCommandHistory,
+		IssueFilter:     opts.IssueFilter,
 	}
 
 	if opts.ConnectionURI != "" {
-		result := handleConnect(&sh, opts.ConnectionURI)
+		result := handleConnect(&sh, strings.TrimSpace(opts.ConnectionName+" "+opts.ConnectionURI))
 		if !result.IsSuccess {
 			return fmt.Errorf("error connecting to database: %s", result.Message)
 		}
+		// the flags override the issue filter of a profile
+		sh.DB.IssueFilter = opts.IssueFilter
 	}
 
 	rl, err := readline.NewEx(&readline.Config{
@@ -37,36 +41,67 @@ func RunShell(opts types.ShellOpts) error {
 		HistoryFile:     historyFile,
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
+		AutoComplete:    &completer{sh: &sh},
+		// a statement is saved as one entry once it's complete, rather than
+		// line by line
+		DisableAutoSaveHistory: true,
 	})
 	if err != nil {
 		log.Printf("Error creating readline: %v", err)
 	}
 	defer rl.Close()
 
+	sh.Confirm = func(message string) bool {
+		defer rl.SetPrompt(prompt(&sh))
+
+		rl.SetPrompt(message + " [y/N] ")
+		answer, err := rl.Readline()
+		if err != nil {
+			return false
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+
 	for {
 		line, err := rl.Readline()
+		if err == readline.ErrInterrupt && len(sh.Input) > 0 {
+			// ^C discards the statement being entered
+			sh.Input = nil
+			rl.SetPrompt(prompt(&sh))
+			continue
+		}
 		if err != nil { // io.EOF
 			break
 		}
 
-		if err := trimHistory(&sh); err != nil {
-			log.Printf("Error trimming history: ex%v", err)
+		entry := historyEntry(append(append([]string{}, sh.Input...), line))
+		statements := readInput(&sh, line)
+		if len(statements) > 0 {
+			if err := rl.SaveHistory(entry); err != nil {
+				log.Printf("Error saving history: %v", err)
+			}
+			if err := trimHistory(&sh); err != nil {
+				log.Printf("Error trimming history: ex%v", err)
+			}
 		}
 
-		result := processShellCommand(&sh, line)
-		if result.IsFatal {
-			if result.IsSuccess {
-				os.Exit(0)
-			}
+		for _, statement := range statements {
+			result := processShellCommand(&sh, statement)
+			if result.IsFatal {
+				if result.IsSuccess {
+					os.Exit(0)
+				}
 
-			fmt.Printf("Error: %s\n", result.Message)
-			os.Exit(1)
-		} else {
-			if !result.IsSuccess {
 				fmt.Printf("Error: %s\n", result.Message)
+				os.Exit(1)
 			} else {
-				if result.Message != "" {
-					fmt.Println(result.Message)
+				if !result.IsSuccess {
+					fmt.Printf("Error: %s\n", result.Message)
+				} else {
+					if result.Message != "" {
+						fmt.Println(result.Message)
+					}
 				}
 			}
 		}
@@ -78,57 +113,59 @@ func RunShell(opts types.ShellOpts) error {
 	return nil
 }
 
+// prompt returns the prompt for the next line. While a statement is being
+// entered, it's a continuation prompt of the same width.
 func prompt(sh *types.Shell) string {
+	p := connectionPrompt(sh)
+	if len(sh.Input) > 0 {
+		return strings.Repeat(" ", len(p)-len("... ")) + "... "
+	}
+	return p
+}
+
+func connectionPrompt(sh *types.Shell) string {
 	if sh.DB == nil {
 		return "<not connected, use /connect> >>> "
 	}
 
-	return fmt.Sprintf("%s/%s >>> ", sh.DatabaseEngine, sh.DatabaseName)
+	// the name of the connection is shown once there's a choice of them
+	connection := fmt.Sprintf("%s/%s", sh.DatabaseEngine, sh.DatabaseName)
+	if sh.ConnectionName != "" && (sh.ConnectionName != defaultConnectionName || len(sh.Connections) > 1) {
+		connection = fmt.Sprintf("%s %s", sh.ConnectionName, connection)
+	}
+
+	if sh.Transaction != nil {
+		return fmt.Sprintf("%s (transaction, %d statements) >>> ", connection, len(sh.Transaction))
+	}
+
+	return fmt.Sprintf("%s >>> ", connection)
 }
 
 func processShellCommand(sh *types.Shell, cmd string) *types.ShellCommandResult {
-	if !strings.HasPrefix(cmd, "/") {
+	if !isShellCommand(cmd) {
+		if sh.Transaction != nil || plan.TransactionControl(cmd) != "" {
+			return handleTransaction(sh, cmd)
+		}
 		return handleQuery(sh, stripCommand(cmd))
 	}
 
-	cmdParts := strings.Split(cmd, " ")
-	switch cmdParts[0] {
-	case "/exit":
-		return &types.ShellCommandResult{
-			IsSuccess: true,
-			IsFatal:   true,
-		}
-	case "/help", "/?":
-		return showHelp()
-	case "/connect":
-		return handleConnect(sh, stripCommand(cmd))
-	default:
-		return showUnknownCommand()
+	name := strings.Fields(cmd)[0]
+	c, ok := findCommand(name)
+	if !ok {
+		return showUnknownCommand(name)
 	}
+
+	return c.handler(sh, stripCommand(cmd))
 }
 
 func stripCommand(cmd string) string {
-	// remove the / command from the cmd
-	parts := strings.Fields(cmd)
-	if strings.HasPrefix(parts[0], "/") {
-		return strings.Join(parts[1:], " ")
+	// remove the / command from the cmd, keeping the arguments as they were
+	// typed, since they can be a query with string literals
+	trimmed := strings.TrimSpace(cmd)
+	if strings.HasPrefix(trimmed, "/") {
+		name := strings.Fields(trimmed)[0]
+		return strings.TrimSpace(strings.TrimPrefix(trimmed, name))
 	}
 
 	return cmd
 }
-
-func showHelp() *types.ShellCommandResult {
-	return &types.ShellCommandResult{
-		IsSuccess: false,
-		IsFatal:   false,
-		Message:   "not implemented",
-	}
-}
-
-func showUnknownCommand() *types.ShellCommandResult {
-	return &types.ShellCommandResult{
-		IsSuccess: false,
-		IsFatal:   false,
-		Message:   "not implemented",
-	}
-}
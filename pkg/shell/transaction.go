@@ -0,0 +1,108 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/queryplan-ai/qp/pkg/lexer"
+	"github.com/queryplan-ai/qp/pkg/plan"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+)
+
+// handleTransaction collects the statements entered between BEGIN and COMMIT,
+// and plans them as one transaction on COMMIT. The statements of a line are
+// applied to a copy of the transaction, which replaces it only when every one
+// of them succeeds, so a line that fails partway leaves it as it was.
+func handleTransaction(sh *types.Shell, line string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	transaction := slices.Clone(sh.Transaction)
+	for _, statement := range lexer.SplitStatements(line) {
+		switch plan.TransactionControl(statement) {
+		case plan.TransactionControlBegin:
+			if transaction != nil {
+				result.Message = "already in a transaction, use COMMIT to plan it or ROLLBACK to discard it"
+				return result
+			}
+			transaction = []string{}
+
+		case plan.TransactionControlCommit:
+			if transaction == nil {
+				result.Message = "not in a transaction"
+				return result
+			}
+			if sh.DB == nil {
+				result.Message = "not connected, use /connect"
+				return result
+			}
+
+			statements := transaction
+			transaction = nil
+
+			message, err := db.PlanTransactions(sh.DB, [][]string{statements})
+			if err != nil {
+				result.Message = fmt.Sprintf("Error planning transaction: %s", err)
+				return result
+			}
+			result.Message = message
+
+		case plan.TransactionControlRollback:
+			if transaction == nil {
+				result.Message = "not in a transaction"
+				return result
+			}
+			transaction = nil
+			result.Message = "transaction discarded"
+
+		default:
+			if !isQuery(statement) {
+				result.Message = "not a valid query"
+				return result
+			}
+			transaction = append(transaction, statement)
+		}
+	}
+
+	sh.Transaction = transaction
+	result.IsSuccess = true
+	return result
+}
+
+// handleBatch plans the transactions in a SQL file.
+func handleBatch(sh *types.Shell, path string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	if sh.DB == nil {
+		result.Message = "not connected, use /connect"
+		return result
+	}
+
+	if path == "" {
+		result.Message = "usage: /batch <file>"
+		return result
+	}
+
+	script, err := os.ReadFile(path)
+	if err != nil {
+		result.Message = fmt.Sprintf("Error reading %s: %s", path, err)
+		return result
+	}
+
+	message, err := db.PlanScript(sh.DB, string(script))
+	if err != nil {
+		result.Message = fmt.Sprintf("Error planning batch: %s", err)
+		return result
+	}
+
+	result.IsSuccess = true
+	result.Message = message
+	return result
+}
@@ -0,0 +1,24 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleTransactionFailsPartway(t *testing.T) {
+	sh := &types.Shell{}
+
+	result := handleTransaction(sh, "begin; update users set name = 'x' where id = 1; not a query")
+	assert.False(t, result.IsSuccess)
+	assert.Nil(t, sh.Transaction)
+
+	result = handleTransaction(sh, "begin; update users set name = 'x' where id = 1")
+	assert.True(t, result.IsSuccess)
+	assert.Equal(t, []string{"update users set name = 'x' where id = 1"}, sh.Transaction)
+
+	result = handleTransaction(sh, "delete from orders where id = 1; rollback; begin; begin")
+	assert.False(t, result.IsSuccess)
+	assert.Equal(t, []string{"update users set name = 'x' where id = 1"}, sh.Transaction)
+}
@@ -2,21 +2,67 @@ package types
 
 import (
 	dbtypes "github.com/queryplan-ai/qp/pkg/db/types"
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
 )
 
 type ShellOpts struct {
 	ConnectionURI string
-	OpenAIAPIKey  string
+	// ConnectionName names the connection to ConnectionURI, after the
+	// profile it comes from
+	ConnectionName string
+	OpenAIAPIKey   string
+	// IssueFilter selects the issues that are reported for connections
+	// that aren't made with a profile
+	IssueFilter issuetypes.Filter
 }
 
 type Shell struct {
+	// DB, DatabaseName and DatabaseEngine are those of the active
+	// connection
 	DB *dbtypes.DB
 
 	DatabaseName   string
 	DatabaseEngine string
 
+	// Connections are the open connections by name, and ConnectionName is
+	// the name of the active one
+	Connections    map[string]*Connection
+	ConnectionName string
+
 	HistoryFilePath string
 	HistoryMaxSize  int
+
+	// Transaction holds the statements entered since BEGIN, and is nil
+	// outside of a transaction
+	Transaction []string
+
+	// Parameters are the values /explain binds the parameters of a query
+	// to, as SQL literals
+	Parameters []string
+
+	// IssueFilter selects the issues that are reported for connections
+	// that aren't made with a profile, which have their own
+	IssueFilter issuetypes.Filter
+
+	// TimingRuns is the number of times /run executes a query to time it,
+	// and is 0 when timing is off
+	TimingRuns int
+
+	// Confirm asks the user to confirm an action, and is nil when there's
+	// no one to ask
+	Confirm func(message string) bool
+
+	// Input holds the lines of a statement that isn't terminated with a
+	// semicolon yet
+	Input []string
+}
+
+// Connection is a named connection of the shell.
+type Connection struct {
+	Name           string
+	DB             *dbtypes.DB
+	DatabaseName   string
+	DatabaseEngine string
 }
 
 type ShellCommandResult struct {
@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/queryplan-ai/qp/pkg/db"
+	"github.com/queryplan-ai/qp/pkg/shell/types"
+	"github.com/queryplan-ai/qp/pkg/workload"
+)
+
+// handleTop plans the statements the database spends the most time on. The
+// arguments are an optional number of statements and an optional order, in
+// either order: /top 10 calls.
+func handleTop(sh *types.Shell, args string) *types.ShellCommandResult {
+	result := &types.ShellCommandResult{
+		IsFatal:   false,
+		IsSuccess: false,
+	}
+
+	if sh.DB == nil {
+		result.Message = "not connected, use /connect"
+		return result
+	}
+
+	opts := workload.Options{
+		OrderBy: workload.OrderByTotalTime,
+		Limit:   workload.DefaultLimit,
+	}
+	for _, arg := range strings.Fields(args) {
+		if limit, err := strconv.Atoi(arg); err == nil {
+			opts.Limit = limit
+			continue
+		}
+		opts.OrderBy = arg
+	}
+
+	message, err := db.AnalyzeWorkload(sh.DB, opts)
+	if err != nil {
+		result.Message = fmt.Sprintf("Error analyzing workload: %s", err)
+		return result
+	}
+
+	result.IsSuccess = true
+	result.Message = message
+	return result
+}
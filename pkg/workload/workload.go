@@ -0,0 +1,246 @@
+package workload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+)
+
+const (
+	OrderByTotalTime  = "total-time"
+	OrderByMeanTime   = "mean-time"
+	OrderByCalls      = "calls"
+	OrderByRows       = "rows"
+	OrderByBlocksRead = "blocks-read"
+	// OrderByExaminedRatio ranks by the rows examined for every row sent,
+	// which is high for statements that read far more than they need
+	OrderByExaminedRatio = "examined-ratio"
+)
+
+// DefaultLimit is the number of statements analyzed when no limit is given.
+const DefaultLimit = 20
+
+// Options selects the statements of a workload to analyze.
+type Options struct {
+	// OrderBy is the measurement the top statements are picked and ranked by
+	OrderBy string
+	Limit   int
+}
+
+// Statement is a normalized statement from the statistics the database keeps
+// for every query it runs, and what the statement cost in total.
+type Statement struct {
+	// QueryID is the database's identifier for the normalized statement
+	QueryID string
+	Query   string
+	// Parameters are the values of the bind parameters of Query, as SQL
+	// literals, when they were logged with it
+	Parameters []string
+	Calls      int64
+	TotalTime  time.Duration
+	MeanTime   time.Duration
+	// Rows is the number of rows returned or changed
+	Rows int64
+	// SharedBlocksRead is the number of blocks Postgres read from outside of
+	// shared buffers
+	SharedBlocksRead int64
+	// RowsExamined is the number of rows MySQL read to run the statement
+	RowsExamined int64
+	// NoIndexUsed is the number of MySQL executions that scanned a table
+	// without an index
+	NoIndexUsed int64
+	// Plan is the plan of the slowest execution, when the database logged
+	// one
+	Plan string
+}
+
+// ExaminedRatio returns the number of rows examined for every row returned.
+func (s Statement) ExaminedRatio() float64 {
+	if s.Rows == 0 {
+		return float64(s.RowsExamined)
+	}
+	return float64(s.RowsExamined) / float64(s.Rows)
+}
+
+// Finding is a statement of the workload and the issues planning it found.
+type Finding struct {
+	Statement Statement
+	Issues    []issuetypes.QueryIssue
+	// Err is set when the statement couldn't be planned
+	Err error
+}
+
+// Scanner returns the issues with a query, or nil if the statement isn't one
+// that's planned.
+type Scanner func(query string) ([]issuetypes.QueryIssue, error)
+
+// ValidateOrderBy returns an error if orderBy isn't one of the given orders.
+func ValidateOrderBy(orderBy string, supported []string) error {
+	for _, order := range supported {
+		if order == orderBy {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported order %q, use one of %s", orderBy, strings.Join(supported, ", "))
+}
+
+// Analyze plans every statement of a workload, and ranks the statements with
+// issues by their measured cost, so the issues that cost the most come first.
+func Analyze(statements []Statement, scan Scanner, orderBy string) []Finding {
+	findings := []Finding{}
+	for _, statement := range statements {
+		issues, err := ScanSafely(scan, statement.Query)
+		if err == nil && issues == nil {
+			continue
+		}
+		findings = append(findings, Finding{
+			Statement: statement,
+			Issues:    issues,
+			Err:       err,
+		})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if (len(a.Issues) > 0) != (len(b.Issues) > 0) {
+			return len(a.Issues) > 0
+		}
+		if impact(a.Statement, orderBy) != impact(b.Statement, orderBy) {
+			return impact(a.Statement, orderBy) > impact(b.Statement, orderBy)
+		}
+		return a.Statement.TotalTime > b.Statement.TotalTime
+	})
+
+	return findings
+}
+
+// ScanSafely scans a query, and returns an error instead if the parser panics
+// on it, so one statement doesn't stop the analysis of the rest.
+func ScanSafely(scan Scanner, query string) (issues []issuetypes.QueryIssue, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			issues, err = nil, fmt.Errorf("plan query: %v", r)
+		}
+	}()
+
+	return scan(query)
+}
+
+// Top returns the statements with the highest measurement for the order of
+// the options, at most Limit of them.
+func Top(statements []Statement, opts Options) []Statement {
+	top := append([]Statement{}, statements...)
+	sort.SliceStable(top, func(i, j int) bool {
+		return impact(top[i], opts.OrderBy) > impact(top[j], opts.OrderBy)
+	})
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if len(top) > limit {
+		top = top[:limit]
+	}
+
+	return top
+}
+
+// impact returns the measurement a statement is ranked by.
+func impact(statement Statement, orderBy string) float64 {
+	switch orderBy {
+	case OrderByMeanTime:
+		return float64(statement.MeanTime)
+	case OrderByCalls:
+		return float64(statement.Calls)
+	case OrderByRows:
+		return float64(statement.Rows)
+	case OrderByBlocksRead:
+		return float64(statement.SharedBlocksRead)
+	case OrderByExaminedRatio:
+		return statement.ExaminedRatio()
+	default:
+		return float64(statement.TotalTime)
+	}
+}
+
+// FormatReport returns the findings as a report, with the statements that
+// have issues first, and then the statements that couldn't be planned and why.
+func FormatReport(findings []Finding) string {
+	var b strings.Builder
+
+	clean := 0
+	failed := []Finding{}
+	rank := 0
+	for _, finding := range findings {
+		switch {
+		case finding.Err != nil:
+			failed = append(failed, finding)
+			continue
+		case len(finding.Issues) == 0:
+			clean++
+			continue
+		}
+
+		rank++
+		statement := finding.Statement
+		fmt.Fprintf(&b, "%d. %s\n", rank, describe(statement))
+		fmt.Fprintf(&b, "   %s\n", strings.Join(strings.Fields(statement.Query), " "))
+		if len(statement.Parameters) > 0 {
+			fmt.Fprintf(&b, "   parameters: %s\n", formatParameters(statement.Parameters))
+		}
+		for _, issue := range finding.Issues {
+			fmt.Fprintf(&b, "   - [%s] %s\n", issue.IssueSeverity, issue.Message)
+		}
+	}
+
+	if rank == 0 {
+		b.WriteString("No issues found\n")
+	}
+	if len(failed) > 0 {
+		b.WriteString("Could not be planned:\n")
+		for _, finding := range failed {
+			fmt.Fprintf(&b, "   %s\n", strings.Join(strings.Fields(finding.Statement.Query), " "))
+			fmt.Fprintf(&b, "   - %s\n", finding.Err)
+		}
+	}
+	fmt.Fprintf(&b, "%d statements without issues, %d statements could not be planned", clean, len(failed))
+
+	return b.String()
+}
+
+// formatParameters returns the values of bind parameters as they're logged:
+// $1 = '42', $2 = NULL.
+func formatParameters(values []string) string {
+	parameters := []string{}
+	for i, value := range values {
+		parameters = append(parameters, fmt.Sprintf("$%d = %s", i+1, value))
+	}
+	return strings.Join(parameters, ", ")
+}
+
+func describe(statement Statement) string {
+	parts := []string{
+		fmt.Sprintf("%d calls", statement.Calls),
+		fmt.Sprintf("%s total", statement.TotalTime.Round(time.Millisecond)),
+		fmt.Sprintf("%s mean", statement.MeanTime.Round(time.Microsecond)),
+		fmt.Sprintf("%d rows", statement.Rows),
+	}
+	if statement.SharedBlocksRead > 0 {
+		parts = append(parts, fmt.Sprintf("%d blocks read", statement.SharedBlocksRead))
+	}
+	if statement.RowsExamined > 0 {
+		parts = append(parts, fmt.Sprintf("%d rows examined (%.1f per row)", statement.RowsExamined, statement.ExaminedRatio()))
+	}
+	if statement.NoIndexUsed > 0 {
+		parts = append(parts, fmt.Sprintf("no index used in %d calls", statement.NoIndexUsed))
+	}
+
+	description := strings.Join(parts, ", ")
+	if statement.QueryID != "" {
+		description = fmt.Sprintf("%s (%s)", statement.QueryID, description)
+	}
+	return description
+}
@@ -0,0 +1,93 @@
+package workload
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	issuetypes "github.com/queryplan-ai/qp/pkg/issue/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	statements := []Statement{
+		{Query: "select * from a", Calls: 10, TotalTime: time.Second, Rows: 10, RowsExamined: 100000},
+		{Query: "select * from b", Calls: 1000, TotalTime: 3 * time.Second, Rows: 1000, RowsExamined: 1000},
+		{Query: "select * from c", Calls: 5, TotalTime: 5 * time.Second},
+		{Query: "vacuum", Calls: 1, TotalTime: time.Minute},
+		{Query: "select * from broken", Calls: 1, TotalTime: time.Hour},
+	}
+
+	scan := func(query string) ([]issuetypes.QueryIssue, error) {
+		switch query {
+		case "select * from c":
+			return []issuetypes.QueryIssue{}, nil
+		case "vacuum":
+			return nil, nil
+		case "select * from broken":
+			return nil, fmt.Errorf("syntax error")
+		}
+		return []issuetypes.QueryIssue{{IssueSeverity: issuetypes.IssueSeverityMedium, Message: "full scan of " + query[len(query)-1:]}}, nil
+	}
+
+	tests := []struct {
+		name    string
+		orderBy string
+		want    []string
+	}{
+		{
+			name:    "total time",
+			orderBy: OrderByTotalTime,
+			want:    []string{"select * from b", "select * from a", "select * from broken", "select * from c"},
+		},
+		{
+			name:    "examined ratio, then total time",
+			orderBy: OrderByExaminedRatio,
+			want:    []string{"select * from a", "select * from b", "select * from broken", "select * from c"},
+		},
+		{
+			name:    "calls",
+			orderBy: OrderByCalls,
+			want:    []string{"select * from b", "select * from a", "select * from c", "select * from broken"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := Analyze(statements, scan, tt.orderBy)
+
+			queries := []string{}
+			for _, finding := range findings {
+				queries = append(queries, finding.Statement.Query)
+			}
+			assert.Equal(t, tt.want, queries)
+		})
+	}
+}
+
+func TestFormatReport(t *testing.T) {
+	findings := []Finding{
+		{
+			Statement: Statement{QueryID: "42", Query: "select *\n  from a where id = $1", Parameters: []string{"-5"}, Calls: 10, TotalTime: 1500 * time.Millisecond, MeanTime: 150 * time.Millisecond, Rows: 20},
+			Issues:    []issuetypes.QueryIssue{{IssueSeverity: issuetypes.IssueSeverityHigh, Message: "full scan of a"}},
+		},
+		{Statement: Statement{Query: "select * from b"}, Issues: []issuetypes.QueryIssue{}},
+		{Statement: Statement{Query: "select * from broken"}, Err: fmt.Errorf("syntax error")},
+	}
+
+	report := FormatReport(findings)
+	require.NotEmpty(t, report)
+	assert.Equal(t, `1. 42 (10 calls, 1.5s total, 150ms mean, 20 rows)
+   select * from a where id = $1
+   parameters: $1 = -5
+   - [high] full scan of a
+Could not be planned:
+   select * from broken
+   - syntax error
+1 statements without issues, 1 statements could not be planned`, report)
+}
+
+func TestValidateOrderBy(t *testing.T) {
+	assert.NoError(t, ValidateOrderBy(OrderByCalls, []string{OrderByTotalTime, OrderByCalls}))
+	assert.Error(t, ValidateOrderBy("latency", []string{OrderByTotalTime, OrderByCalls}))
+}